@@ -1,19 +1,39 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/vybraan/vyai/internal/agent"
 	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/cli"
+	"github.com/vybraan/vyai/internal/mcp"
+	"github.com/vybraan/vyai/internal/project"
 	"github.com/vybraan/vyai/internal/providers/gemini"
 	"github.com/vybraan/vyai/internal/ui"
 	"github.com/vybraan/vyai/internal/utils"
 )
 
 func main() {
+	agent.MaybeRunSandboxHelper()
+
+	cli.StartInterface = runInterface
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	if err := runInterface(""); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runInterface starts the interactive interface, with the conversation
+// conversationID open when it is not empty.
+func runInterface(conversationID string) error {
 	if os.Getenv("GOOGLE_API_KEY") == "" {
 		fmt.Println("Error: GOOGLE_API_KEY environment variable is not set.")
 		fmt.Println("Get a key from https://aistudio.google.com/apikey")
@@ -23,25 +43,69 @@ func main() {
 
 	cfg, err := appconfig.Load()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	cm := gemini.NewConversationManager()
 
 	gsService := gemini.NewGeminiService(cm, cfg)
 	if err := gsService.LoadStoredConversations(); err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if conversationID == "" && cfg.ResumeLast {
+		if conversations, err := gsService.GetAllConversations(); err == nil {
+			conversationID = conversations[0].ID
+		}
+	}
+
+	servers, errs := mcp.StartAll(context.Background(), mcp.Configs(cfg.MCPServers))
+	defer servers.Close()
+	for _, err := range errs {
+		gsService.Notify(fmt.Sprintf("MCP server not started: %v", err))
 	}
+	gsService.SetChatTools(servers.ChatTools())
 
 	workspace, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	gsService.SetProjectBrief(project.Brief(project.Detect(workspace), cfg.ProjectContext.MaxTokens))
 
-	agentRunner := agent.NewLocalRunner(workspace, utils.GenerateEphemeralMessage)
+	policy, err := agent.PolicyFromConfig(cfg.AgentPolicyFor(workspace))
+	if err != nil {
+		return fmt.Errorf("agent policy: %w", err)
+	}
 
-	p := tea.NewProgram(ui.NewUIModel(gsService, workspace, agentRunner))
-	if _, err := p.Run(); err != nil {
-		log.Fatal(err)
+	sandbox, err := agent.ParseSandboxMode(cfg.Agent.Sandbox)
+	if err != nil {
+		return fmt.Errorf("agent sandbox: %w", err)
+	}
+
+	agentEnv := agent.Env{
+		Workspace: workspace,
+		Policy:    policy,
+		Limits: agent.ExecLimits{
+			Timeout:        cfg.Agent.Timeout,
+			MaxOutputBytes: cfg.Agent.MaxOutputBytes,
+		},
+		Sandbox:  sandbox,
+		Writable: cfg.Agent.WritablePaths,
+		CacheDir: filepath.Join(cfg.DataDir, "sandbox-cache"),
+		Audit:    agent.NewAuditLog(agent.AuditLogPath(cfg.DataDir)),
+	}
+	plugins, errs := agent.LoadPlugins(cfg.PluginDir, agentEnv)
+	for _, err := range errs {
+		gsService.Notify(fmt.Sprintf("Agent plugin not loaded: %v", err))
+	}
+	agentEnv.Tools = append(plugins, servers.AgentTools()...)
+
+	agentRunner := agent.NewLocalRunner(agentEnv, utils.GenerateEphemeralMessage)
+
+	model := ui.NewUIModel(gsService, workspace, agentRunner)
+	if conversationID != "" {
+		model = model.WithConversation(conversationID)
 	}
+	p := tea.NewProgram(model)
+	_, err = p.Run()
+	return err
 }
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Approval decisions recorded in the audit log.
+const (
+	DecisionAllowed     = "allowed"
+	DecisionDenied      = "denied"
+	DecisionNotRequired = "not-required"
+)
+
+// AuditLogPath is where the audit log lives inside the data directory.
+func AuditLogPath(dataDir string) string {
+	return filepath.Join(dataDir, "audit.jsonl")
+}
+
+// AuditEntry is one line of the audit log: a single tool invocation.
+type AuditEntry struct {
+	Time           time.Time         `json:"time"`
+	Workspace      string            `json:"workspace"`
+	ConversationID string            `json:"conversation_id,omitempty"`
+	Tool           string            `json:"tool"`
+	Attrs          map[string]string `json:"attrs,omitempty"`
+	Command        string            `json:"command,omitempty"`
+	Argv           []string          `json:"argv,omitempty"`
+	ExitCode       *int              `json:"exit_code,omitempty"`
+	OutputBytes    int               `json:"output_bytes"`
+	Decision       string            `json:"decision"`
+	Failed         bool              `json:"failed,omitempty"`
+}
+
+// AuditLog appends entries to a JSONL file. It is safe for concurrent use
+// within the process; each entry is written with a single append so lines
+// from separate processes do not interleave.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+func (l *AuditLog) Record(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("create audit log dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// AuditFilter selects entries by time range, workspace, tool or
+// conversation. Zero fields match everything.
+type AuditFilter struct {
+	Since          time.Time
+	Until          time.Time
+	Workspace      string
+	Tool           string
+	ConversationID string
+}
+
+func (f AuditFilter) match(entry AuditEntry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	if f.Workspace != "" {
+		workspace := filepath.Clean(f.Workspace)
+		if entry.Workspace != workspace && !strings.HasPrefix(entry.Workspace, workspace+string(filepath.Separator)) {
+			return false
+		}
+	}
+	if f.Tool != "" && entry.Tool != f.Tool {
+		return false
+	}
+	if f.ConversationID != "" && !strings.HasPrefix(entry.ConversationID, f.ConversationID) {
+		return false
+	}
+	return true
+}
+
+// ReadAuditLog returns the entries matching filter in file order. A missing
+// log is not an error; it just has no entries yet.
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parse audit log line %d: %w", line, err)
+		}
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	return entries, nil
+}
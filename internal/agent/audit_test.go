@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func TestRunnerWritesAuditEntries(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{"notes.txt": "hello\n"})
+	audit := NewAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+
+	runner := NewLocalRunner(Env{Workspace: workspace, Sandbox: SandboxOff, Audit: audit}, nil)
+	_, err := runner.Run(context.Background(), RunRequest{
+		Input:          `<run-bash>cat notes.txt</run-bash><run-bash>rm notes.txt</run-bash><read-file path="notes.txt"></read-file><summary>done</summary>`,
+		ConversationID: "CONVERSATION-ABC",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	entries, err := ReadAuditLog(audit.Path(), AuditFilter{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog returned error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", len(entries))
+	}
+
+	cat := entries[0]
+	if cat.Decision != DecisionAllowed || cat.ExitCode == nil || *cat.ExitCode != 0 {
+		t.Fatalf("unexpected cat entry: %+v", cat)
+	}
+	if len(cat.Argv) != 2 || cat.Argv[1] != filepath.Join(workspace, "notes.txt") {
+		t.Fatalf("expected argv after path rewriting, got %v", cat.Argv)
+	}
+	if cat.ConversationID != "CONVERSATION-ABC" || cat.Workspace != workspace || cat.OutputBytes == 0 {
+		t.Fatalf("unexpected cat entry: %+v", cat)
+	}
+
+	rm := entries[1]
+	if rm.Decision != DecisionDenied || rm.Argv != nil || rm.ExitCode != nil || rm.Command != "rm notes.txt" {
+		t.Fatalf("unexpected rm entry: %+v", rm)
+	}
+
+	read := entries[2]
+	if read.Decision != DecisionNotRequired || read.Attrs["path"] != "notes.txt" {
+		t.Fatalf("unexpected read-file entry: %+v", read)
+	}
+}
+
+func TestReadAuditLogFilters(t *testing.T) {
+	t.Parallel()
+
+	audit := NewAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, entry := range []AuditEntry{
+		{Time: day, Workspace: "/srv/app", Tool: "run-bash"},
+		{Time: day.Add(24 * time.Hour), Workspace: "/srv/app/sub", Tool: "read-file"},
+		{Time: day.Add(48 * time.Hour), Workspace: "/srv/application", Tool: "run-bash"},
+	} {
+		if err := audit.Record(entry); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	tools := func(filter AuditFilter) string {
+		entries, err := ReadAuditLog(audit.Path(), filter)
+		if err != nil {
+			t.Fatalf("ReadAuditLog returned error: %v", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Workspace)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := tools(AuditFilter{Workspace: "/srv/app"}); got != "/srv/app,/srv/app/sub" {
+		t.Fatalf("unexpected workspace filter result: %s", got)
+	}
+	if got := tools(AuditFilter{Tool: "run-bash", Since: day.Add(time.Hour)}); got != "/srv/application" {
+		t.Fatalf("unexpected tool/since filter result: %s", got)
+	}
+	if got := tools(AuditFilter{Until: day.Add(24 * time.Hour)}); got != "/srv/app" {
+		t.Fatalf("unexpected until filter result: %s", got)
+	}
+
+	missing, err := ReadAuditLog(filepath.Join(t.TempDir(), "none.jsonl"), AuditFilter{})
+	if err != nil || missing != nil {
+		t.Fatalf("expected an empty result for a missing log, got %v, %v", missing, err)
+	}
+}
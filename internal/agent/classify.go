@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Risk grades a shell command a person is about to run.
+type Risk int
+
+const (
+	// RiskSafe commands pass the agent command policy.
+	RiskSafe Risk = iota
+	// RiskReview commands are ordinary but were not checked in detail.
+	RiskReview
+	// RiskDangerous commands can destroy data or stop the system.
+	RiskDangerous
+)
+
+func (r Risk) String() string {
+	switch r {
+	case RiskSafe:
+		return "safe"
+	case RiskReview:
+		return "review"
+	default:
+		return "dangerous"
+	}
+}
+
+// Assessment is the risk of a command and what it is based on.
+type Assessment struct {
+	Risk    Risk
+	Reasons []string
+}
+
+func (a *Assessment) raise(risk Risk, reason string) {
+	a.Risk = max(a.Risk, risk)
+	if !slices.Contains(a.Reasons, reason) {
+		a.Reasons = append(a.Reasons, reason)
+	}
+}
+
+// shellSeparators split a command line into the simple commands it runs.
+var shellSeparators = regexp.MustCompile("\\|\\||&&|[|;&\n`]|\\$\\(|\\)")
+
+// commandWrappers run the command that follows them.
+var commandWrappers = []string{"command", "env", "exec", "nice", "nohup", "stdbuf", "time", "timeout", "xargs"}
+
+// wrapperValueOptions are the options of the wrappers, sudo included, that
+// take the next word as their value.
+var wrapperValueOptions = map[string][]string{
+	"sudo":    {"-u", "-g", "-h", "-p", "-r", "-t", "-C", "-D", "-T", "-U"},
+	"doas":    {"-u", "-C"},
+	"su":      {"-s", "-g", "-G"},
+	"env":     {"-u", "-C", "-S"},
+	"exec":    {"-a"},
+	"nice":    {"-n"},
+	"stdbuf":  {"-i", "-o", "-e"},
+	"time":    {"-f", "-o"},
+	"timeout": {"-s", "-k"},
+	"xargs":   {"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s"},
+}
+
+// ClassifyCommand grades a shell command line run in dir. A single command
+// that ParseCommand accepts, policy prepares for dir, and that stays inside
+// dir is safe; a nil policy means the built-in one. Anything else needs
+// review, and is dangerous when it matches a known destructive pattern.
+// The check is a heuristic that puts warnings in front of the user, not a
+// sandbox.
+func ClassifyCommand(line string, policy *CommandPolicy, dir string) Assessment {
+	if policy == nil {
+		policy = DefaultCommandPolicy()
+	}
+	args, err := ParseCommand(line)
+	var a Assessment
+	switch {
+	case err != nil:
+		a.raise(RiskReview, "uses shell syntax")
+	case !policy.Allow(args):
+		a.raise(RiskReview, "not in the agent command policy")
+	default:
+		if _, prepErr := policy.Prepare(args, dir); prepErr != nil || slices.ContainsFunc(args[1:], argEscapes) {
+			a.raise(RiskReview, "reaches outside the current directory")
+		} else {
+			return Assessment{Risk: RiskSafe, Reasons: []string{"allowed by the agent command policy"}}
+		}
+	}
+
+	if strings.Contains(strings.ReplaceAll(line, " ", ""), ":(){") {
+		a.raise(RiskDangerous, "is a fork bomb")
+	}
+	if redirect := overwriteTarget(line); redirect != "" {
+		if strings.HasPrefix(redirect, "/dev/sd") || strings.HasPrefix(redirect, "/dev/nvme") {
+			a.raise(RiskDangerous, "writes to a disk device")
+		} else {
+			a.raise(RiskReview, "overwrites "+redirect)
+		}
+	}
+
+	downloads := false
+	for _, segment := range shellSeparators.Split(line, -1) {
+		words := strings.Fields(segment)
+		words = unwrap(words, &a)
+		if len(words) == 0 {
+			continue
+		}
+		name := path.Base(words[0])
+		switch name {
+		case "curl", "wget":
+			downloads = true
+		case "sh", "bash", "zsh", "dash", "fish", "python", "python3", "perl":
+			if downloads {
+				a.raise(RiskDangerous, "runs a downloaded script")
+			}
+		}
+		classifyWords(name, words[1:], &a)
+	}
+	return a
+}
+
+// argEscapes reports whether a plain argument or a flag value is a path
+// outside the current directory.
+func argEscapes(arg string) bool {
+	if value, ok := flagValue(arg); ok {
+		return escapesWorkspace(value)
+	}
+	return !strings.HasPrefix(arg, "-") && escapesWorkspace(arg)
+}
+
+// unwrap drops variable assignments and wrappers such as sudo, with their
+// options, in front of a command.
+func unwrap(words []string, a *Assessment) []string {
+	for len(words) > 0 {
+		word := words[0]
+		if !strings.Contains(word, "=") {
+			word = path.Base(word)
+		}
+		switch {
+		case word == "sudo" || word == "doas" || word == "su":
+			a.raise(RiskReview, "runs as root")
+			if command, ok := suCommand(word, words[1:]); ok {
+				return command
+			}
+			words = skipWrapperOptions(word, words[1:])
+		case slices.Contains(commandWrappers, word):
+			words = skipWrapperOptions(word, words[1:])
+			if word == "timeout" && len(words) > 0 {
+				// The duration comes before the command.
+				words = words[1:]
+			}
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+			words = words[1:]
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// skipWrapperOptions drops the options of wrapper and their values.
+func skipWrapperOptions(wrapper string, words []string) []string {
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") && words[0] != "-" {
+		option := words[0]
+		words = words[1:]
+		if option == "--" {
+			break
+		}
+		if slices.Contains(wrapperValueOptions[wrapper], option) && len(words) > 0 {
+			words = words[1:]
+		}
+	}
+	return words
+}
+
+// suCommand returns the command su runs with -c, unquoted.
+func suCommand(wrapper string, words []string) ([]string, bool) {
+	if wrapper != "su" {
+		return nil, false
+	}
+	for i, word := range words {
+		if word == "-c" || word == "--command" {
+			return strings.Fields(strings.Trim(strings.Join(words[i+1:], " "), `"'`)), true
+		}
+	}
+	return nil, false
+}
+
+func classifyWords(name string, args []string, a *Assessment) {
+	has := func(flags ...string) bool {
+		for _, arg := range args {
+			for _, flag := range flags {
+				if arg == flag || (len(flag) == 2 && strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg[1:], flag[1:])) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch name {
+	case "rm", "rmdir", "shred", "unlink", "srm":
+		a.raise(RiskDangerous, "deletes files")
+	case "dd", "mkfs", "fdisk", "sfdisk", "parted", "wipefs", "mkswap", "blkdiscard":
+		a.raise(RiskDangerous, "writes to disks")
+	case "shutdown", "reboot", "poweroff", "halt":
+		a.raise(RiskDangerous, "shuts down or restarts the system")
+	case "kill", "pkill", "killall":
+		a.raise(RiskReview, "stops processes")
+	case "mv", "truncate":
+		a.raise(RiskReview, "moves or overwrites files")
+	case "chmod", "chown", "chgrp":
+		if has("-R", "--recursive") {
+			a.raise(RiskDangerous, "changes ownership or permissions recursively")
+		}
+	case "find":
+		if has("-delete") || slices.ContainsFunc(args, func(arg string) bool { return arg == "rm" || strings.HasSuffix(arg, "/rm") }) {
+			a.raise(RiskDangerous, "deletes files")
+		}
+	case "systemctl":
+		if len(args) > 0 && slices.Contains([]string{"poweroff", "reboot", "halt", "kexec"}, args[0]) {
+			a.raise(RiskDangerous, "shuts down or restarts the system")
+		}
+	case "git":
+		switch {
+		case slices.Contains(args, "push") && has("-f", "--force", "--force-with-lease"):
+			a.raise(RiskDangerous, "rewrites remote history")
+		case slices.Contains(args, "reset") && has("--hard"), slices.Contains(args, "clean") && has("-f", "--force"):
+			a.raise(RiskDangerous, "discards uncommitted work")
+		}
+	}
+	if strings.HasPrefix(name, "mkfs.") {
+		a.raise(RiskDangerous, "writes to disks")
+	}
+}
+
+var overwriteRedirect = regexp.MustCompile(`(?:^|[^>&0-9])[0-9]?>\|?\s*([^\s>&|;]+)`)
+
+// overwriteTarget returns the file a ">" redirection truncates, ignoring
+// appends and /dev/null.
+func overwriteTarget(line string) string {
+	for _, match := range overwriteRedirect.FindAllStringSubmatch(line, -1) {
+		if target := match[1]; target != "/dev/null" && !strings.HasPrefix(target, "/dev/std") {
+			return target
+		}
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"io"
 
 	"github.com/grahms/promptweaver"
@@ -11,14 +12,28 @@ type AgentEngine struct {
 	sink   *promptweaver.HandlerSink
 }
 
-func NewAgent(uiOut func(string), workspace string) *AgentEngine {
-	reg := BuildRegistry()
-	sink := BuildSink(uiOut, workspace)
+func NewAgent(ctx context.Context, report Reporter, env Env) *AgentEngine {
+	reg := BuildRegistry(env.Tools...)
+	sink := BuildSink(ctx, report, env)
 	engine := promptweaver.NewEngine(reg)
 
 	return &AgentEngine{engine, sink}
 }
 
-func (a *AgentEngine) Process(r io.Reader) error {
-	return a.engine.ProcessStream(r, a.sink)
+// Process runs every section in r. Once ctx is done the stream stops being
+// read and handlers skip the remaining tools.
+func (a *AgentEngine) Process(ctx context.Context, r io.Reader) error {
+	return a.engine.ProcessStream(contextReader{ctx: ctx, r: r}, a.sink)
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
 }
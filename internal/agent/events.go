@@ -0,0 +1,85 @@
+package agent
+
+import "strings"
+
+// EventKind identifies a step of an agent run.
+type EventKind string
+
+const (
+	EventTranslating  EventKind = "translating"
+	EventProgram      EventKind = "program"
+	EventToolStarted  EventKind = "tool-started"
+	EventToolFinished EventKind = "tool-finished"
+	EventSummary      EventKind = "summary"
+)
+
+// Event reports agent progress as PromptWeaver sections are processed, so
+// callers can render tools while they run instead of waiting for the
+// joined output.
+type Event struct {
+	Kind   EventKind
+	Tool   string
+	Detail string
+	Output string
+	Failed bool
+}
+
+// Reporter receives events in the order they happen.
+type Reporter func(Event)
+
+// toolOutput collects the lines a section handler reports, along with
+// what the audit log needs to know about commands it ran.
+type toolOutput struct {
+	lines    []string
+	failed   bool
+	argv     []string
+	exitCode *int
+	decision string
+	// input is what the audit log records as the command for tools other
+	// than run-bash, such as the JSON arguments of an external tool.
+	input string
+}
+
+func (o *toolOutput) print(line string) {
+	o.lines = append(o.lines, line)
+}
+
+func (o *toolOutput) fail(line string) {
+	o.failed = true
+	o.lines = append(o.lines, line)
+}
+
+func (o *toolOutput) String() string {
+	var parts []string
+	for _, line := range o.lines {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// sectionDetail picks the attribute that best identifies a tool call in a
+// one-line progress header.
+func sectionDetail(tool string, content string, attrs map[string]string) string {
+	switch tool {
+	case "run-bash":
+		line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+		return line
+	case "grep-file", "glob-file":
+		return strings.TrimSpace(attrs["pattern"] + " " + attrs["path"])
+	case "git-diff":
+		return strings.TrimSpace(attrs["ref"] + " " + attrs["path"])
+	case "apply-patch":
+		patch, err := ParsePatch(content)
+		if err != nil {
+			return ""
+		}
+		paths := make([]string, 0, len(patch))
+		for _, file := range patch {
+			paths = append(paths, file.displayPath())
+		}
+		return strings.Join(paths, ", ")
+	}
+	return attrs["path"]
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	DefaultCommandTimeout = 2 * time.Minute
+	DefaultMaxOutputBytes = 64 * 1024
+
+	// waitDelay bounds how long we wait for pipes to drain after the
+	// process group has been killed.
+	waitDelay = 2 * time.Second
+)
+
+// ExecLimits bounds a single command run by an agent tool. Zero values fall
+// back to the defaults.
+type ExecLimits struct {
+	Timeout        time.Duration
+	MaxOutputBytes int
+}
+
+func (l ExecLimits) withDefaults() ExecLimits {
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultCommandTimeout
+	}
+	if l.MaxOutputBytes <= 0 {
+		l.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	return l
+}
+
+// Env is the execution environment shared by the agent's tools.
+type Env struct {
+	Workspace string
+	Policy    *CommandPolicy
+	Limits    ExecLimits
+	Sandbox   SandboxMode
+	// Writable lists extra directories sandboxed commands may write to.
+	Writable []string
+	// CacheDir keeps build caches such as GOCACHE across sandboxed runs.
+	CacheDir string
+	// Audit, when set, records every tool invocation.
+	Audit *AuditLog
+	// ConversationID ties audit entries to the conversation that ran them.
+	ConversationID string
+	// Tools are extra sections, e.g. from MCP servers, available to programs.
+	Tools []Tool
+}
+
+func (e Env) policy() *CommandPolicy {
+	if e.Policy == nil {
+		return DefaultCommandPolicy()
+	}
+	return e.Policy
+}
+
+// exitCode maps the error returned by runCommand to a process exit code,
+// using -1 for commands that were killed or never reported one.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// runCommand executes argv in the workspace sandbox, killing the whole
+// process group when ctx is cancelled or the timeout expires. Combined output
+// is capped, keeping the head and tail so both the command banner and the
+// final error survive.
+func runCommand(ctx context.Context, argv []string, env Env) (string, error) {
+	return runCommandEnv(ctx, argv, env, nil)
+}
+
+// runCommandEnv is runCommand with extra variables added to the scrubbed
+// environment.
+func runCommandEnv(ctx context.Context, argv []string, env Env, extraEnv []string) (string, error) {
+	if len(argv) == 0 {
+		return "", errors.New("empty command")
+	}
+	limits := env.Limits.withDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	c, cleanup, err := sandboxCommand(ctx, argv, env)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	c.Dir = env.Workspace
+	c.Env = append(c.Env, extraEnv...)
+	return runLimited(ctx, c, limits)
+}
+
+// runLimited runs c in its own process group with capped combined output.
+// ctx must be the context c was created with and carry the timeout.
+func runLimited(ctx context.Context, c *exec.Cmd, limits ExecLimits) (string, error) {
+	c.WaitDelay = waitDelay
+	setProcessGroup(c)
+
+	out := newCappedBuffer(limits.MaxOutputBytes)
+	c.Stdout = out
+	c.Stderr = out
+
+	err := c.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return out.String(), fmt.Errorf("command timed out after %s", limits.Timeout)
+		}
+		return out.String(), fmt.Errorf("command cancelled: %w", ctxErr)
+	}
+	return out.String(), err
+}
+
+// cappedBuffer retains at most limit bytes: the first half of the stream and
+// a rolling window over the last half.
+type cappedBuffer struct {
+	limit   int
+	head    []byte
+	tail    []byte
+	dropped int
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	headLimit := b.limit / 2
+	if room := headLimit - len(b.head); room > 0 {
+		take := min(room, len(p))
+		b.head = append(b.head, p[:take]...)
+		p = p[take:]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	tailLimit := b.limit - headLimit
+	b.tail = append(b.tail, p...)
+	if over := len(b.tail) - tailLimit; over > 0 {
+		b.dropped += over
+		b.tail = append(b.tail[:0], b.tail[over:]...)
+	}
+	return n, nil
+}
+
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return string(b.head) + string(b.tail)
+	}
+	return fmt.Sprintf("%s\n... [%d bytes truncated] ...\n%s", b.head, b.dropped, b.tail)
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCappedBufferKeepsHeadAndTail(t *testing.T) {
+	t.Parallel()
+
+	buf := newCappedBuffer(8)
+	buf.Write([]byte("abcd"))
+	buf.Write([]byte("0123456789"))
+	buf.Write([]byte("wxyz"))
+
+	got := buf.String()
+	if !strings.HasPrefix(got, "abcd") || !strings.HasSuffix(got, "wxyz") {
+		t.Fatalf("expected head and tail to be kept, got %q", got)
+	}
+	if !strings.Contains(got, "[10 bytes truncated]") {
+		t.Fatalf("expected truncation marker, got %q", got)
+	}
+}
+
+func TestRunCommandTimesOutAndKillsProcessGroup(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are unix-only")
+	}
+
+	start := time.Now()
+	_, err := runCommand(context.Background(), []string{"sh", "-c", "sleep 30 & sleep 30"}, Env{
+		Workspace: t.TempDir(),
+		Limits:    ExecLimits{Timeout: 200 * time.Millisecond},
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the process group to be killed promptly, took %s", elapsed)
+	}
+}
+
+func TestRunCommandHonorsCallerCancellation(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are unix-only")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := runCommand(ctx, []string{"sleep", "30"}, Env{Workspace: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+}
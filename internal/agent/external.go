@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grahms/promptweaver"
+)
+
+// Tool is a section supplied from outside the package, such as a tool
+// offered by an MCP server. It is registered next to the built-in sections
+// and described to the model in the translation prompt.
+type Tool struct {
+	// Name is the section tag; it must be a valid PromptWeaver tag name.
+	Name    string
+	Aliases []string
+	// Usage is an example tag shown to the model, e.g.
+	// <weather city="...">{"units": "metric"}</weather>.
+	Usage       string
+	Description string
+	Run         func(ctx context.Context, attrs map[string]string, content string) (string, error)
+}
+
+func registerTools(reg *promptweaver.Registry, tools []Tool) {
+	for _, tool := range tools {
+		reg.Register(promptweaver.SectionPlugin{Name: tool.Name, Aliases: tool.Aliases})
+	}
+}
+
+func toolNames(tools []Tool) map[string]struct{} {
+	names := make(map[string]struct{}, len(promptWeaverTags)+len(tools))
+	for name := range promptWeaverTags {
+		names[name] = struct{}{}
+	}
+	for _, tool := range tools {
+		names[tool.Name] = struct{}{}
+		for _, alias := range tool.Aliases {
+			names[alias] = struct{}{}
+		}
+	}
+	return names
+}
+
+// toolPromptLines describes external tools in the translation prompt.
+func toolPromptLines(tools []Tool) string {
+	var b strings.Builder
+	for _, tool := range tools {
+		usage := tool.Usage
+		if usage == "" {
+			usage = "<" + tool.Name + "></" + tool.Name + ">"
+		}
+		b.WriteString("  - " + usage)
+		if description := strings.Join(strings.Fields(tool.Description), " "); description != "" {
+			b.WriteString(" — " + description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
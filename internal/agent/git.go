@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultGitLogLimit = 20
+	maxGitLogLimit     = 200
+)
+
+// gitSafetyArgs neutralise configuration that could run arbitrary programs,
+// since the agent can write files inside the workspace. Filter drivers are
+// switched off separately by filterSafetyArgs, and the write tools refuse
+// to touch .git, so the repository config cannot gain new ones.
+var gitSafetyArgs = []string{
+	"--no-pager",
+	"--no-optional-locks",
+	"-c", "core.fsmonitor=false",
+	"-c", "core.hooksPath=/dev/null",
+	"-c", "core.pager=cat",
+	"-c", "core.attributesFile=/dev/null",
+	"-c", "diff.external=",
+	"-c", "protocol.allow=never",
+}
+
+// gitSafetyEnv keeps git from reading the system and user config and the
+// system attributes, which the sandbox does not otherwise hide.
+var gitSafetyEnv = []string{
+	"GIT_CONFIG_NOSYSTEM=1",
+	"GIT_CONFIG_GLOBAL=/dev/null",
+	"GIT_ATTR_NOSYSTEM=1",
+}
+
+var gitRefPattern = regexp.MustCompile(`^[A-Za-z0-9._/~^@{}-]+$`)
+
+type GitDiffOptions struct {
+	Path   string
+	Ref    string
+	Staged bool
+	Stat   bool
+}
+
+func GitStatus(ctx context.Context, env Env) (string, error) {
+	return runGit(ctx, env, "status", "--short", "--branch")
+}
+
+func GitDiff(ctx context.Context, env Env, opts GitDiffOptions) (string, error) {
+	args := []string{"diff", "--no-ext-diff", "--no-textconv", "--no-color"}
+	if opts.Staged {
+		args = append(args, "--cached")
+	}
+	if opts.Stat {
+		args = append(args, "--stat")
+	}
+	if opts.Ref != "" {
+		if err := validateGitRef(opts.Ref); err != nil {
+			return "", err
+		}
+		args = append(args, opts.Ref)
+	}
+
+	path, err := gitPathArg(env.Workspace, opts.Path)
+	if err != nil {
+		return "", err
+	}
+	return runGit(ctx, env, append(args, "--", path)...)
+}
+
+func GitLog(ctx context.Context, env Env, path string, limit int) (string, error) {
+	if limit <= 0 {
+		limit = defaultGitLogLimit
+	}
+	limit = min(limit, maxGitLogLimit)
+
+	pathArg, err := gitPathArg(env.Workspace, path)
+	if err != nil {
+		return "", err
+	}
+	return runGit(ctx, env, "log", "--no-color", "--date=short", "--format=%h %ad %an %s", "-n", strconv.Itoa(limit), "--", pathArg)
+}
+
+func GitBlame(ctx context.Context, env Env, path string, start, end int) (string, error) {
+	if path == "" {
+		return "", errors.New("git-blame requires a path")
+	}
+	pathArg, err := gitPathArg(env.Workspace, path)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"blame", "--no-textconv", "--date=short"}
+	if start > 0 {
+		if end < start {
+			end = start
+		}
+		args = append(args, "-L", fmt.Sprintf("%d,%d", start, end))
+	}
+	return runGit(ctx, env, append(args, "--", pathArg)...)
+}
+
+func runGit(ctx context.Context, env Env, args ...string) (string, error) {
+	filters, err := filterSafetyArgs(ctx, env)
+	if err != nil {
+		return "", err
+	}
+	argv := append(append([]string{"git"}, gitSafetyArgs...), filters...)
+	out, err := runCommandEnv(ctx, append(argv, args...), env, gitSafetyEnv)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, out)
+	}
+	return out, nil
+}
+
+// filterSafetyArgs empties the commands of every filter driver in the
+// repository config. .gitattributes in the worktree still names the
+// drivers, but git skips a driver without a command.
+func filterSafetyArgs(ctx context.Context, env Env) ([]string, error) {
+	argv := append(append([]string{"git"}, gitSafetyArgs...), "config", "--name-only", "--get-regexp", `^filter\.`)
+	out, err := runCommandEnv(ctx, argv, env, gitSafetyEnv)
+	if exitCode(err) == 1 {
+		// No filters configured.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, out)
+	}
+
+	var args []string
+	seen := map[string]bool{}
+	for _, key := range strings.Fields(out) {
+		name := strings.TrimPrefix(key, "filter.")
+		if i := strings.LastIndex(name, "."); i > 0 {
+			name = name[:i]
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		for _, setting := range []string{"clean=", "smudge=", "process=", "required=false"} {
+			args = append(args, "-c", "filter."+name+"."+setting)
+		}
+	}
+	return args, nil
+}
+
+// gitPathArg confines path to the workspace and returns it relative to the
+// workspace, which is the directory git runs in.
+func gitPathArg(workspace, path string) (string, error) {
+	safePath, err := ResolveWorkspacePath(workspace, path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(filepath.Clean(workspace), safePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func validateGitRef(ref string) error {
+	if ref[0] == '-' || !gitRefPattern.MatchString(ref) {
+		return fmt.Errorf("invalid git ref %q", ref)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+	git("add", "main.go")
+	git("commit", "-q", "-m", "initial commit")
+	return dir
+}
+
+func TestGitToolsReportRepositoryState(t *testing.T) {
+	t.Parallel()
+
+	workspace := initGitRepo(t)
+	env := Env{Workspace: workspace}
+	if err := os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("modify main.go: %v", err)
+	}
+
+	status, err := GitStatus(context.Background(), env)
+	if err != nil {
+		t.Fatalf("GitStatus returned error: %v", err)
+	}
+	if !strings.Contains(status, " M main.go") {
+		t.Fatalf("expected modified main.go in status, got %q", status)
+	}
+
+	diff, err := GitDiff(context.Background(), env, GitDiffOptions{Path: "main.go"})
+	if err != nil {
+		t.Fatalf("GitDiff returned error: %v", err)
+	}
+	if !strings.Contains(diff, "+func main() {}") {
+		t.Fatalf("expected added line in diff, got %q", diff)
+	}
+
+	log, err := GitLog(context.Background(), env, "", 5)
+	if err != nil {
+		t.Fatalf("GitLog returned error: %v", err)
+	}
+	if !strings.Contains(log, "initial commit") {
+		t.Fatalf("expected commit subject in log, got %q", log)
+	}
+
+	blame, err := GitBlame(context.Background(), env, "main.go", 1, 1)
+	if err != nil {
+		t.Fatalf("GitBlame returned error: %v", err)
+	}
+	if !strings.Contains(blame, "package main") {
+		t.Fatalf("expected blamed line, got %q", blame)
+	}
+}
+
+func TestGitToolsRejectUnsafeArguments(t *testing.T) {
+	t.Parallel()
+
+	env := Env{Workspace: t.TempDir()}
+	if _, err := GitDiff(context.Background(), env, GitDiffOptions{Ref: "--output=/tmp/x"}); err == nil {
+		t.Fatal("expected option-like ref to be rejected")
+	}
+	if _, err := GitBlame(context.Background(), env, "../outside.go", 0, 0); err == nil {
+		t.Fatal("expected path escape to be rejected")
+	}
+}
+
+func TestGitToolsNeverRunFilterDrivers(t *testing.T) {
+	t.Parallel()
+
+	workspace := initGitRepo(t)
+	marker := filepath.Join(t.TempDir(), "filter-ran")
+	config := exec.Command("git", "config", "filter.evil.clean", "touch "+marker+"; cat")
+	config.Dir = workspace
+	if out, err := config.CombinedOutput(); err != nil {
+		t.Fatalf("git config: %v: %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, ".gitattributes"), []byte("*.go filter=evil\n"), 0644); err != nil {
+		t.Fatalf("write .gitattributes: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("modify main.go: %v", err)
+	}
+
+	env := Env{Workspace: workspace, Sandbox: SandboxOff}
+	if _, err := GitStatus(context.Background(), env); err != nil {
+		t.Fatalf("GitStatus returned error: %v", err)
+	}
+	if _, err := GitDiff(context.Background(), env, GitDiffOptions{Path: "main.go"}); err != nil {
+		t.Fatalf("GitDiff returned error: %v", err)
+	}
+	if _, err := GitBlame(context.Background(), env, "main.go", 0, 0); err != nil {
+		t.Fatalf("GitBlame returned error: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("a filter driver from the repository config ran")
+	}
+}
+
+func TestWriteToolsRefuseGitDirectory(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	for _, path := range []string{".git/config", "sub/.git/hooks/pre-commit", ".GIT/config"} {
+		if _, err := SecureWritePath(workspace, path); err == nil {
+			t.Fatalf("expected a write to %s to be refused", path)
+		}
+	}
+	if _, err := SecureWritePath(workspace, ".gitattributes"); err != nil {
+		t.Fatalf("SecureWritePath returned error for .gitattributes: %v", err)
+	}
+
+	patch := "--- /dev/null\n+++ b/.git/config\n@@ -0,0 +1 @@\n+[filter \"evil\"]\n"
+	if _, err := ApplyPatch(patch, workspace); err == nil {
+		t.Fatal("expected apply-patch into .git to be refused")
+	}
+}
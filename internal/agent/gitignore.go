@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one line of a .gitignore file, relative to the directory the
+// file lives in.
+type ignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreMatcher accumulates .gitignore rules while the walker descends.
+// Later rules win, so rules from deeper directories override their parents.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadDir appends the rules of dir/.gitignore, where dir is relative to the
+// workspace root, and returns the matcher to use below dir.
+func (m *ignoreMatcher) loadDir(root, dir string) *ignoreMatcher {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+
+	next := &ignoreMatcher{rules: append([]ignoreRule(nil), m.rules...)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(dir, scanner.Text()); ok {
+			next.rules = append(next.rules, rule)
+		}
+	}
+	return next
+}
+
+func parseIgnoreLine(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+	return rule, true
+}
+
+// ignored reports whether rel, a slash-separated path relative to the
+// workspace root, is excluded.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if rule.base != "." {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+
+		if matchGlob(rule.pattern, target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchGlob matches a slash-separated name against pattern, where a "**"
+// segment matches any number of path segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], parts[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// validGlob reports whether every segment of pattern is well formed.
+func validGlob(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,11 @@
+package agent
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	MaybeRunSandboxHelper()
+	os.Exit(m.Run())
+}
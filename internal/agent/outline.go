@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Symbol is one entry of a file outline.
+type Symbol struct {
+	Line  int
+	Kind  string
+	Name  string
+	Depth int
+}
+
+// FileOutline lists the top-level symbols of a Go, Python or JavaScript/
+// TypeScript file so the agent can read large files by range.
+func FileOutline(path string) (string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var symbols []Symbol
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		symbols, err = goOutline(path, src)
+		if err != nil {
+			return "", err
+		}
+	case ".py":
+		symbols = regexOutline(src, pythonOutlinePatterns)
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx":
+		symbols = regexOutline(src, jsOutlinePatterns)
+	default:
+		return "", fmt.Errorf("outline is not supported for %s files", filepath.Ext(path))
+	}
+
+	if len(symbols) == 0 {
+		return "No symbols found.", nil
+	}
+
+	var out strings.Builder
+	for _, symbol := range symbols {
+		fmt.Fprintf(&out, "%6d\t%s%s %s\n", symbol.Line, strings.Repeat("  ", symbol.Depth), symbol.Kind, symbol.Name)
+	}
+	return out.String(), nil
+}
+
+func goOutline(path string, src []byte) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			kind := "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				kind = "method"
+				name = "(" + receiverType(d.Recv.List[0].Type) + ") " + name
+			}
+			symbols = append(symbols, Symbol{Line: fset.Position(d.Pos()).Line, Kind: kind, Name: name})
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, Symbol{Line: fset.Position(s.Pos()).Line, Kind: "type", Name: s.Name.Name})
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{Line: fset.Position(name.Pos()).Line, Kind: d.Tok.String(), Name: name.Name})
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	default:
+		return "?"
+	}
+}
+
+type outlinePattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+var pythonOutlinePatterns = []outlinePattern{
+	{"class", regexp.MustCompile(`^(\s*)class\s+([A-Za-z_]\w*)`)},
+	{"def", regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+([A-Za-z_]\w*)`)},
+}
+
+var jsOutlinePatterns = []outlinePattern{
+	{"class", regexp.MustCompile(`^(\s*)(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)},
+	{"function", regexp.MustCompile(`^(\s*)(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)},
+	{"const", regexp.MustCompile(`^(\s*)(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|[A-Za-z_$][\w$]*\s*=>)`)},
+	{"interface", regexp.MustCompile(`^(\s*)(?:export\s+)?interface\s+([A-Za-z_$][\w$]*)`)},
+	{"type", regexp.MustCompile(`^(\s*)(?:export\s+)?type\s+([A-Za-z_$][\w$]*)\s*=`)},
+	{"method", regexp.MustCompile(`^(\s+)(?:static\s+)?(?:async\s+)?(?:get\s+|set\s+)?([A-Za-z_$][\w$]*)\s*\([^)]*\)\s*\{`)},
+}
+
+var jsKeywords = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "function": true}
+
+// regexOutline matches declarations line by line and derives nesting from
+// indentation, which is enough for conventionally formatted code.
+func regexOutline(src []byte, patterns []outlinePattern) []Symbol {
+	var symbols []Symbol
+	var indents []int
+
+	for i, line := range strings.Split(string(src), "\n") {
+		for _, pattern := range patterns {
+			m := pattern.re.FindStringSubmatch(line)
+			if m == nil || jsKeywords[m[2]] {
+				continue
+			}
+
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			for len(indents) > 0 && indents[len(indents)-1] >= indent {
+				indents = indents[:len(indents)-1]
+			}
+			symbols = append(symbols, Symbol{Line: i + 1, Kind: pattern.kind, Name: m[2], Depth: len(indents)})
+			indents = append(indents, indent)
+			break
+		}
+	}
+	return symbols
+}
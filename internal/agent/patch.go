@@ -0,0 +1,473 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPatchFuzz is how many context lines may be dropped from each end of a
+// hunk when it does not apply as written.
+const maxPatchFuzz = 2
+
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+type Hunk struct {
+	Header   string
+	OldStart int
+	Lines    []HunkLine
+	// NoNewlineOld and NoNewlineNew record "\ No newline at end of file".
+	NoNewlineOld bool
+	NoNewlineNew bool
+}
+
+type HunkLine struct {
+	Op   byte // ' ', '-' or '+'
+	Text string
+}
+
+// PatchError names the file and hunk that could not be applied.
+type PatchError struct {
+	Path   string
+	Hunk   int
+	Header string
+	Reason string
+}
+
+func (e *PatchError) Error() string {
+	if e.Hunk == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+	}
+	return fmt.Sprintf("%s: hunk %d (%s) failed: %s", e.Path, e.Hunk, e.Header, e.Reason)
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch reads a unified diff touching one or more files. Git headers
+// and other lines outside of hunks are ignored. A hunk runs until the next
+// hunk or file header rather than for the line counts in its header, which
+// model-written diffs often get wrong.
+func ParsePatch(patch string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var files []FilePatch
+	var file *FilePatch
+	var hunk *Hunk
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		body := hunk != nil && isHunkBody(lines, i)
+
+		switch {
+		case body && (strings.HasPrefix(line, " ") || line == ""):
+			hunk.Lines = append(hunk.Lines, HunkLine{Op: ' ', Text: strings.TrimPrefix(line, " ")})
+		case body && strings.HasPrefix(line, "-"):
+			hunk.Lines = append(hunk.Lines, HunkLine{Op: '-', Text: line[1:]})
+		case body && strings.HasPrefix(line, "+"):
+			hunk.Lines = append(hunk.Lines, HunkLine{Op: '+', Text: line[1:]})
+		case strings.HasPrefix(line, `\ `):
+			if hunk == nil || len(hunk.Lines) == 0 {
+				continue
+			}
+			switch hunk.Lines[len(hunk.Lines)-1].Op {
+			case '-':
+				hunk.NoNewlineOld = true
+			case '+':
+				hunk.NoNewlineNew = true
+			default:
+				hunk.NoNewlineOld = true
+				hunk.NoNewlineNew = true
+			}
+		case isFileHeader(lines, i):
+			files = append(files, FilePatch{
+				OldPath: patchPath(line[4:]),
+				NewPath: patchPath(lines[i+1][4:]),
+			})
+			file = &files[len(files)-1]
+			hunk = nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if file == nil {
+				return nil, fmt.Errorf("patch line %d: hunk without a file header", i+1)
+			}
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("patch line %d: malformed hunk header %q", i+1, line)
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			file.Hunks = append(file.Hunks, Hunk{Header: strings.TrimSpace(m[0]), OldStart: oldStart})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		default:
+			// Anything else, such as a git "diff" or "index" line, ends the hunk.
+			hunk = nil
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("patch contains no file headers")
+	}
+	for _, f := range files {
+		if len(f.Hunks) == 0 {
+			return nil, fmt.Errorf("%s: patch has no hunks", f.displayPath())
+		}
+		for n, h := range f.Hunks {
+			if len(h.Lines) == 0 {
+				return nil, &PatchError{Path: f.displayPath(), Hunk: n + 1, Header: h.Header, Reason: "hunk has no lines"}
+			}
+		}
+	}
+	return files, nil
+}
+
+// isFileHeader reports whether lines[i] starts a "--- old" "+++ new" pair.
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// isHunkBody reports whether lines[i] continues the current hunk. Blank
+// lines count as context when more of the hunk follows them, since models
+// often drop the space in front of an empty context line.
+func isHunkBody(lines []string, i int) bool {
+	line := lines[i]
+	switch {
+	case isFileHeader(lines, i):
+		return false
+	case line == "":
+		for j := i + 1; j < len(lines); j++ {
+			if lines[j] != "" {
+				return isHunkBody(lines, j)
+			}
+		}
+		return false
+	}
+	return line[0] == ' ' || line[0] == '-' || line[0] == '+'
+}
+
+// patchPath strips the timestamp and a/ or b/ prefix from a header path.
+func patchPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+	return path
+}
+
+func (f FilePatch) displayPath() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+type patchResult struct {
+	path    string
+	content string
+	mode    os.FileMode
+	remove  bool
+	// renamedFrom is the old path of a renamed file, removed once the new
+	// one is written.
+	renamedFrom string
+}
+
+// ApplyPatch applies a multi-file unified diff inside workspace. Every hunk
+// is resolved in memory first, so either all files change or none do.
+func ApplyPatch(patch, workspace string) ([]string, error) {
+	files, err := ParsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []patchResult
+	seen := map[string]bool{}
+	for _, file := range files {
+		result, err := resolveFilePatch(file, workspace)
+		if err != nil {
+			return nil, err
+		}
+		// Every result is resolved against the files on disk, so a second
+		// entry for a path would silently replace the first one's changes.
+		for _, path := range []string{result.path, result.renamedFrom} {
+			if path == "" {
+				continue
+			}
+			if seen[path] {
+				return nil, &PatchError{Path: file.displayPath(), Reason: "file appears more than once in the patch"}
+			}
+			seen[path] = true
+		}
+		results = append(results, result)
+	}
+
+	if err := commitPatchResults(results); err != nil {
+		return nil, err
+	}
+
+	summary := make([]string, 0, len(results))
+	for i, result := range results {
+		action := "patched"
+		switch {
+		case result.remove:
+			action = "deleted"
+		case files[i].OldPath == "":
+			action = "created"
+		case result.renamedFrom != "":
+			action = "renamed " + files[i].OldPath + " to"
+		}
+		summary = append(summary, fmt.Sprintf("%s %s (%d hunks)", action, files[i].displayPath(), len(files[i].Hunks)))
+	}
+	return summary, nil
+}
+
+func resolveFilePatch(file FilePatch, workspace string) (patchResult, error) {
+	display := file.displayPath()
+	path, err := SecureWritePath(workspace, display)
+	if err != nil {
+		return patchResult{}, &PatchError{Path: display, Reason: err.Error()}
+	}
+	result := patchResult{path: path, mode: 0644, remove: file.NewPath == ""}
+	source := path
+	if file.OldPath != "" && file.OldPath != display {
+		source, err = SecureWritePath(workspace, file.OldPath)
+		if err != nil {
+			return patchResult{}, &PatchError{Path: file.OldPath, Reason: err.Error()}
+		}
+		if _, err := os.Stat(path); err == nil {
+			return patchResult{}, &PatchError{Path: display, Reason: "rename target already exists"}
+		}
+		result.renamedFrom = source
+	}
+
+	var original string
+	if file.OldPath != "" {
+		info, err := os.Stat(source)
+		if err != nil {
+			return patchResult{}, &PatchError{Path: file.OldPath, Reason: err.Error()}
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return patchResult{}, &PatchError{Path: file.OldPath, Reason: err.Error()}
+		}
+		original = string(data)
+		result.mode = info.Mode()
+	} else if _, err := os.Stat(path); err == nil {
+		return patchResult{}, &PatchError{Path: display, Reason: "file to create already exists"}
+	}
+
+	lines, trailingNewline := splitPatchLines(original)
+	if file.OldPath == "" {
+		trailingNewline = true
+	}
+
+	offset := 0
+	for i, hunk := range file.Hunks {
+		var pos int
+		lines, pos, err = applyHunk(lines, hunk, offset)
+		if err != nil {
+			return patchResult{}, &PatchError{Path: display, Hunk: i + 1, Header: hunk.Header, Reason: err.Error()}
+		}
+		offset = pos - (hunk.OldStart - 1)
+		if hunk.NoNewlineNew {
+			trailingNewline = false
+		} else if hunk.NoNewlineOld {
+			trailingNewline = true
+		}
+	}
+
+	if !result.remove {
+		result.content = strings.Join(lines, "\n")
+		if trailingNewline && len(lines) > 0 {
+			result.content += "\n"
+		}
+	}
+	return result, nil
+}
+
+func splitPatchLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+	trailingNewline := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), trailingNewline
+}
+
+// applyHunk locates the hunk's old lines near their expected position,
+// relaxing whitespace and then dropping edge context lines until it fits.
+// It returns the new lines and the index where the hunk matched.
+func applyHunk(lines []string, hunk Hunk, offset int) ([]string, int, error) {
+	expected := max(hunk.OldStart-1+offset, 0)
+	if hunk.OldStart == 0 {
+		expected = 0
+	}
+
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		body, lead, ok := trimContext(hunk.Lines, fuzz)
+		if !ok {
+			break
+		}
+
+		var old, replacement []string
+		for _, line := range body {
+			if line.Op != '+' {
+				old = append(old, line.Text)
+			}
+			if line.Op != '-' {
+				replacement = append(replacement, line.Text)
+			}
+		}
+
+		if len(old) == 0 {
+			at := min(expected+lead, len(lines))
+			return splice(lines, at, 0, replacement), at - lead, nil
+		}
+
+		for _, normalize := range []func(string) string{
+			func(s string) string { return s },
+			func(s string) string { return strings.TrimRight(s, " \t") },
+			strings.TrimSpace,
+		} {
+			if at, ok := findLines(lines, old, expected+lead, normalize); ok {
+				return splice(lines, at, len(old), replacement), at - lead, nil
+			}
+		}
+	}
+
+	return nil, 0, fmt.Errorf("context not found near line %d", hunk.OldStart)
+}
+
+// trimContext drops up to fuzz context lines from each end of the hunk.
+func trimContext(lines []HunkLine, fuzz int) ([]HunkLine, int, bool) {
+	lead, trail := 0, 0
+	for lead < fuzz && lead < len(lines) && lines[lead].Op == ' ' {
+		lead++
+	}
+	for trail < fuzz && len(lines)-trail-1 > lead && lines[len(lines)-trail-1].Op == ' ' {
+		trail++
+	}
+	if fuzz > 0 && lead == 0 && trail == 0 {
+		return nil, 0, false
+	}
+	return lines[lead : len(lines)-trail], lead, true
+}
+
+// findLines returns the index of old in lines closest to expected.
+func findLines(lines, old []string, expected int, normalize func(string) string) (int, bool) {
+	matchesAt := func(at int) bool {
+		if at < 0 || at+len(old) > len(lines) {
+			return false
+		}
+		for i, want := range old {
+			if normalize(lines[at+i]) != normalize(want) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for delta := 0; delta <= len(lines); delta++ {
+		if matchesAt(expected - delta) {
+			return expected - delta, true
+		}
+		if delta > 0 && matchesAt(expected+delta) {
+			return expected + delta, true
+		}
+	}
+	return 0, false
+}
+
+func splice(lines []string, at, remove int, insert []string) []string {
+	out := make([]string, 0, len(lines)-remove+len(insert))
+	out = append(out, lines[:at]...)
+	out = append(out, insert...)
+	return append(out, lines[at+remove:]...)
+}
+
+// commitPatchResults writes every result through a temp file and rename,
+// restoring the files already replaced if a later one fails.
+func commitPatchResults(results []patchResult) error {
+	type backup struct {
+		path    string
+		content []byte
+		mode    os.FileMode
+		existed bool
+	}
+	var done []backup
+
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			b := done[i]
+			if b.existed {
+				_ = os.WriteFile(b.path, b.content, b.mode)
+			} else {
+				_ = os.Remove(b.path)
+			}
+		}
+	}
+
+	change := func(path string, remove bool, content string, mode os.FileMode) error {
+		b := backup{path: path, mode: mode}
+		if data, err := os.ReadFile(path); err == nil {
+			b.content, b.existed = data, true
+		}
+
+		var err error
+		if remove {
+			err = os.Remove(path)
+		} else {
+			err = writeFileAtomic(path, []byte(content), mode)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("apply patch to %s: %w", path, err)
+		}
+		done = append(done, b)
+		return nil
+	}
+
+	for _, result := range results {
+		if err := change(result.path, result.remove, result.content, result.mode); err != nil {
+			return err
+		}
+		if result.renamedFrom != "" {
+			if err := change(result.renamedFrom, true, "", result.mode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".vyai-patch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode.Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func TestApplyPatchAcrossFiles(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		"a.go":    "package a\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int {\n\treturn 1\n}\n",
+		"old.txt": "remove me\n",
+	})
+
+	patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -7,3 +7,3 @@
+ func B() int {
+-	return 1
++	return 2
+ }
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++content
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-remove me
+`
+	applied, err := ApplyPatch(patch, workspace)
+	if err != nil {
+		t.Fatalf("ApplyPatch returned error: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Fatalf("expected 3 files in summary, got %v", applied)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workspace, "a.go"))
+	if !strings.Contains(string(data), "func A() int {\n\treturn 1\n}") || !strings.Contains(string(data), "func B() int {\n\treturn 2\n}") {
+		t.Fatalf("expected only the second return to change, got:\n%s", data)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "docs", "new.md")); err != nil || string(data) != "# New\ncontent\n" {
+		t.Fatalf("unexpected new file: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "old.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected old.txt to be deleted, stat err: %v", err)
+	}
+}
+
+func TestApplyPatchFuzzyMatchesShiftedAndReindentedHunks(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		"main.go": "// header\n// more header\npackage main\n\nfunc main() {\n    println(\"hi\")\n}\n",
+	})
+
+	patch := `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ func main() {
+-	println("hi")
++	println("hello")
+ }
+`
+	if _, err := ApplyPatch(patch, workspace); err != nil {
+		t.Fatalf("ApplyPatch returned error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(workspace, "main.go"))
+	if !strings.Contains(string(data), "\tprintln(\"hello\")") {
+		t.Fatalf("expected fuzzy hunk to apply, got:\n%s", data)
+	}
+}
+
+func TestApplyPatchIsAtomicAndReportsFailingHunk(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		"a.txt": "one\ntwo\n",
+		"b.txt": "three\nfour\n",
+	})
+
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+--- a/b.txt
++++ b/b.txt
+@@ -1,2 +1,2 @@
+ three
+-four
++FOUR
+@@ -5,2 +5,2 @@
+-missing
++line
+ here
+`
+	_, err := ApplyPatch(patch, workspace)
+	var patchErr *PatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("expected PatchError, got %v", err)
+	}
+	if patchErr.Path != "b.txt" || patchErr.Hunk != 2 {
+		t.Fatalf("expected hunk 2 of b.txt to fail, got %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workspace, "a.txt"))
+	if string(data) != "one\ntwo\n" {
+		t.Fatalf("expected a.txt to be untouched, got %q", data)
+	}
+}
+
+func TestApplyPatchRejectsPathEscape(t *testing.T) {
+	t.Parallel()
+
+	patch := "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+	if _, err := ApplyPatch(patch, t.TempDir()); err == nil {
+		t.Fatal("expected path escape to be rejected")
+	}
+}
+
+func TestApplyPatchIgnoresMiscountedHunkHeaders(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		"a.txt": "one\ntwo\n",
+		"b.txt": "three\nfour\n\nfive\n",
+	})
+
+	// The first header counts one line too few, the second too many.
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++TWO
++EXTRA
+--- a/b.txt
++++ b/b.txt
+@@ -1,9 +1,9 @@
+ three
+ four
+
+-five
++FIVE
+`
+	if _, err := ApplyPatch(patch, workspace); err != nil {
+		t.Fatalf("ApplyPatch returned error: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"a.txt": "one\nTWO\nEXTRA\n",
+		"b.txt": "three\nfour\n\nFIVE\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(workspace, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Fatalf("unexpected %s: %q", name, data)
+		}
+	}
+}
+
+func TestApplyPatchRenamesFiles(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{"old.go": "package a\n\nconst N = 1\n"})
+
+	patch := `diff --git a/old.go b/pkg/new.go
+--- a/old.go
++++ b/pkg/new.go
+@@ -1,3 +1,3 @@
+ package a
+ 
+-const N = 1
++const N = 2
+`
+	applied, err := ApplyPatch(patch, workspace)
+	if err != nil {
+		t.Fatalf("ApplyPatch returned error: %v", err)
+	}
+	if len(applied) != 1 || !strings.HasPrefix(applied[0], "renamed old.go to pkg/new.go") {
+		t.Fatalf("unexpected summary: %v", applied)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "pkg", "new.go")); err != nil || string(data) != "package a\n\nconst N = 2\n" {
+		t.Fatalf("unexpected renamed file: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "old.go")); !os.IsNotExist(err) {
+		t.Fatalf("expected old.go to be removed, stat err: %v", err)
+	}
+}
+
+func TestApplyPatchRejectsRepeatedFiles(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{"a.txt": "one\ntwo\n"})
+
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- a/a.txt
++++ b/a.txt
+@@ -2 +2 @@
+-two
++TWO
+`
+	_, err := ApplyPatch(patch, workspace)
+	var patchErr *PatchError
+	if !errors.As(err, &patchErr) || patchErr.Path != "a.txt" || !strings.Contains(patchErr.Reason, "more than once") {
+		t.Fatalf("expected a repeated file to be rejected, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "a.txt")); string(data) != "one\ntwo\n" {
+		t.Fatalf("expected a.txt to be untouched, got %q", data)
+	}
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var pluginNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// PluginManifest describes an executable that provides an agent section.
+// Manifests are JSON files in the plugin directory; Command is resolved
+// against that directory when it is a relative path.
+type PluginManifest struct {
+	Name        string            `json:"name"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Description string            `json:"description"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Attributes  []PluginAttribute `json:"attributes,omitempty"`
+	// Content describes what goes between the tags; empty when the section
+	// body is not used.
+	Content string `json:"content,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+type PluginAttribute struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PluginInput is the JSON a plugin receives on stdin.
+type PluginInput struct {
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes"`
+	Content    string            `json:"content"`
+	Workspace  string            `json:"workspace"`
+}
+
+// LoadPlugins reads every *.json manifest in dir. Invalid manifests, and
+// those whose names clash with built-in sections or earlier plugins, are
+// reported and skipped. A missing directory has no plugins.
+func LoadPlugins(dir string, env Env) ([]Tool, []error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+	sort.Strings(paths)
+
+	var tools []Tool
+	var errs []error
+	for _, path := range paths {
+		manifest, err := readPluginManifest(path)
+		if err == nil {
+			err = checkPluginNames(manifest, tools)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", filepath.Base(path), err))
+			continue
+		}
+		tools = append(tools, pluginTool(manifest, env))
+	}
+	return tools, errs
+}
+
+func readPluginManifest(path string) (PluginManifest, error) {
+	var manifest PluginManifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	if manifest.Command == "" {
+		return manifest, errors.New("command is required")
+	}
+	if !filepath.IsAbs(manifest.Command) && filepath.Base(manifest.Command) != manifest.Command {
+		manifest.Command = filepath.Join(filepath.Dir(path), manifest.Command)
+	}
+	if _, err := exec.LookPath(manifest.Command); err != nil {
+		return manifest, err
+	}
+	if manifest.Timeout != "" {
+		timeout, err := time.ParseDuration(manifest.Timeout)
+		if err != nil {
+			return manifest, fmt.Errorf("parse timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return manifest, errors.New("timeout must be positive")
+		}
+	}
+	for _, attr := range manifest.Attributes {
+		if attr.Name == "" {
+			return manifest, errors.New("attribute without a name")
+		}
+	}
+	return manifest, nil
+}
+
+func checkPluginNames(manifest PluginManifest, loaded []Tool) error {
+	reg := BuildRegistry(loaded...)
+	for _, name := range append([]string{manifest.Name}, manifest.Aliases...) {
+		if !pluginNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid section name %q (use lowercase letters, digits and dashes)", name)
+		}
+		if reg.IsAllowed(name) {
+			return fmt.Errorf("section name %q is already taken", name)
+		}
+	}
+	return nil
+}
+
+func pluginTool(manifest PluginManifest, env Env) Tool {
+	return Tool{
+		Name:        manifest.Name,
+		Aliases:     manifest.Aliases,
+		Usage:       pluginUsage(manifest),
+		Description: manifest.Description,
+		Run: func(ctx context.Context, attrs map[string]string, content string) (string, error) {
+			return runPlugin(ctx, manifest, env, attrs, content)
+		},
+	}
+}
+
+// pluginUsage renders an example tag listing the plugin's attributes.
+func pluginUsage(manifest PluginManifest) string {
+	var b strings.Builder
+	b.WriteString("<" + manifest.Name)
+	for _, attr := range manifest.Attributes {
+		value := attr.Description
+		if value == "" {
+			value = "..."
+		}
+		if !attr.Required {
+			value = "optional: " + value
+		}
+		fmt.Fprintf(&b, " %s=%q", attr.Name, value)
+	}
+	b.WriteString(">")
+	b.WriteString(manifest.Content)
+	b.WriteString("</" + manifest.Name + ">")
+	return b.String()
+}
+
+// runPlugin executes the plugin in the workspace with its input as JSON on
+// stdin. Plugins are configured by the user and trusted like MCP servers:
+// they keep the user's environment and are not sandboxed, but they share
+// the command timeout, output cap and cancellation of run-bash.
+func runPlugin(ctx context.Context, manifest PluginManifest, env Env, attrs map[string]string, content string) (string, error) {
+	for _, attr := range manifest.Attributes {
+		if attr.Required && strings.TrimSpace(attrs[attr.Name]) == "" {
+			return "", fmt.Errorf("missing attribute %q", attr.Name)
+		}
+	}
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	input, err := json.Marshal(PluginInput{
+		Name:       manifest.Name,
+		Attributes: attrs,
+		Content:    content,
+		Workspace:  env.Workspace,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	limits := env.Limits.withDefaults()
+	if manifest.Timeout != "" {
+		limits.Timeout, _ = time.ParseDuration(manifest.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, manifest.Command, manifest.Args...)
+	c.Dir = env.Workspace
+	c.Stdin = strings.NewReader(string(input))
+	return runLimited(ctx, c, limits)
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func writePlugin(t *testing.T, dir, name, manifest, script string) {
+	t.Helper()
+	testutil.WriteTree(t, dir, map[string]string{name + ".json": manifest})
+	if script != "" {
+		if err := os.WriteFile(filepath.Join(dir, name+".sh"), []byte(script), 0755); err != nil {
+			t.Fatalf("write plugin script: %v", err)
+		}
+	}
+}
+
+func TestLoadPluginsRunsExecutableWithJSONInput(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a unix shell")
+	}
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "inventory", `{
+  "name": "inventory",
+  "aliases": ["inv"],
+  "description": "Look up stock for a SKU.",
+  "command": "./inventory.sh",
+  "attributes": [{"name": "sku", "description": "SKU to look up", "required": true}]
+}`, "#!/bin/sh\necho \"stdin: $(cat)\"\n")
+
+	workspace := t.TempDir()
+	tools, errs := LoadPlugins(dir, Env{Workspace: workspace})
+	if len(errs) != 0 || len(tools) != 1 {
+		t.Fatalf("unexpected plugins %+v, errors %v", tools, errs)
+	}
+	if tools[0].Usage != `<inventory sku="SKU to look up"></inventory>` {
+		t.Fatalf("unexpected usage %q", tools[0].Usage)
+	}
+
+	var outputs []string
+	runner := NewLocalRunner(Env{Workspace: workspace, Tools: tools}, nil)
+	_, err := runner.Run(context.Background(), RunRequest{
+		Input: `<inv sku="A-1">note</inv><inventory></inventory><summary>done</summary>`,
+		OnEvent: func(ev Event) {
+			if ev.Kind == EventToolFinished {
+				outputs = append(outputs, ev.Output)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := `stdin: {"name":"inventory","attributes":{"sku":"A-1"},"content":"note","workspace":"` + workspace + `"}`
+	if len(outputs) != 2 || outputs[0] != want {
+		t.Fatalf("unexpected plugin outputs: %q", outputs)
+	}
+	if !strings.Contains(outputs[1], `missing attribute "sku"`) {
+		t.Fatalf("expected missing attribute error, got %q", outputs[1])
+	}
+}
+
+func TestLoadPluginsSkipsInvalidManifests(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "a-shadow", `{"name": "read-file", "command": "go"}`, "")
+	writePlugin(t, dir, "b-missing", `{"name": "missing", "command": "./nope.sh"}`, "")
+	writePlugin(t, dir, "c-bad-name", `{"name": "Bad_Name", "command": "go"}`, "")
+	writePlugin(t, dir, "d-ok", `{"name": "gover", "command": "go", "args": ["version"]}`, "")
+	writePlugin(t, dir, "e-duplicate", `{"name": "other", "aliases": ["gover"], "command": "go"}`, "")
+
+	tools, errs := LoadPlugins(dir, Env{Workspace: t.TempDir()})
+	if len(tools) != 1 || tools[0].Name != "gover" {
+		t.Fatalf("expected only the valid plugin, got %+v", tools)
+	}
+	if len(errs) != 4 || !strings.Contains(errs[0].Error(), `"read-file" is already taken`) || !strings.Contains(errs[3].Error(), `"gover" is already taken`) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if tools, errs := LoadPlugins(filepath.Join(dir, "absent"), Env{}); len(tools) != 0 || len(errs) != 0 {
+		t.Fatalf("expected no plugins for a missing directory, got %v %v", tools, errs)
+	}
+}
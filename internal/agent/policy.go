@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+const (
+	PathArgsNone     = ""
+	PathArgsOptional = "optional"
+	PathArgsRequired = "required"
+)
+
+// PolicyError explains why a command was refused so the agent can pick an
+// allowed alternative instead of retrying blindly.
+type PolicyError struct {
+	Command string
+	Reason  string
+	Allowed []string
+}
+
+func (e *PolicyError) Error() string {
+	msg := fmt.Sprintf("command %q denied: %s", e.Command, e.Reason)
+	if len(e.Allowed) > 0 {
+		msg += " (allowed: " + strings.Join(e.Allowed, ", ") + ")"
+	}
+	return msg
+}
+
+type commandRule struct {
+	command     string
+	subcommands []string
+	args        []*regexp.Regexp
+	paths       string
+}
+
+type CommandPolicy struct {
+	rules []commandRule
+}
+
+func DefaultCommandRules() []appconfig.CommandRule {
+	return []appconfig.CommandRule{
+		{Command: "ls", Paths: PathArgsOptional},
+		{Command: "cat", Paths: PathArgsRequired},
+		{Command: "gofmt", Paths: PathArgsOptional},
+		{Command: "goimports", Paths: PathArgsOptional},
+		{Command: "go", Subcommands: []string{"build", "test", "vet"}},
+	}
+}
+
+func DefaultCommandPolicy() *CommandPolicy {
+	policy, err := NewCommandPolicy(DefaultCommandRules())
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// PolicyFromConfig builds the command policy for one workspace: the built-in
+// rules unless the config replaces them, followed by the configured rules.
+func PolicyFromConfig(cfg appconfig.AgentPolicy) (*CommandPolicy, error) {
+	var rules []appconfig.CommandRule
+	if !cfg.ReplaceDefaults {
+		rules = append(rules, DefaultCommandRules()...)
+	}
+	rules = append(rules, cfg.Commands...)
+	return NewCommandPolicy(rules)
+}
+
+func NewCommandPolicy(rules []appconfig.CommandRule) (*CommandPolicy, error) {
+	policy := &CommandPolicy{}
+	for _, rule := range rules {
+		command := strings.TrimSpace(rule.Command)
+		if command == "" {
+			return nil, fmt.Errorf("command rule is missing a command")
+		}
+		if strings.ContainsAny(command, `/\`) {
+			return nil, fmt.Errorf("command rule %q must be a bare executable name", command)
+		}
+
+		switch rule.Paths {
+		case PathArgsNone, PathArgsOptional, PathArgsRequired:
+		default:
+			return nil, fmt.Errorf("command rule %q: unknown paths mode %q", command, rule.Paths)
+		}
+
+		compiled := commandRule{
+			command:     command,
+			subcommands: rule.Subcommands,
+			paths:       rule.Paths,
+		}
+		for _, pattern := range rule.Args {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("command rule %q: invalid argument pattern %q: %w", command, pattern, err)
+			}
+			compiled.args = append(compiled.args, re)
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+
+	return policy, nil
+}
+
+// Allowed describes every permitted command, e.g. "go build|test|vet".
+func (p *CommandPolicy) Allowed() []string {
+	allowed := make([]string, 0, len(p.rules))
+	for _, rule := range p.rules {
+		entry := rule.command
+		if len(rule.subcommands) > 0 {
+			entry += " " + strings.Join(rule.subcommands, "|")
+		}
+		allowed = append(allowed, entry)
+	}
+	return allowed
+}
+
+func (p *CommandPolicy) Allow(args []string) bool {
+	_, err := p.match(args)
+	return err == nil
+}
+
+// Prepare checks args against the policy and returns the argv to execute,
+// with path arguments rewritten to absolute paths inside the workspace.
+func (p *CommandPolicy) Prepare(args []string, workspace string) ([]string, error) {
+	rule, err := p.match(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.paths == PathArgsNone {
+		return args, nil
+	}
+	// Keep the executable and its subcommand out of the path rewrite.
+	fixed := 0
+	if len(rule.subcommands) > 0 {
+		fixed = 1
+	}
+	argv, err := rewritePathArgs(args[fixed:], workspace, rule.paths == PathArgsRequired)
+	if err != nil {
+		return nil, &PolicyError{Command: args[0], Reason: err.Error()}
+	}
+	return append(slices.Clone(args[:fixed]), argv...), nil
+}
+
+func (p *CommandPolicy) match(args []string) (commandRule, error) {
+	if len(args) == 0 {
+		return commandRule{}, &PolicyError{Reason: "empty command"}
+	}
+
+	reason := "not in the allowlist"
+	for _, rule := range p.rules {
+		if rule.command != args[0] {
+			continue
+		}
+		if err := rule.check(args[1:]); err != nil {
+			reason = err.Error()
+			continue
+		}
+		return rule, nil
+	}
+
+	return commandRule{}, &PolicyError{Command: args[0], Reason: reason, Allowed: p.Allowed()}
+}
+
+func (r commandRule) check(args []string) error {
+	if len(r.subcommands) > 0 {
+		if len(args) == 0 {
+			return fmt.Errorf("subcommand required, one of %s", strings.Join(r.subcommands, ", "))
+		}
+		if !slices.Contains(r.subcommands, args[0]) {
+			return fmt.Errorf("subcommand %q not allowed, use one of %s", args[0], strings.Join(r.subcommands, ", "))
+		}
+		args = args[1:]
+	}
+
+	for _, arg := range args {
+		if r.paths == PathArgsNone && isAbsoluteArg(arg) {
+			return fmt.Errorf("absolute paths are not allowed: %s", arg)
+		}
+		// Without path rewriting, a plain argument may be the value of the
+		// flag before it, as in -o ../bin, so it must not leave the
+		// workspace either.
+		if r.paths == PathArgsNone && !strings.HasPrefix(arg, "-") && escapesWorkspace(arg) {
+			return fmt.Errorf("arguments must be paths inside the workspace: %s", arg)
+		}
+		// Path rewriting skips flags, so a value such as --output=PATH must
+		// stay inside the workspace on its own.
+		if value, ok := flagValue(arg); ok && escapesWorkspace(value) {
+			return fmt.Errorf("flag values must be paths inside the workspace: %s", arg)
+		}
+		if len(r.args) > 0 && !matchesAny(r.args, arg) {
+			return fmt.Errorf("argument %q does not match the allowed patterns", arg)
+		}
+	}
+
+	return nil
+}
+
+func isAbsoluteArg(arg string) bool {
+	if filepath.IsAbs(arg) {
+		return true
+	}
+	if _, value, ok := strings.Cut(arg, "="); ok && filepath.IsAbs(value) {
+		return true
+	}
+	return false
+}
+
+// flagValue returns VALUE of a -x=VALUE or --x=VALUE argument.
+func flagValue(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", false
+	}
+	_, value, ok := strings.Cut(arg, "=")
+	return value, ok
+}
+
+// escapesWorkspace reports whether value, read as a path, is absolute or
+// climbs out of the directory it is relative to.
+func escapesWorkspace(value string) bool {
+	if filepath.IsAbs(value) || strings.HasPrefix(value, "~") {
+		return true
+	}
+	return slices.Contains(strings.Split(filepath.ToSlash(value), "/"), "..")
+}
+
+func matchesAny(patterns []*regexp.Regexp, arg string) bool {
+	for _, re := range patterns {
+		if re.MatchString(arg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+func TestCommandPolicyAllowsConfiguredSubcommands(t *testing.T) {
+	t.Parallel()
+
+	policy, err := PolicyFromConfig(appconfig.AgentPolicy{
+		Commands: []appconfig.CommandRule{
+			{Command: "git", Subcommands: []string{"status", "diff"}, Paths: PathArgsOptional},
+			{Command: "kubectl", Subcommands: []string{"get"}, Args: []string{`[a-z]+`, `-n`, `--namespace=[a-z-]+`}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PolicyFromConfig returned error: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"git", "status"},
+		{"kubectl", "get", "pods", "-n", "default"},
+		{"go", "test", "./..."},
+	} {
+		if !policy.Allow(args) {
+			t.Fatalf("expected %v to be allowed", args)
+		}
+	}
+	for _, args := range [][]string{
+		{"git", "push"},
+		{"kubectl", "get", "pods", "--kubeconfig=other"},
+		{"kubectl", "delete", "pod", "x"},
+		{"rm", "-rf", "."},
+	} {
+		if policy.Allow(args) {
+			t.Fatalf("expected %v to be denied", args)
+		}
+	}
+}
+
+func TestCommandPolicyReplaceDefaults(t *testing.T) {
+	t.Parallel()
+
+	policy, err := PolicyFromConfig(appconfig.AgentPolicy{
+		ReplaceDefaults: true,
+		Commands:        []appconfig.CommandRule{{Command: "make", Subcommands: []string{"test"}}},
+	})
+	if err != nil {
+		t.Fatalf("PolicyFromConfig returned error: %v", err)
+	}
+
+	if policy.Allow([]string{"ls"}) {
+		t.Fatal("expected default rules to be replaced")
+	}
+	if !policy.Allow([]string{"make", "test"}) {
+		t.Fatal("expected make test to be allowed")
+	}
+}
+
+func TestCommandPolicyPrepareRewritesPathsAfterSubcommand(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	policy, err := NewCommandPolicy([]appconfig.CommandRule{
+		{Command: "git", Subcommands: []string{"diff"}, Paths: PathArgsOptional},
+	})
+	if err != nil {
+		t.Fatalf("NewCommandPolicy returned error: %v", err)
+	}
+
+	argv, err := policy.Prepare([]string{"git", "diff", "--stat", "main.go"}, workspace)
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	want := []string{"git", "diff", "--stat", filepath.Join(workspace, "main.go")}
+	if strings.Join(argv, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected argv: %v", argv)
+	}
+}
+
+func TestCommandPolicyDenialListsAllowedCommands(t *testing.T) {
+	t.Parallel()
+
+	_, err := DefaultCommandPolicy().Prepare([]string{"go", "run", "."}, t.TempDir())
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected PolicyError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "go build|test|vet") {
+		t.Fatalf("expected allowed commands in denial, got %q", err.Error())
+	}
+}
+
+func TestNewCommandPolicyRejectsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []appconfig.CommandRule{
+		{Command: "/bin/sh"},
+		{Command: "make", Paths: "sometimes"},
+		{Command: "make", Args: []string{"("}},
+	} {
+		if _, err := NewCommandPolicy([]appconfig.CommandRule{rule}); err == nil {
+			t.Fatalf("expected rule %+v to be rejected", rule)
+		}
+	}
+}
+
+func TestCommandPolicyConfinesFlagValues(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	policy, err := NewCommandPolicy([]appconfig.CommandRule{
+		{Command: "git", Subcommands: []string{"diff"}, Paths: PathArgsOptional},
+		{Command: "go", Subcommands: []string{"test"}},
+	})
+	if err != nil {
+		t.Fatalf("NewCommandPolicy returned error: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"git", "diff", "--output=/home/u/.bashrc"},
+		{"git", "diff", "--output=../outside.txt"},
+		{"git", "diff", "--output=~/.bashrc"},
+		{"go", "test", "-coverprofile=/tmp/cover.out"},
+		{"go", "test", "-o=sub/../../bin"},
+		{"go", "test", "-o", "../../x"},
+		{"go", "test", "-coverprofile", "../cover.out"},
+		{"go", "test", "-o", "~/bin/x"},
+	} {
+		if _, err := policy.Prepare(args, workspace); err == nil {
+			t.Fatalf("expected %v to be denied", args)
+		}
+	}
+
+	if _, err := policy.Prepare([]string{"git", "diff", "--output=diff.txt"}, workspace); err != nil {
+		t.Fatalf("Prepare returned error for a workspace flag value: %v", err)
+	}
+	if _, err := policy.Prepare([]string{"go", "test", "-run=TestX", "./..."}, workspace); err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	if _, err := policy.Prepare([]string{"go", "test", "-o", "bin/x.test", "-run", "TestX", "./..."}, workspace); err != nil {
+		t.Fatalf("Prepare returned error for a separate workspace flag value: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const DefaultReadMaxBytes = 48 * 1024
+
+type ReadOptions struct {
+	// Start and End are 1-based inclusive line numbers; zero means the
+	// beginning and the end of the file.
+	Start    int
+	End      int
+	MaxBytes int
+}
+
+// ReadFile returns the requested line range with line numbers, stopping at
+// MaxBytes with a marker telling the agent where to continue.
+func ReadFile(path string, opts ReadOptions) (string, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultReadMaxBytes
+	}
+	if opts.Start <= 0 {
+		opts.Start = 1
+	}
+	if opts.End > 0 && opts.End < opts.Start {
+		return "", fmt.Errorf("end line %d is before start line %d", opts.End, opts.Start)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", errors.New("path is a directory, use list-dir")
+	}
+
+	reader := bufio.NewReader(f)
+	if sniff, _ := reader.Peek(binarySniffSize); bytes.IndexByte(sniff, 0) >= 0 {
+		return fmt.Sprintf("Binary file (%d bytes), not shown.", info.Size()), nil
+	}
+
+	var out strings.Builder
+	lineNo := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", err
+		}
+		lineNo++
+
+		if lineNo < opts.Start {
+			continue
+		}
+		if opts.End > 0 && lineNo > opts.End {
+			break
+		}
+
+		text := strings.TrimRight(line, "\r\n")
+		numbered := fmt.Sprintf("%6d\t%s\n", lineNo, text)
+		if out.Len() == 0 && len(numbered) > opts.MaxBytes {
+			// A single line over the budget, such as minified code, is cut
+			// so the agent always gets something and moves on past it.
+			out.WriteString(truncateLine(lineNo, text, opts.MaxBytes))
+			continue
+		}
+		if out.Len()+len(numbered) > opts.MaxBytes {
+			fmt.Fprintf(&out, "... [truncated at %d bytes, continue with start=\"%d\"]\n", opts.MaxBytes, lineNo)
+			return out.String(), nil
+		}
+		out.WriteString(numbered)
+	}
+
+	if lineNo < opts.Start && !(lineNo == 0 && opts.Start == 1) {
+		return "", fmt.Errorf("start line %d is past the end of the file (%d lines)", opts.Start, lineNo)
+	}
+	return out.String(), nil
+}
+
+// lineMarkerReserve leaves room for the marker truncateLine adds.
+const lineMarkerReserve = 64
+
+// truncateLine numbers line and cuts it to about maxBytes, on a UTF-8
+// boundary, with a marker saying how much is shown.
+func truncateLine(lineNo int, text string, maxBytes int) string {
+	prefix := fmt.Sprintf("%6d\t", lineNo)
+	keep := max(maxBytes-len(prefix)-lineMarkerReserve, 0)
+	for keep > 0 && !utf8.RuneStart(text[keep]) {
+		keep--
+	}
+	return fmt.Sprintf("%s%s ... [line truncated, %d of %d bytes shown]\n", prefix, text[:keep], keep, len(text))
+}
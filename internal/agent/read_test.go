@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFileReturnsNumberedRange(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	out, err := ReadFile(path, ReadOptions{Start: 2, End: 3})
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if out != "     2\ttwo\n     3\tthree\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	if _, err := ReadFile(path, ReadOptions{Start: 10}); err == nil {
+		t.Fatal("expected start past the end to fail")
+	}
+}
+
+func TestReadFileTruncatesAndSkipsBinary(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	large := filepath.Join(dir, "large.txt")
+	if err := os.WriteFile(large, []byte(strings.Repeat("0123456789\n", 100)), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	out, err := ReadFile(large, ReadOptions{MaxBytes: 64})
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if !strings.Contains(out, `[truncated at 64 bytes, continue with start="4"]`) {
+		t.Fatalf("expected truncation marker, got %q", out)
+	}
+
+	binary := filepath.Join(dir, "blob.bin")
+	if err := os.WriteFile(binary, []byte{0x7f, 'E', 'L', 'F', 0, 1, 2}, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	out, err = ReadFile(binary, ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if !strings.HasPrefix(out, "Binary file (7 bytes)") {
+		t.Fatalf("expected binary notice, got %q", out)
+	}
+}
+
+func TestReadFileCutsALineLongerThanTheBudget(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bundle.min.js")
+	long := strings.Repeat("x", 100*1024)
+	if err := os.WriteFile(path, []byte(long+"\n"+long+"\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	for _, start := range []int{1, 2} {
+		out, err := ReadFile(path, ReadOptions{Start: start})
+		if err != nil {
+			t.Fatalf("ReadFile returned error: %v", err)
+		}
+		if !strings.HasPrefix(out, fmt.Sprintf("%6d\txxx", start)) || !strings.Contains(out, "of 102400 bytes shown]") {
+			t.Fatalf("expected line %d cut with a marker, got %q", start, out[:min(len(out), 80)])
+		}
+		if len(out) > DefaultReadMaxBytes+128 {
+			t.Fatalf("expected output near the budget, got %d bytes", len(out))
+		}
+		// The continuation must point past the line that was cut.
+		if start == 1 && !strings.HasSuffix(out, `continue with start="2"]`+"\n") {
+			t.Fatalf("expected continuation at line 2, got %q", out[len(out)-80:])
+		}
+		if start == 2 && strings.Contains(out, "continue with") {
+			t.Fatalf("expected no continuation after the last line, got %q", out[len(out)-80:])
+		}
+	}
+}
+
+func TestFileOutlineListsSymbols(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go": "package main\n\ntype Server struct{}\n\nconst Port = 80\n\nfunc (s *Server) Start() {}\n\nfunc main() {}\n",
+		"app.py":  "class App:\n    def run(self):\n        pass\n\nasync def main():\n    pass\n",
+		"app.ts":  "export class Store {\n  load(id) {\n    if (id) {}\n  }\n}\nexport const handler = async (req) => {}\n",
+	}
+	want := map[string][]string{
+		"main.go": {"3\ttype Server", "5\tconst Port", "7\tmethod (*Server) Start", "9\tfunc main"},
+		"app.py":  {"1\tclass App", "2\t  def run", "5\tdef main"},
+		"app.ts":  {"1\tclass Store", "2\t  method load", "6\tconst handler"},
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		out, err := FileOutline(path)
+		if err != nil {
+			t.Fatalf("FileOutline(%s) returned error: %v", name, err)
+		}
+		for _, entry := range want[name] {
+			if !strings.Contains(out, entry) {
+				t.Fatalf("expected %q in %s outline:\n%s", entry, name, out)
+			}
+		}
+		if strings.Contains(out, " if") {
+			t.Fatalf("expected control flow to be ignored in %s outline:\n%s", name, out)
+		}
+	}
+}
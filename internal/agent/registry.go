@@ -2,7 +2,9 @@ package agent
 
 import "github.com/grahms/promptweaver"
 
-func BuildRegistry() *promptweaver.Registry {
+// BuildRegistry registers the built-in sections followed by any external
+// tools.
+func BuildRegistry(tools ...Tool) *promptweaver.Registry {
 	reg := promptweaver.NewRegistry()
 
 	reg.Register(promptweaver.SectionPlugin{Name: "think"})
@@ -15,11 +17,18 @@ func BuildRegistry() *promptweaver.Registry {
 		Aliases: []string{"write-file"},
 	})
 	reg.Register(promptweaver.SectionPlugin{Name: "read-file", Aliases: []string{"view-file"}})
+	reg.Register(promptweaver.SectionPlugin{Name: "file-outline", Aliases: []string{"outline"}})
 	reg.Register(promptweaver.SectionPlugin{Name: "list-dir", Aliases: []string{"ls"}})
 	reg.Register(promptweaver.SectionPlugin{Name: "grep-file", Aliases: []string{"search-file"}})
 	reg.Register(promptweaver.SectionPlugin{Name: "glob-file", Aliases: []string{"find-file"}})
 	reg.Register(promptweaver.SectionPlugin{Name: "edit-file", Aliases: []string{"update-file"}})
+	reg.Register(promptweaver.SectionPlugin{Name: "apply-patch", Aliases: []string{"patch"}})
+	reg.Register(promptweaver.SectionPlugin{Name: "git-status"})
+	reg.Register(promptweaver.SectionPlugin{Name: "git-diff"})
+	reg.Register(promptweaver.SectionPlugin{Name: "git-log"})
+	reg.Register(promptweaver.SectionPlugin{Name: "git-blame"})
 	reg.Register(promptweaver.SectionPlugin{Name: "summary"})
+	registerTools(reg, tools)
 
 	return reg
 }
@@ -12,20 +12,55 @@ type Translator func(context.Context, string, string) (string, error)
 type RunRequest struct {
 	Input string
 	Model string
+	// ConversationID is recorded in the audit log with every tool call.
+	ConversationID string
+	// OnEvent, when set, receives progress as each section is processed.
+	OnEvent Reporter
+	// ProjectBrief describes the workspace project to the translator.
+	ProjectBrief string
+	// Policy, when set, replaces the runner's command policy for this run,
+	// as for a conversation whose profile allows more commands.
+	Policy *CommandPolicy
 }
 
 type Runner interface {
 	Run(context.Context, RunRequest) (string, error)
 }
 
+// CancelledError is returned when a run's context ends before the program
+// finished. It lists the tools that had already completed and the one that
+// was cut off, so the caller can tell what actually happened.
+type CancelledError struct {
+	Executed    []string
+	Interrupted string
+	Err         error
+}
+
+func (e *CancelledError) Error() string {
+	var b strings.Builder
+	if len(e.Executed) == 0 {
+		b.WriteString("agent run cancelled before any tool completed")
+	} else {
+		fmt.Fprintf(&b, "agent run cancelled after %d completed tool(s): %s", len(e.Executed), strings.Join(e.Executed, "; "))
+	}
+	if e.Interrupted != "" {
+		fmt.Fprintf(&b, " (interrupted: %s)", e.Interrupted)
+	}
+	return b.String()
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
 type LocalRunner struct {
-	workspace string
+	env       Env
 	translate Translator
 }
 
-func NewLocalRunner(workspace string, translate Translator) *LocalRunner {
+func NewLocalRunner(env Env, translate Translator) *LocalRunner {
 	return &LocalRunner{
-		workspace: workspace,
+		env:       env,
 		translate: translate,
 	}
 }
@@ -37,31 +72,63 @@ func (r *LocalRunner) Run(ctx context.Context, req RunRequest) (string, error) {
 	}
 
 	agentInput := userInput
-	if !LooksLikeCompletePromptWeaverProgram(userInput) {
+	if !LooksLikeCompletePromptWeaverProgram(userInput, r.env.Tools...) {
 		if r.translate == nil {
 			return "", fmt.Errorf("agent translation is not configured")
 		}
 
-		translated, err := r.translate(ctx, req.Model, BuildTranslationPrompt(userInput))
+		req.emit(Event{Kind: EventTranslating})
+		prompt := BuildTranslationPrompt(userInput, r.env.Tools...)
+		if brief := strings.TrimSpace(req.ProjectBrief); brief != "" {
+			prompt = brief + "\n\n" + prompt
+		}
+		translated, err := r.translate(ctx, req.Model, prompt)
+		if ctx.Err() != nil {
+			return "", &CancelledError{Err: ctx.Err()}
+		}
 		if err != nil {
 			return "", fmt.Errorf("translate agent request: %w", err)
 		}
 
 		agentInput = strings.TrimSpace(translated)
-		if !LooksLikeCompletePromptWeaverProgram(agentInput) {
+		if !LooksLikeCompletePromptWeaverProgram(agentInput, r.env.Tools...) {
 			return "", fmt.Errorf("translation did not produce a valid tool program")
 		}
 	}
 
+	req.emit(Event{Kind: EventProgram, Output: agentInput})
+
+	env := r.env
+	env.ConversationID = req.ConversationID
+	if req.Policy != nil {
+		env.Policy = req.Policy
+	}
+
 	var output []string
-	engine := NewAgent(func(line string) {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			output = append(output, line)
+	cancelled := &CancelledError{}
+	engine := NewAgent(ctx, func(ev Event) {
+		if ev.Kind == EventToolFinished || ev.Kind == EventSummary {
+			if line := strings.TrimSpace(ev.Output); line != "" {
+				output = append(output, line)
+			}
+		}
+		if ev.Kind == EventToolFinished {
+			label := strings.TrimSpace(ev.Tool + " " + ev.Detail)
+			if ctx.Err() != nil {
+				cancelled.Interrupted = label
+			} else {
+				cancelled.Executed = append(cancelled.Executed, label)
+			}
 		}
-	}, r.workspace)
+		req.emit(ev)
+	}, env)
 
-	if err := engine.Process(strings.NewReader(agentInput)); err != nil {
+	err := engine.Process(ctx, strings.NewReader(agentInput))
+	if ctx.Err() != nil {
+		cancelled.Err = ctx.Err()
+		return strings.Join(output, "\n\n"), cancelled
+	}
+	if err != nil {
 		return "", err
 	}
 	if len(output) == 0 {
@@ -71,21 +138,36 @@ func (r *LocalRunner) Run(ctx context.Context, req RunRequest) (string, error) {
 	return strings.Join(output, "\n\n"), nil
 }
 
+func (req RunRequest) emit(ev Event) {
+	if req.OnEvent != nil {
+		req.OnEvent(ev)
+	}
+}
+
 var promptWeaverTags = map[string]struct{}{
-	"think":       {},
-	"run-bash":    {},
-	"create-file": {},
-	"read-file":   {},
-	"list-dir":    {},
-	"grep-file":   {},
-	"glob-file":   {},
-	"edit-file":   {},
-	"summary":     {},
+	"think":        {},
+	"run-bash":     {},
+	"create-file":  {},
+	"read-file":    {},
+	"file-outline": {},
+	"list-dir":     {},
+	"grep-file":    {},
+	"glob-file":    {},
+	"edit-file":    {},
+	"apply-patch":  {},
+	"git-status":   {},
+	"git-diff":     {},
+	"git-log":      {},
+	"git-blame":    {},
+	"summary":      {},
 }
 
 var promptWeaverTagPattern = regexp.MustCompile(`(?s)<\s*(/?)\s*([a-zA-Z][a-zA-Z0-9_-]*)\b[^>]*>`)
 
-func LooksLikeCompletePromptWeaverProgram(input string) bool {
+// LooksLikeCompletePromptWeaverProgram reports whether input consists of
+// balanced sections using only built-in tags and those of tools.
+func LooksLikeCompletePromptWeaverProgram(input string, tools ...Tool) bool {
+	known := toolNames(tools)
 	matches := promptWeaverTagPattern.FindAllStringSubmatch(input, -1)
 	if len(matches) == 0 {
 		return false
@@ -96,7 +178,7 @@ func LooksLikeCompletePromptWeaverProgram(input string) bool {
 	for _, match := range matches {
 		closing := match[1] == "/"
 		name := match[2]
-		if _, ok := promptWeaverTags[name]; !ok {
+		if _, ok := known[name]; !ok {
 			return false
 		}
 
@@ -115,7 +197,7 @@ func LooksLikeCompletePromptWeaverProgram(input string) bool {
 	return len(stack) == 0 && completeSections > 0
 }
 
-func BuildTranslationPrompt(userInput string) string {
+func BuildTranslationPrompt(userInput string, tools ...Tool) string {
 	return strings.TrimSpace(fmt.Sprintf(`
 You convert a user's natural-language request into PromptWeaver sections for a local coding agent.
 
@@ -127,17 +209,25 @@ Output rules:
   - <think>hidden reasoning or short plan</think>
   - <run-bash>safe shell command</run-bash>
   - <create-file path="...">content</create-file>
-  - <read-file path="..."></read-file>
+  - <read-file path="..." start="1" end="200"></read-file>
+  - <file-outline path="..."></file-outline>
   - <list-dir path="..."></list-dir>
-  - <grep-file path="..." pattern="..." include="..."></grep-file>
-  - <glob-file path="..." pattern="..."></glob-file>
+  - <grep-file path="..." pattern="regexp" include="*.go" context="2" limit="50" ignore-case="true"></grep-file>
+  - <glob-file path="..." pattern="**/*.go" limit="100"></glob-file>
   - <edit-file path="..." old="..." new="..."></edit-file>
+  - <apply-patch>unified diff with --- a/path, +++ b/path and @@ hunks, one or more files</apply-patch>
+  - <git-status></git-status>
+  - <git-diff path="..." ref="HEAD~1" staged="true" stat="true"></git-diff>
+  - <git-log path="..." limit="20"></git-log>
+  - <git-blame path="..." start="10" end="40"></git-blame>
   - <summary>final visible response</summary>
-- Prefer read-only actions unless the user clearly asks to modify files.
+%s- Prefer read-only actions unless the user clearly asks to modify files.
+- For large files, use <file-outline> first and then read only the relevant line range.
+- Prefer <apply-patch> over <edit-file> for multi-line or multi-file changes.
 - Always end with exactly one <summary>...</summary>.
 - If the task is unclear or cannot be completed safely, emit only a <summary> explaining what is missing.
 
 User request:
 %s
-`, userInput))
+`, toolPromptLines(tools), userInput))
 }
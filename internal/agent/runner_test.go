@@ -2,8 +2,12 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/testutil"
 )
 
 func TestLooksLikeCompletePromptWeaverProgram(t *testing.T) {
@@ -38,13 +42,16 @@ func TestBuildTranslationPromptIncludesUserRequestAndTags(t *testing.T) {
 func TestLocalRunnerTranslatesPlainEnglish(t *testing.T) {
 	t.Parallel()
 
-	runner := NewLocalRunner(t.TempDir(), func(context.Context, string, string) (string, error) {
+	var prompt string
+	runner := NewLocalRunner(Env{Workspace: t.TempDir()}, func(_ context.Context, _ string, p string) (string, error) {
+		prompt = p
 		return "<summary>done</summary>", nil
 	})
 
 	output, err := runner.Run(context.Background(), RunRequest{
-		Input: "list the files in this repository",
-		Model: "gemini-test",
+		Input:        "list the files in this repository",
+		Model:        "gemini-test",
+		ProjectBrief: "Project context for the workspace /src/app:",
 	})
 	if err != nil {
 		t.Fatalf("Run returned error: %v", err)
@@ -52,4 +59,150 @@ func TestLocalRunnerTranslatesPlainEnglish(t *testing.T) {
 	if output != "done" {
 		t.Fatalf("unexpected output: %q", output)
 	}
+	if !strings.HasPrefix(prompt, "Project context for the workspace /src/app:") {
+		t.Fatalf("expected project brief in translation prompt, got %q", prompt)
+	}
+}
+
+func TestLocalRunnerReportsToolEvents(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{"notes.txt": "hello\n"})
+
+	var events []Event
+	runner := NewLocalRunner(Env{Workspace: workspace}, nil)
+	_, err := runner.Run(context.Background(), RunRequest{
+		Input: `<read-file path="notes.txt"></read-file><read-file path="missing.txt"></read-file><summary>done</summary>`,
+		OnEvent: func(ev Event) {
+			events = append(events, ev)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if events[0].Kind != EventProgram || !strings.Contains(events[0].Output, "<read-file") {
+		t.Fatalf("expected the program first, got %+v", events[0])
+	}
+	events = events[1:]
+
+	var kinds []string
+	for _, ev := range events {
+		kinds = append(kinds, string(ev.Kind)+":"+ev.Detail)
+	}
+	want := "tool-started:notes.txt tool-finished:notes.txt tool-started:missing.txt tool-finished:missing.txt summary:"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Fatalf("unexpected events: %s", got)
+	}
+	if events[1].Failed || !strings.Contains(events[1].Output, "hello") {
+		t.Fatalf("unexpected read result: %+v", events[1])
+	}
+	if !events[3].Failed {
+		t.Fatalf("expected missing file to fail: %+v", events[3])
+	}
+	if events[4].Output != "done" {
+		t.Fatalf("unexpected summary: %q", events[4].Output)
+	}
+}
+
+func TestLocalRunnerStopsRemainingToolsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{"notes.txt": "hello\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var tools []string
+	runner := NewLocalRunner(Env{Workspace: workspace}, nil)
+	_, err := runner.Run(ctx, RunRequest{
+		Input: `<list-dir path="."></list-dir><read-file path="notes.txt"></read-file><summary>done</summary>`,
+		OnEvent: func(ev Event) {
+			tools = append(tools, string(ev.Kind)+":"+ev.Tool)
+			if ev.Kind == EventToolFinished {
+				cancel()
+			}
+		},
+	})
+
+	var cancelled *CancelledError
+	if !errors.As(err, &cancelled) {
+		t.Fatalf("expected CancelledError, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if strings.Join(cancelled.Executed, ",") != "list-dir ." || cancelled.Interrupted != "" {
+		t.Fatalf("unexpected executed tools: %+v", cancelled)
+	}
+	if got := strings.Join(tools, " "); got != "program: tool-started:list-dir tool-finished:list-dir" {
+		t.Fatalf("expected remaining tools to be skipped, got %s", got)
+	}
+}
+
+func TestLocalRunnerInterruptsRunningCommand(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewCommandPolicy([]appconfig.CommandRule{{Command: "sleep"}})
+	if err != nil {
+		t.Fatalf("NewCommandPolicy returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := NewLocalRunner(Env{Workspace: t.TempDir(), Policy: policy, Sandbox: SandboxOff}, nil)
+	_, err = runner.Run(ctx, RunRequest{
+		Input: `<run-bash>sleep 30</run-bash><summary>done</summary>`,
+		OnEvent: func(ev Event) {
+			if ev.Kind == EventToolStarted {
+				cancel()
+			}
+		},
+	})
+
+	var cancelled *CancelledError
+	if !errors.As(err, &cancelled) {
+		t.Fatalf("expected CancelledError, got %v", err)
+	}
+	if cancelled.Interrupted != "run-bash sleep 30" || len(cancelled.Executed) != 0 {
+		t.Fatalf("unexpected cancellation report: %+v", cancelled)
+	}
+}
+
+func TestLocalRunnerRunsExternalTools(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	weather := Tool{
+		Name:        "mcp-demo-weather",
+		Usage:       `<mcp-demo-weather>{"city": ...}</mcp-demo-weather>`,
+		Description: "Current weather for a city.",
+		Run: func(_ context.Context, attrs map[string]string, content string) (string, error) {
+			got = content
+			return "sunny", nil
+		},
+	}
+	if !strings.Contains(BuildTranslationPrompt("weather?", weather), "Current weather for a city.") {
+		t.Fatal("expected external tool in translation prompt")
+	}
+
+	var output string
+	runner := NewLocalRunner(Env{Workspace: t.TempDir(), Tools: []Tool{weather}}, nil)
+	_, err := runner.Run(context.Background(), RunRequest{
+		Input: `<mcp-demo-weather>{"city": "Maputo"}</mcp-demo-weather><summary>done</summary>`,
+		OnEvent: func(ev Event) {
+			if ev.Kind == EventToolFinished {
+				output = ev.Output
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.TrimSpace(got) != `{"city": "Maputo"}` || output != "sunny" {
+		t.Fatalf("unexpected tool call: content %q, output %q", got, output)
+	}
 }
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type SandboxMode string
+
+const (
+	// SandboxAuto isolates commands with whatever the platform supports.
+	SandboxAuto SandboxMode = "auto"
+	// SandboxRequired refuses to run commands when no isolation is available.
+	SandboxRequired SandboxMode = "required"
+	// SandboxOff only scrubs the environment.
+	SandboxOff SandboxMode = "off"
+)
+
+func ParseSandboxMode(value string) (SandboxMode, error) {
+	switch mode := SandboxMode(value); mode {
+	case "":
+		return SandboxAuto, nil
+	case SandboxAuto, SandboxRequired, SandboxOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown sandbox mode %q (use auto, required or off)", value)
+	}
+}
+
+// sandboxHelperArg marks a re-execution of the vyai binary as the sandbox
+// helper, which confines itself and then execs the real command.
+const sandboxHelperArg = "__vyai_sandbox"
+
+// sandboxEnvAllowlist lists the variables commands may inherit. Everything
+// else, notably GOOGLE_API_KEY, is dropped.
+var sandboxEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TZ", "LANG",
+	"GOPATH", "GOROOT", "GOMODCACHE", "GOFLAGS", "GOTOOLCHAIN", "GOOS", "GOARCH",
+}
+
+// scrubbedEnv returns the allowlisted environment with the temporary dir
+// pointed at scratch and build caches at cacheDir (scratch when empty), the
+// only writable places besides the workspace.
+func scrubbedEnv(scratch, cacheDir string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "LC_") || slices.Contains(sandboxEnvAllowlist, key) {
+			env = append(env, kv)
+		}
+	}
+	if cacheDir == "" {
+		cacheDir = scratch
+	}
+	return append(env,
+		"TMPDIR="+scratch,
+		"GOCACHE="+filepath.Join(cacheDir, "go-build"),
+		"XDG_CACHE_HOME="+filepath.Join(cacheDir, "cache"),
+	)
+}
@@ -0,0 +1,402 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxSpec is handed from the parent to the helper as its first argument.
+type sandboxSpec struct {
+	Writable []string `json:"writable"`
+	Mounts   bool     `json:"mounts"`
+	Landlock bool     `json:"landlock"`
+}
+
+type sandboxLimit struct {
+	resource int
+	value    uint64
+}
+
+var sandboxLimits = []sandboxLimit{
+	{unix.RLIMIT_CPU, 600},
+	{unix.RLIMIT_FSIZE, 1 << 30},
+	{unix.RLIMIT_NOFILE, 1024},
+	{unix.RLIMIT_CORE, 0},
+}
+
+// lowerRlimit caps current at value. Limits are only ever lowered, since
+// an unprivileged process may not raise its hard limit.
+func lowerRlimit(current unix.Rlimit, value uint64) unix.Rlimit {
+	limit := unix.Rlimit{Cur: min(current.Cur, value), Max: min(current.Max, value)}
+	limit.Cur = min(limit.Cur, limit.Max)
+	return limit
+}
+
+// sandboxCommand wraps argv in the sandbox helper. It prefers a fresh user,
+// mount and network namespace and falls back to landlock alone when
+// namespaces are unavailable. The returned cleanup removes the scratch dir.
+func sandboxCommand(ctx context.Context, argv []string, env Env) (*exec.Cmd, func(), error) {
+	scratch, err := os.MkdirTemp("", "vyai-sandbox-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create sandbox scratch dir: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(scratch) }
+
+	if env.Sandbox == SandboxOff {
+		c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		c.Env = scrubbedEnv(scratch, env.CacheDir)
+		return c, cleanup, nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("locate sandbox helper: %w", err)
+	}
+
+	writable := []string{filepath.Clean(env.Workspace), scratch}
+	if env.CacheDir != "" {
+		if err := os.MkdirAll(env.CacheDir, 0700); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("create sandbox cache dir: %w", err)
+		}
+		writable = append(writable, filepath.Clean(env.CacheDir))
+	}
+	for _, path := range env.Writable {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		writable = append(writable, filepath.Clean(path))
+	}
+	spec := sandboxSpec{
+		Writable: writable,
+		Mounts:   namespacesAvailable(),
+		Landlock: landlockABI() > 0,
+	}
+	if !spec.Mounts && !spec.Landlock && env.Sandbox == SandboxRequired {
+		cleanup()
+		return nil, nil, errors.New("sandbox required but neither user namespaces nor landlock are available")
+	}
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	c := exec.CommandContext(ctx, self, append([]string{sandboxHelperArg, string(encoded)}, argv...)...)
+	c.Env = scrubbedEnv(scratch, env.CacheDir)
+	if spec.Mounts {
+		uid, gid := os.Getuid(), os.Getgid()
+		c.SysProcAttr = &syscall.SysProcAttr{
+			Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET,
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}},
+			AmbientCaps: []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_SETPCAP},
+		}
+	}
+
+	return c, cleanup, nil
+}
+
+var (
+	namespacesOnce sync.Once
+	namespacesOK   bool
+)
+
+// namespacesAvailable probes once whether this process may create the user,
+// mount and network namespaces the helper runs in.
+func namespacesAvailable() bool {
+	namespacesOnce.Do(func() {
+		probe, err := exec.LookPath("true")
+		if err != nil {
+			return
+		}
+		uid, gid := os.Getuid(), os.Getgid()
+		c := exec.Command(probe)
+		c.SysProcAttr = &syscall.SysProcAttr{
+			Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET,
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}},
+			AmbientCaps: []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_SETPCAP},
+		}
+		namespacesOK = c.Run() == nil
+	})
+	return namespacesOK
+}
+
+func landlockABI() int {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// SandboxStatus describes the isolation agent commands get on this machine.
+func SandboxStatus() string {
+	var layers []string
+	if namespacesAvailable() {
+		layers = append(layers, "namespaces")
+	}
+	if abi := landlockABI(); abi > 0 {
+		layers = append(layers, "landlock v"+strconv.Itoa(abi))
+	}
+	if len(layers) == 0 {
+		return "environment scrubbing and rlimits only"
+	}
+	return strings.Join(layers, " + ")
+}
+
+// MaybeRunSandboxHelper turns the process into the sandbox helper when the
+// binary was re-executed for that purpose, and never returns in that case.
+// main and TestMain call it before doing anything else.
+func MaybeRunSandboxHelper() {
+	if len(os.Args) < 2 {
+		return
+	}
+	if os.Args[1] != sandboxHelperArg {
+		return
+	}
+
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "sandbox: missing command")
+		os.Exit(126)
+	}
+	if err := runSandboxHelper(os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(126)
+	}
+}
+
+func runSandboxHelper(encodedSpec string, argv []string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(encodedSpec), &spec); err != nil {
+		return fmt.Errorf("parse spec: %w", err)
+	}
+
+	// Landlock, no_new_privs and capability changes apply to the calling
+	// thread, which must therefore be the one that calls execve.
+	runtime.LockOSThread()
+
+	if spec.Mounts {
+		if err := remountReadOnly(spec.Writable); err != nil {
+			return err
+		}
+		// The working directory still points at the mount underneath the
+		// writable bind, so resolve it again.
+		if wd, err := os.Getwd(); err == nil {
+			if err := os.Chdir(wd); err != nil {
+				return fmt.Errorf("chdir %s: %w", wd, err)
+			}
+		}
+	}
+	for _, limit := range sandboxLimits {
+		var current unix.Rlimit
+		if err := unix.Getrlimit(limit.resource, &current); err != nil {
+			return fmt.Errorf("getrlimit %d: %w", limit.resource, err)
+		}
+		rlimit := lowerRlimit(current, limit.value)
+		if err := unix.Setrlimit(limit.resource, &rlimit); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", limit.resource, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+	if spec.Landlock {
+		if err := restrictWithLandlock(spec.Writable); err != nil {
+			return err
+		}
+	}
+	if spec.Mounts {
+		dropCapabilities()
+	}
+
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, argv, os.Environ())
+}
+
+// remountReadOnly binds every writable path onto itself and then remounts
+// every other mount in the private mount namespace read-only.
+func remountReadOnly(writable []string) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	for _, path := range writable {
+		if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %s: %w", path, err)
+		}
+	}
+
+	mountPoints, err := readMountPoints()
+	if err != nil {
+		return err
+	}
+	for _, mountPoint := range mountPoints {
+		if isWithinAny(mountPoint, writable) {
+			continue
+		}
+
+		var st unix.Statfs_t
+		if err := unix.Statfs(mountPoint, &st); err != nil {
+			continue
+		}
+		// Flags that are locked on mounts inherited from the parent
+		// namespace have to be repeated, otherwise the remount fails.
+		locked := uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+		err := unix.Mount("", mountPoint, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|locked, "")
+		if err != nil && mountPoint == "/" {
+			return fmt.Errorf("remount / read-only: %w", err)
+		}
+	}
+	return nil
+}
+
+func readMountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("read mountinfo: %w", err)
+	}
+	defer f.Close()
+
+	var mountPoints []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mountPoints = append(mountPoints, unescapeMountPath(fields[4]))
+	}
+	return mountPoints, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes mountinfo uses for spaces,
+// tabs, newlines and backslashes.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if v, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+func isWithinAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	landlockRead = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockWriteV1 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+)
+
+// restrictWithLandlock allows reading everywhere, writing only beneath the
+// writable paths and /dev, and, from ABI v4 on, denies all TCP traffic.
+func restrictWithLandlock(writable []string) error {
+	abi := landlockABI()
+	handled := uint64(landlockRead | landlockWriteV1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		handled |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	if abi >= 4 {
+		attr.Access_net = unix.LANDLOCK_ACCESS_NET_BIND_TCP | unix.LANDLOCK_ACCESS_NET_CONNECT_TCP
+	}
+
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("landlock create ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	if err := addLandlockRule(int(fd), "/", landlockRead); err != nil {
+		return err
+	}
+	for _, path := range append([]string{"/dev"}, writable...) {
+		if err := addLandlockRule(int(fd), path, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock restrict self: %w", errno)
+	}
+	return nil
+}
+
+func addLandlockRule(rulesetFD int, path string, access uint64) error {
+	pathFD, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("landlock open %s: %w", path, err)
+	}
+	defer unix.Close(pathFD)
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(pathFD)}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFD), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("landlock add rule %s: %w", path, errno)
+	}
+	return nil
+}
+
+// dropCapabilities clears the ambient set and the bounding set so the
+// command cannot undo the read-only mounts, even as uid 0.
+func dropCapabilities() {
+	_ = unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0)
+	for capability := 0; capability <= unix.CAP_LAST_CAP; capability++ {
+		_ = unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0)
+	}
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package agent
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func requireSandbox(t *testing.T) {
+	t.Helper()
+	if !namespacesAvailable() && landlockABI() == 0 {
+		t.Skip("neither user namespaces nor landlock are available")
+	}
+}
+
+func TestSandboxScrubsEnvironment(t *testing.T) {
+	requireSandbox(t)
+	t.Setenv("GOOGLE_API_KEY", "secret-key")
+
+	out, err := runCommand(context.Background(), []string{"env"}, Env{Workspace: t.TempDir(), Sandbox: SandboxRequired})
+	if err != nil {
+		t.Fatalf("run env: %v: %s", err, out)
+	}
+	if strings.Contains(out, "secret-key") {
+		t.Fatalf("expected GOOGLE_API_KEY to be scrubbed, got %q", out)
+	}
+}
+
+func TestSandboxBlocksWritesOutsideWorkspace(t *testing.T) {
+	requireSandbox(t)
+
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	env := Env{Workspace: workspace, Sandbox: SandboxRequired}
+
+	out, err := runCommand(context.Background(), []string{"sh", "-c", "echo ok > inside.txt"}, env)
+	if err != nil {
+		t.Fatalf("expected workspace write to succeed: %v: %s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "inside.txt")); err != nil {
+		t.Fatalf("expected inside.txt to exist: %v", err)
+	}
+
+	target := filepath.Join(outside, "escape.txt")
+	if _, err := runCommand(context.Background(), []string{"sh", "-c", "echo pwned > " + target}, env); err == nil {
+		t.Fatal("expected write outside the workspace to fail")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not to be created, stat err: %v", target, err)
+	}
+}
+
+func TestSandboxBlocksNetwork(t *testing.T) {
+	requireSandbox(t)
+	if !namespacesAvailable() && landlockABI() < 4 {
+		t.Skip("network isolation needs namespaces or landlock v4")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is required for /dev/tcp")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	out, err := runCommand(context.Background(), []string{"bash", "-c", "exec 3<>/dev/tcp/127.0.0.1/" + port}, Env{Workspace: t.TempDir(), Sandbox: SandboxRequired})
+	if err == nil {
+		t.Fatalf("expected connection to the host to fail, got %q", out)
+	}
+}
+
+func TestLowerRlimitNeverRaises(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		current unix.Rlimit
+		value   uint64
+		want    unix.Rlimit
+	}{
+		{unix.Rlimit{Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY}, 1024, unix.Rlimit{Cur: 1024, Max: 1024}},
+		{unix.Rlimit{Cur: 256, Max: 512}, 1024, unix.Rlimit{Cur: 256, Max: 512}},
+		{unix.Rlimit{Cur: 4096, Max: 512}, 1024, unix.Rlimit{Cur: 512, Max: 512}},
+		{unix.Rlimit{Cur: 0, Max: 0}, 600, unix.Rlimit{Cur: 0, Max: 0}},
+	} {
+		if got := lowerRlimit(tc.current, tc.value); got != tc.want {
+			t.Fatalf("lowerRlimit(%+v, %d) = %+v, want %+v", tc.current, tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// sandboxCommand only scrubs the environment: namespaces and landlock are
+// Linux features.
+func sandboxCommand(ctx context.Context, argv []string, env Env) (*exec.Cmd, func(), error) {
+	if env.Sandbox == SandboxRequired {
+		return nil, nil, errors.New("sandbox required but not supported on this platform")
+	}
+
+	scratch, err := os.MkdirTemp("", "vyai-sandbox-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create sandbox scratch dir: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Env = scrubbedEnv(scratch, env.CacheDir)
+	return c, func() { _ = os.RemoveAll(scratch) }, nil
+}
+
+func SandboxStatus() string {
+	return "environment scrubbing only"
+}
+
+func MaybeRunSandboxHelper() {}
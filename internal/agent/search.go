@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultGrepResults = 200
+	DefaultGlobResults = 500
+
+	// Files larger than this are skipped by grep-file.
+	maxSearchFileSize = 4 << 20
+	// binarySniffSize bytes are inspected for NUL to detect binary files.
+	binarySniffSize = 8000
+)
+
+var errSearchLimit = errors.New("search result limit reached")
+
+type GrepOptions struct {
+	Pattern    string
+	Include    string
+	Path       string
+	Context    int
+	MaxResults int
+	IgnoreCase bool
+}
+
+// GrepFile searches file contents below opts.Path with a regular expression
+// and prints grep-style "path:line:text" matches, with "path-line-text" for
+// context lines and "--" between groups.
+func GrepFile(ctx context.Context, opts GrepOptions, workspace string) (string, error) {
+	if opts.Pattern == "" {
+		return "", errors.New("grep pattern is required")
+	}
+	if opts.Include != "" && !validGlob(opts.Include) {
+		return "", fmt.Errorf("invalid include pattern %q", opts.Include)
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = DefaultGrepResults
+	}
+
+	expr := opts.Pattern
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid grep pattern: %w", err)
+	}
+
+	root, err := ResolveWorkspacePath(workspace, opts.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	matches := 0
+	err = walkWorkspace(ctx, workspace, root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if opts.Include != "" && !matchInclude(opts.Include, rel) {
+			return nil
+		}
+
+		n, err := grepOne(filepath.Join(workspace, filepath.FromSlash(rel)), rel, re, opts.Context, opts.MaxResults-matches, &out)
+		matches += n
+		if err != nil {
+			return nil
+		}
+		if matches >= opts.MaxResults {
+			return errSearchLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errSearchLimit) {
+		fmt.Fprintf(&out, "... stopped after %d matches\n", opts.MaxResults)
+	} else if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func grepOne(path, rel string, re *regexp.Regexp, contextLines, remaining int, out *strings.Builder) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() > maxSearchFileSize {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(f)
+	if sniff, _ := reader.Peek(binarySniffSize); bytes.IndexByte(sniff, 0) >= 0 {
+		return 0, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxSearchFileSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	matches := 0
+	lastPrinted := -1
+	for i, line := range lines {
+		if matches >= remaining {
+			break
+		}
+		if !re.MatchString(line) {
+			continue
+		}
+		matches++
+
+		start := max(i-contextLines, lastPrinted+1)
+		if contextLines > 0 && lastPrinted >= 0 && start > lastPrinted+1 {
+			out.WriteString("--\n")
+		}
+		end := min(i+contextLines, len(lines)-1)
+		for j := start; j <= end; j++ {
+			sep := "-"
+			if re.MatchString(lines[j]) {
+				sep = ":"
+			}
+			fmt.Fprintf(out, "%s%s%d%s%s\n", rel, sep, j+1, sep, lines[j])
+		}
+		lastPrinted = end
+	}
+
+	return matches, nil
+}
+
+// matchInclude matches patterns with a slash against the workspace-relative
+// path and bare patterns such as "*.go" against the file name.
+func matchInclude(pattern, rel string) bool {
+	if strings.Contains(pattern, "/") {
+		return matchGlob(pattern, rel)
+	}
+	return matchGlob(pattern, path.Base(rel))
+}
+
+// GlobFile lists workspace-relative paths below path matching pattern,
+// which may use "**" to cross directories.
+func GlobFile(ctx context.Context, pattern, path, workspace string, maxResults int) (string, error) {
+	if pattern == "" {
+		return "", errors.New("glob pattern is required")
+	}
+	if filepath.IsAbs(pattern) {
+		return "", errors.New("absolute patterns are not allowed")
+	}
+
+	root, err := ResolveWorkspacePath(workspace, path)
+	if err != nil {
+		return "", err
+	}
+
+	cleanPattern := filepath.ToSlash(filepath.Clean(pattern))
+	if cleanPattern == ".." || strings.HasPrefix(cleanPattern, "../") ||
+		strings.Contains(cleanPattern, "/../") || strings.HasSuffix(cleanPattern, "/..") {
+		return "", errors.New("glob pattern escapes the workspace")
+	}
+	if !validGlob(cleanPattern) {
+		return "", fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	if maxResults <= 0 {
+		maxResults = DefaultGlobResults
+	}
+
+	rootRel, err := filepath.Rel(workspace, root)
+	if err != nil {
+		return "", err
+	}
+	rootRel = filepath.ToSlash(rootRel)
+
+	var matches []string
+	err = walkWorkspace(ctx, workspace, root, func(rel string, d fs.DirEntry) error {
+		target := rel
+		if rootRel != "." {
+			target = strings.TrimPrefix(rel, rootRel+"/")
+		}
+		if !matchGlob(cleanPattern, target) {
+			return nil
+		}
+		matches = append(matches, rel)
+		if len(matches) >= maxResults {
+			return errSearchLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errSearchLimit) {
+		matches = append(matches, fmt.Sprintf("... stopped after %d matches", maxResults))
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.Join(matches, "\n"), nil
+}
+
+// WalkWorkspace walks the whole workspace the way grep-file and glob-file
+// do, skipping .git and ignored entries.
+func WalkWorkspace(ctx context.Context, workspace string, fn func(rel string, d fs.DirEntry) error) error {
+	return walkWorkspace(ctx, workspace, workspace, fn)
+}
+
+// walkWorkspace walks root, which must lie inside workspace, skipping .git
+// and anything excluded by .gitignore files between workspace and each
+// entry. fn receives slash-separated paths relative to workspace.
+func walkWorkspace(ctx context.Context, workspace, root string, fn func(rel string, d fs.DirEntry) error) error {
+	workspace = filepath.Clean(workspace)
+	rootRel, err := filepath.Rel(workspace, root)
+	if err != nil {
+		return err
+	}
+	rootRel = filepath.ToSlash(rootRel)
+
+	// Collect the .gitignore files above the starting directory.
+	matcher := (&ignoreMatcher{}).loadDir(workspace, ".")
+	if rootRel != "." {
+		dir := ""
+		for _, part := range strings.Split(rootRel, "/") {
+			dir = path.Join(dir, part)
+			if matcher.ignored(dir, true) {
+				return nil
+			}
+			matcher = matcher.loadDir(workspace, dir)
+		}
+	}
+
+	return walkDir(ctx, workspace, rootRel, matcher, fn)
+}
+
+func walkDir(ctx context.Context, workspace, dir string, matcher *ignoreMatcher, fn func(string, fs.DirEntry) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(workspace, filepath.FromSlash(dir)))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		rel := path.Join(dir, entry.Name())
+		if entry.IsDir() && entry.Name() == ".git" {
+			continue
+		}
+		if matcher.ignored(rel, entry.IsDir()) {
+			continue
+		}
+
+		if err := fn(rel, entry); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if err := walkDir(ctx, workspace, rel, matcher.loadDir(workspace, rel), fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func TestGrepFileHonorsGitignore(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		".gitignore":                "node_modules/\n*.log\n!keep.log\n",
+		"main.go":                   "package main\n// TODO: ship\n",
+		"keep.log":                  "TODO: keep\n",
+		"debug.log":                 "TODO: noise\n",
+		"node_modules/pkg/index.js": "// TODO: vendored\n",
+		"web/.gitignore":            "dist\n",
+		"web/dist/app.js":           "// TODO: built\n",
+		"web/src/app.js":            "// TODO: source\n",
+		".git/config":               "TODO\n",
+		"image.png":                 "TODO\x00binary",
+	})
+
+	out, err := GrepFile(context.Background(), GrepOptions{Pattern: "TODO"}, workspace)
+	if err != nil {
+		t.Fatalf("GrepFile returned error: %v", err)
+	}
+
+	for _, want := range []string{"main.go:2:// TODO: ship", "keep.log:1:TODO: keep", "web/src/app.js:1:"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"debug.log", "node_modules", "dist", ".git/", "image.png"} {
+		if strings.Contains(out, unwanted) {
+			t.Fatalf("expected %q to be skipped:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestGrepFileContextAndLimit(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		"a.txt": "one\ntwo\nmatch\nfour\nfive\nsix\nseven\nmatch\nnine\n",
+	})
+
+	out, err := GrepFile(context.Background(), GrepOptions{Pattern: "match", Context: 1}, workspace)
+	if err != nil {
+		t.Fatalf("GrepFile returned error: %v", err)
+	}
+	want := "a.txt-2-two\na.txt:3:match\na.txt-4-four\n--\na.txt-7-seven\na.txt:8:match\na.txt-9-nine\n"
+	if out != want {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	out, err = GrepFile(context.Background(), GrepOptions{Pattern: "MATCH", IgnoreCase: true, MaxResults: 1}, workspace)
+	if err != nil {
+		t.Fatalf("GrepFile returned error: %v", err)
+	}
+	if !strings.HasPrefix(out, "a.txt:3:match\n") || !strings.Contains(out, "stopped after 1 matches") {
+		t.Fatalf("expected a single limited match, got:\n%s", out)
+	}
+}
+
+func TestGlobFileSupportsDoublestar(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		".gitignore":        "vendor/\n",
+		"main.go":           "",
+		"internal/a/a.go":   "",
+		"internal/a/a.txt":  "",
+		"vendor/dep/dep.go": "",
+	})
+
+	out, err := GlobFile(context.Background(), "**/*.go", "", workspace, 0)
+	if err != nil {
+		t.Fatalf("GlobFile returned error: %v", err)
+	}
+	if out != "internal/a/a.go\nmain.go" {
+		t.Fatalf("unexpected matches:\n%s", out)
+	}
+
+	out, err = GlobFile(context.Background(), "a/*", "internal", workspace, 0)
+	if err != nil {
+		t.Fatalf("GlobFile returned error: %v", err)
+	}
+	if out != "internal/a/a.go\ninternal/a/a.txt" {
+		t.Fatalf("unexpected matches:\n%s", out)
+	}
+}
+
+func TestGlobFileRejectsEscapingPatterns(t *testing.T) {
+	t.Parallel()
+
+	if _, err := GlobFile(context.Background(), "../*", "", t.TempDir(), 0); err == nil {
+		t.Fatal("expected escaping pattern to be rejected")
+	}
+}
@@ -3,7 +3,6 @@ package agent
 import (
 	"errors"
 	"path/filepath"
-	"slices"
 	"strings"
 )
 
@@ -18,6 +17,22 @@ func SecureJoin(base, rel string) (string, error) {
 	return target, nil
 }
 
+// SecureWritePath is SecureJoin for the tools that write files. It also
+// refuses paths inside a .git directory, where a written config or hook
+// would make the next git command run arbitrary programs.
+func SecureWritePath(base, rel string) (string, error) {
+	target, err := SecureJoin(base, rel)
+	if err != nil {
+		return "", err
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.EqualFold(part, ".git") {
+			return "", errors.New("writing inside .git is not allowed")
+		}
+	}
+	return target, nil
+}
+
 func ResolveWorkspacePath(base, rel string) (string, error) {
 	if rel == "" || rel == "." {
 		return filepath.Clean(base), nil
@@ -44,17 +59,7 @@ func ParseCommand(cmd string) ([]string, error) {
 	return fields, nil
 }
 
+// AllowCommand reports whether args pass the built-in command policy.
 func AllowCommand(args []string) bool {
-	if len(args) == 0 {
-		return false
-	}
-
-	switch args[0] {
-	case "ls", "cat", "gofmt", "goimports":
-		return true
-	case "go":
-		return len(args) > 1 && slices.Contains([]string{"build", "test", "vet"}, args[1])
-	default:
-		return false
-	}
+	return DefaultCommandPolicy().Allow(args)
 }
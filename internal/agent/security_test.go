@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,7 +29,7 @@ func TestRunBashRejectsAbsolutePaths(t *testing.T) {
 	t.Parallel()
 
 	workspace := t.TempDir()
-	_, err := RunBash("cat /etc/passwd", workspace)
+	_, err := RunBash(context.Background(), "cat /etc/passwd", Env{Workspace: workspace})
 	if err == nil {
 		t.Fatal("expected absolute path access to be blocked")
 	}
@@ -46,7 +47,7 @@ func TestGrepFileRejectsPathEscape(t *testing.T) {
 		_ = os.Remove(parentFile)
 	})
 
-	_, err := GrepFile("secret", "*.txt", "../", workspace)
+	_, err := GrepFile(context.Background(), GrepOptions{Pattern: "secret", Include: "*.txt", Path: "../"}, workspace)
 	if err == nil {
 		t.Fatal("expected grep path escape to be blocked")
 	}
@@ -61,7 +62,7 @@ func TestRunBashReadsFilesInsideWorkspace(t *testing.T) {
 		t.Fatalf("write file: %v", err)
 	}
 
-	out, err := RunBash("cat note.txt", workspace)
+	out, err := RunBash(context.Background(), "cat note.txt", Env{Workspace: workspace})
 	if err != nil {
 		t.Fatalf("run command: %v", err)
 	}
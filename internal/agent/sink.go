@@ -1,122 +1,293 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grahms/promptweaver"
 )
 
-func BuildSink(uiOut func(string), workspace string) *promptweaver.HandlerSink {
+func BuildSink(ctx context.Context, report Reporter, env Env) *promptweaver.HandlerSink {
+	workspace := env.Workspace
 	sink := promptweaver.NewHandlerSink()
 
+	// handle wraps a tool so the reporter sees it start and finish. Tools
+	// parsed from the same buffer are skipped once ctx is cancelled.
+	handle := func(name string, run func(ev promptweaver.SectionEvent, out *toolOutput)) {
+		sink.RegisterHandler(name, func(ev promptweaver.SectionEvent) {
+			if ctx.Err() != nil {
+				return
+			}
+			detail := sectionDetail(name, ev.Content, ev.Attrs)
+			report(Event{Kind: EventToolStarted, Tool: name, Detail: detail})
+
+			out := &toolOutput{decision: DecisionNotRequired}
+			run(ev, out)
+			if env.Audit != nil {
+				if err := env.Audit.Record(auditEntry(name, ev, env, out)); err != nil {
+					out.print("Audit log write failed: " + err.Error())
+				}
+			}
+			report(Event{Kind: EventToolFinished, Tool: name, Detail: detail, Output: out.String(), Failed: out.failed})
+		})
+	}
+
 	// Hidden reasoning
 	sink.RegisterHandler("think", func(ev promptweaver.SectionEvent) {})
 
 	// Shell execution
-	sink.RegisterHandler("run-bash", func(ev promptweaver.SectionEvent) {
-		out, err := RunBash(ev.Content, workspace)
+	handle("run-bash", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		argv, err := prepareBash(ev.Content, env)
 		if err != nil {
-			uiOut("Exec error: " + err.Error())
+			result.decision = DecisionDenied
+			result.fail("Exec error: " + err.Error())
 			return
 		}
+		result.decision = DecisionAllowed
+		result.argv = argv
 
-		uiOut(out)
+		out, err := runCommand(ctx, argv, env)
+		code := exitCode(err)
+		result.exitCode = &code
+		if err != nil {
+			// Keep the output of commands that ran but failed, e.g. go test.
+			if strings.TrimSpace(out) != "" {
+				result.print(out)
+			}
+			result.fail("Exec error: " + err.Error())
+			return
+		}
+
+		result.print(out)
 	})
 
 	// File creation
-	sink.RegisterHandler("create-file", func(ev promptweaver.SectionEvent) {
-		path, err := SecureJoin(workspace, ev.Attrs["path"])
+	handle("create-file", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		path, err := SecureWritePath(workspace, ev.Attrs["path"])
 		if err != nil {
-			uiOut("File blocked: " + err.Error())
+			result.fail("File blocked: " + err.Error())
 			return
 		}
 
 		dir := filepath.Dir(path)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			uiOut("Write failed: " + err.Error())
+			result.fail("Write failed: " + err.Error())
 			return
 		}
 
 		if err := os.WriteFile(path, []byte(ev.Content), 0644); err != nil {
-			uiOut("Write failed: " + err.Error())
+			result.fail("Write failed: " + err.Error())
 			return
 		}
 
-		uiOut("File created: " + path)
+		result.print("File created: " + path)
 	})
 
 	// File viewing
-	sink.RegisterHandler("read-file", func(ev promptweaver.SectionEvent) {
+	handle("read-file", func(ev promptweaver.SectionEvent, result *toolOutput) {
 		path, err := SecureJoin(workspace, ev.Attrs["path"])
 		if err != nil {
-			uiOut("File blocked: " + err.Error())
+			result.fail("File blocked: " + err.Error())
 			return
 		}
-		out, err := ReadFile(path)
+		out, err := ReadFile(path, ReadOptions{
+			Start:    intAttr(ev.Attrs, "start"),
+			End:      intAttr(ev.Attrs, "end"),
+			MaxBytes: intAttr(ev.Attrs, "max-bytes"),
+		})
 		if err != nil {
-			uiOut("Read error: " + err.Error())
+			result.fail("Read error: " + err.Error())
 			return
 		}
-		uiOut(out)
+		result.print(out)
+	})
+
+	// Symbol outline for navigating large files
+	handle("file-outline", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		path, err := SecureJoin(workspace, ev.Attrs["path"])
+		if err != nil {
+			result.fail("File blocked: " + err.Error())
+			return
+		}
+		out, err := FileOutline(path)
+		if err != nil {
+			result.fail("Outline error: " + err.Error())
+			return
+		}
+		result.print(out)
 	})
 
 	// Directory listing
-	sink.RegisterHandler("list-dir", func(ev promptweaver.SectionEvent) {
+	handle("list-dir", func(ev promptweaver.SectionEvent, result *toolOutput) {
 		path, err := SecureJoin(workspace, ev.Attrs["path"])
 		if err != nil {
-			uiOut("Path blocked: " + err.Error())
+			result.fail("Path blocked: " + err.Error())
 			return
 		}
 		out, err := ListDir(path)
 		if err != nil {
-			uiOut("List error: " + err.Error())
+			result.fail("List error: " + err.Error())
 			return
 		}
-		uiOut(out)
+		result.print(out)
 	})
 
 	// Grep file content
-	sink.RegisterHandler("grep-file", func(ev promptweaver.SectionEvent) {
-		out, err := GrepFile(ev.Attrs["pattern"], ev.Attrs["include"], ev.Attrs["path"], workspace)
+	handle("grep-file", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		out, err := GrepFile(ctx, GrepOptions{
+			Pattern:    ev.Attrs["pattern"],
+			Include:    ev.Attrs["include"],
+			Path:       ev.Attrs["path"],
+			Context:    intAttr(ev.Attrs, "context"),
+			MaxResults: intAttr(ev.Attrs, "limit"),
+			IgnoreCase: ev.Attrs["ignore-case"] == "true",
+		}, workspace)
 		if err != nil {
-			uiOut("Grep error: " + err.Error())
+			result.fail("Grep error: " + err.Error())
 			return
 		}
-		uiOut(out)
+		if out == "" {
+			out = "No matches."
+		}
+		result.print(out)
 	})
 
 	// Glob file paths
-	sink.RegisterHandler("glob-file", func(ev promptweaver.SectionEvent) {
-		out, err := GlobFile(ev.Attrs["pattern"], ev.Attrs["path"], workspace)
+	handle("glob-file", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		out, err := GlobFile(ctx, ev.Attrs["pattern"], ev.Attrs["path"], workspace, intAttr(ev.Attrs, "limit"))
 		if err != nil {
-			uiOut("Glob error: " + err.Error())
+			result.fail("Glob error: " + err.Error())
 			return
 		}
-		uiOut(out)
+		if out == "" {
+			out = "No matches."
+		}
+		result.print(out)
 	})
 
 	// File editing
-	sink.RegisterHandler("edit-file", func(ev promptweaver.SectionEvent) {
-		path, err := SecureJoin(workspace, ev.Attrs["path"])
+	handle("edit-file", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		path, err := SecureWritePath(workspace, ev.Attrs["path"])
 		if err != nil {
-			uiOut("File blocked: " + err.Error())
+			result.fail("File blocked: " + err.Error())
 			return
 		}
 		oldString := ev.Attrs["old"]
 		newString := ev.Attrs["new"]
 
 		if err := EditFile(path, oldString, newString); err != nil {
-			uiOut("Edit failed: " + err.Error())
+			result.fail("Edit failed: " + err.Error())
+			return
+		}
+		result.print("File edited: " + path)
+	})
+
+	// Multi-file unified diffs
+	handle("apply-patch", func(ev promptweaver.SectionEvent, result *toolOutput) {
+		applied, err := ApplyPatch(ev.Content, workspace)
+		if err != nil {
+			result.fail("Patch failed, no files were changed: " + err.Error())
 			return
 		}
-		uiOut("File edited: " + path)
+		result.print("Patch applied:\n" + strings.Join(applied, "\n"))
 	})
 
+	// Read-only git inspection
+	gitHandler := func(run func(promptweaver.SectionEvent) (string, error)) func(promptweaver.SectionEvent, *toolOutput) {
+		return func(ev promptweaver.SectionEvent, result *toolOutput) {
+			out, err := run(ev)
+			if err != nil {
+				result.fail("Git error: " + err.Error())
+				return
+			}
+			if strings.TrimSpace(out) == "" {
+				out = "No output."
+			}
+			result.print(out)
+		}
+	}
+	handle("git-status", gitHandler(func(ev promptweaver.SectionEvent) (string, error) {
+		return GitStatus(ctx, env)
+	}))
+	handle("git-diff", gitHandler(func(ev promptweaver.SectionEvent) (string, error) {
+		return GitDiff(ctx, env, GitDiffOptions{
+			Path:   ev.Attrs["path"],
+			Ref:    ev.Attrs["ref"],
+			Staged: ev.Attrs["staged"] == "true",
+			Stat:   ev.Attrs["stat"] == "true",
+		})
+	}))
+	handle("git-log", gitHandler(func(ev promptweaver.SectionEvent) (string, error) {
+		return GitLog(ctx, env, ev.Attrs["path"], intAttr(ev.Attrs, "limit"))
+	}))
+	handle("git-blame", gitHandler(func(ev promptweaver.SectionEvent) (string, error) {
+		return GitBlame(ctx, env, ev.Attrs["path"], intAttr(ev.Attrs, "start"), intAttr(ev.Attrs, "end"))
+	}))
+
+	// External tools
+	for _, tool := range env.Tools {
+		handle(tool.Name, func(ev promptweaver.SectionEvent, result *toolOutput) {
+			result.input = strings.TrimSpace(ev.Content)
+			out, err := tool.Run(ctx, ev.Attrs, ev.Content)
+			if err != nil {
+				if strings.TrimSpace(out) != "" {
+					result.print(out)
+				}
+				result.fail("Tool error: " + err.Error())
+				return
+			}
+			if strings.TrimSpace(out) == "" {
+				out = "No output."
+			}
+			result.print(out)
+		})
+	}
+
 	// output finale
 	sink.RegisterHandler("summary", func(ev promptweaver.SectionEvent) {
-		uiOut(ev.Content)
+		if ctx.Err() != nil {
+			return
+		}
+		report(Event{Kind: EventSummary, Tool: "summary", Output: strings.TrimSpace(ev.Content)})
 	})
 
 	return sink
 }
+
+func auditEntry(tool string, ev promptweaver.SectionEvent, env Env, out *toolOutput) AuditEntry {
+	entry := AuditEntry{
+		Time:           time.Now().UTC(),
+		Workspace:      env.Workspace,
+		ConversationID: env.ConversationID,
+		Tool:           tool,
+		Attrs:          ev.Attrs,
+		Argv:           out.argv,
+		ExitCode:       out.exitCode,
+		OutputBytes:    len(out.String()),
+		Decision:       out.decision,
+		Failed:         out.failed,
+	}
+	if tool == "run-bash" {
+		entry.Command = strings.TrimSpace(ev.Content)
+	} else if out.input != "" {
+		entry.Command = out.input
+	}
+	if len(entry.Attrs) == 0 {
+		entry.Attrs = nil
+	}
+	return entry
+}
+
+// intAttr parses a numeric section attribute, treating missing or invalid
+// values as zero so the tool falls back to its default.
+func intAttr(attrs map[string]string, name string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(attrs[name]))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
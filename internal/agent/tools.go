@@ -1,46 +1,30 @@
 package agent
 
 import (
-	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 )
 
-func RunBash(cmd, workspace string) (string, error) {
-	args, err := ParseCommand(cmd)
+func RunBash(ctx context.Context, cmd string, env Env) (string, error) {
+	argv, err := prepareBash(cmd, env)
 	if err != nil {
 		return "", err
 	}
-	if !AllowCommand(args) {
-		return "", errors.New("command is not allowed")
-	}
-
-	argv, err := prepareCommandArgs(args, workspace)
-	if err != nil {
-		return "", err
-	}
-
-	c := exec.Command(argv[0], argv[1:]...)
-	c.Dir = workspace
 
-	var out bytes.Buffer
-	c.Stdout = &out
-	c.Stderr = &out
-
-	err = c.Run()
-	return out.String(), err
+	return runCommand(ctx, argv, env)
 }
 
-func ReadFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
+// prepareBash parses a command line and checks it against the policy,
+// returning the argv that will actually be executed.
+func prepareBash(cmd string, env Env) ([]string, error) {
+	args, err := ParseCommand(cmd)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(content), nil
+
+	return env.policy().Prepare(args, env.Workspace)
 }
 
 func ListDir(path string) (string, error) {
@@ -56,74 +40,6 @@ func ListDir(path string) (string, error) {
 	return strings.Join(entries, "\n"), nil
 }
 
-func GrepFile(pattern, include, path, workspace string) (string, error) {
-	if pattern == "" {
-		return "", errors.New("grep pattern is required")
-	}
-
-	safePath, err := ResolveWorkspacePath(workspace, path)
-	if err != nil {
-		return "", err
-	}
-
-	args := []string{"--recursive"}
-	if include != "" {
-		args = append(args, "--include", include)
-	}
-	args = append(args, pattern, safePath)
-
-	out, err := exec.Command("grep", args...).CombinedOutput()
-	if err != nil {
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
-			return "", nil
-		}
-		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
-	}
-	return string(out), nil
-}
-
-func GlobFile(pattern, path, workspace string) (string, error) {
-	if pattern == "" {
-		return "", errors.New("glob pattern is required")
-	}
-	if filepath.IsAbs(pattern) {
-		return "", errors.New("absolute patterns are not allowed")
-	}
-
-	safePath, err := ResolveWorkspacePath(workspace, path)
-	if err != nil {
-		return "", err
-	}
-
-	cleanPattern := filepath.Clean(pattern)
-	if cleanPattern == ".." ||
-		strings.HasPrefix(cleanPattern, ".."+string(os.PathSeparator)) ||
-		strings.Contains(cleanPattern, string(os.PathSeparator)+".."+string(os.PathSeparator)) ||
-		strings.HasSuffix(cleanPattern, string(os.PathSeparator)+"..") {
-		return "", errors.New("glob pattern escapes the workspace")
-	}
-
-	globPattern := filepath.Join(safePath, cleanPattern)
-	matches, err := filepath.Glob(globPattern)
-	if err != nil {
-		return "", err
-	}
-
-	filtered := make([]string, 0, len(matches))
-	for _, match := range matches {
-		rel, err := filepath.Rel(workspace, match)
-		if err != nil {
-			return "", err
-		}
-		if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
-			return "", errors.New("glob match escapes the workspace")
-		}
-		filtered = append(filtered, match)
-	}
-	return strings.Join(filtered, "\n"), nil
-}
-
 func EditFile(path, oldString, newString string) error {
 	if oldString == "" {
 		return errors.New("old string is required")
@@ -147,17 +63,6 @@ func EditFile(path, oldString, newString string) error {
 	return os.WriteFile(path, []byte(newContent), fileInfo.Mode())
 }
 
-func prepareCommandArgs(args []string, workspace string) ([]string, error) {
-	switch args[0] {
-	case "ls", "cat", "gofmt", "goimports":
-		return rewritePathArgs(args, workspace, args[0] == "cat")
-	case "go":
-		return validateGoArgs(args)
-	default:
-		return nil, errors.New("unsupported command")
-	}
-}
-
 func rewritePathArgs(args []string, workspace string, requirePath bool) ([]string, error) {
 	rewritten := []string{args[0]}
 	pathCount := 0
@@ -186,17 +91,3 @@ func rewritePathArgs(args []string, workspace string, requirePath bool) ([]strin
 
 	return rewritten, nil
 }
-
-func validateGoArgs(args []string) ([]string, error) {
-	if len(args) < 2 {
-		return nil, errors.New("go subcommand is required")
-	}
-
-	for _, arg := range args[2:] {
-		if filepath.IsAbs(arg) {
-			return nil, errors.New("absolute paths are not allowed")
-		}
-	}
-
-	return args, nil
-}
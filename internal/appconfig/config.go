@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,7 +17,12 @@ const (
 	DefaultDescriptionModel     = "gemini-3-flash-preview"
 	DefaultSystemPromptFileName = "system_prompt.md"
 	DefaultTitlePromptFileName  = "description_prompt.md"
+	DefaultCommitPromptFileName = "commit_prompt.md"
+	DefaultReviewPromptFileName = "review_prompt.md"
+	DefaultPRPromptFileName     = "pr_prompt.md"
 	DefaultConfigFileName       = "config.json"
+	DefaultPluginDirName        = "plugins"
+	DefaultTemplateDirName      = "templates"
 	defaultSystemPrompt         = `
 You are a Linux System Admin Assistant. Your role is to assist with Linux and infrastructure management by providing clear, concise, and direct answers. Focus on actionable guidance for:
 
@@ -52,14 +59,102 @@ Please give a description to this conversation. Reply only with the description.
 Always use the first messages to describe the conversation. Do not use the last message to describe the conversation.
 
 Words - Max: 15, Min: 3, Recommended Max: 10`
+	defaultCommitPrompt = `
+Write a commit message for the staged changes below, following the Conventional Commits format:
+
+- Subject line: type(optional scope): summary, in the imperative mood, at most 72 characters
+- Types: feat, fix, docs, style, refactor, perf, test, build, ci, chore
+- Leave a blank line after the subject, then explain what changed and why when it is not obvious from the subject
+- Wrap the body at 72 characters
+
+Reply only with the commit message. Do not wrap it in a code block.`
+	defaultReviewPrompt = `
+Review the staged changes below as a careful senior engineer. Point out bugs, security problems, missing error handling, unclear names and missing tests. Refer to the file and line each comment is about, most important comments first. Say so briefly when the change looks good.`
+	defaultPRPrompt = `
+Write a pull request description for the branch below, from its commits and diff. Start with one or two sentences saying what the change does and why, then list the notable changes and how they were tested when the commits say so. Keep it under 250 words and reply only with the description in Markdown.`
 )
 
 type fileConfig struct {
-	ChatModel             string `json:"chat_model"`
-	DescriptionModel      string `json:"description_model"`
-	SystemPromptFile      string `json:"system_prompt_file"`
-	DescriptionPromptFile string `json:"description_prompt_file"`
-	DataDir               string `json:"data_dir"`
+	ChatModel             string               `json:"chat_model"`
+	DescriptionModel      string               `json:"description_model"`
+	SystemPromptFile      string               `json:"system_prompt_file"`
+	DescriptionPromptFile string               `json:"description_prompt_file"`
+	CommitPromptFile      string               `json:"commit_prompt_file"`
+	ReviewPromptFile      string               `json:"review_prompt_file"`
+	PRPromptFile          string               `json:"pr_prompt_file"`
+	DataDir               string               `json:"data_dir"`
+	PluginDir             string               `json:"plugin_dir"`
+	TemplateDir           string               `json:"template_dir"`
+	ProjectContext        ProjectContextConfig `json:"project_context"`
+	ResumeLast            bool                 `json:"resume_last"`
+	Agent                 AgentConfig          `json:"agent"`
+	MCPServers            map[string]MCPServer `json:"mcp_servers"`
+	Profiles              map[string]Profile   `json:"profiles"`
+	DefaultProfile        string               `json:"default_profile"`
+}
+
+// Profile is a named assistant persona that conversations can use instead
+// of the global settings. Empty fields keep the global value, and Agent adds
+// to the agent policy like a workspace policy does.
+type Profile struct {
+	Description      string       `json:"description,omitempty"`
+	SystemPromptFile string       `json:"system_prompt_file,omitempty"`
+	ChatModel        string       `json:"chat_model,omitempty"`
+	Temperature      *float32     `json:"temperature,omitempty"`
+	TopP             *float32     `json:"top_p,omitempty"`
+	MaxOutputTokens  *int32       `json:"max_output_tokens,omitempty"`
+	Agent            *AgentPolicy `json:"agent,omitempty"`
+
+	// SystemPrompt is read from SystemPromptFile during Load.
+	SystemPrompt string `json:"-"`
+}
+
+// ProjectContextConfig controls the project brief added to the system
+// instruction of chat and agent requests. MaxTokens bounds its size.
+type ProjectContextConfig struct {
+	Enabled   bool `json:"enabled"`
+	MaxTokens int  `json:"max_tokens,omitempty"`
+}
+
+// MCPServer launches a Model Context Protocol server over stdio. Command is
+// looked up in PATH unless it contains a slash.
+type MCPServer struct {
+	Command  string            `json:"command"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Dir      string            `json:"dir,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// CommandRule allows one executable for the agent's run-bash tool.
+// Subcommands restricts the first argument, Args holds regular expressions
+// every remaining argument must fully match, and Paths ("optional" or
+// "required") rewrites non-flag arguments into workspace paths.
+type CommandRule struct {
+	Command     string   `json:"command"`
+	Subcommands []string `json:"subcommands,omitempty"`
+	Args        []string `json:"args,omitempty"`
+	Paths       string   `json:"paths,omitempty"`
+}
+
+type AgentPolicy struct {
+	Commands        []CommandRule `json:"commands,omitempty"`
+	ReplaceDefaults bool          `json:"replace_defaults,omitempty"`
+}
+
+// AgentConfig holds the global agent policy and per-workspace additions keyed
+// by workspace directory. Policies live in the user's config rather than the
+// workspace so the agent cannot widen its own permissions by writing files.
+type AgentConfig struct {
+	AgentPolicy
+	Workspaces     map[string]AgentPolicy `json:"workspaces,omitempty"`
+	CommandTimeout string                 `json:"command_timeout,omitempty"`
+	MaxOutputBytes int                    `json:"max_output_bytes,omitempty"`
+	Sandbox        string                 `json:"sandbox,omitempty"`
+	WritablePaths  []string               `json:"writable_paths,omitempty"`
+
+	// Timeout is CommandTimeout parsed during Load.
+	Timeout time.Duration `json:"-"`
 }
 
 type Config struct {
@@ -75,6 +170,23 @@ type Config struct {
 	DescriptionPromptFile string
 	SystemPromptSource    string
 	DescriptionSource     string
+	CommitPrompt          string
+	CommitPromptFile      string
+	CommitPromptSource    string
+	ReviewPrompt          string
+	ReviewPromptFile      string
+	ReviewPromptSource    string
+	PRPrompt              string
+	PRPromptFile          string
+	PRPromptSource        string
+	PluginDir             string
+	TemplateDir           string
+	ProjectContext        ProjectContextConfig
+	ResumeLast            bool
+	Agent                 AgentConfig
+	MCPServers            map[string]MCPServer
+	Profiles              map[string]Profile
+	DefaultProfile        string
 }
 
 func Load() (*Config, error) {
@@ -95,8 +207,19 @@ func Load() (*Config, error) {
 		DescriptionPrompt:     strings.TrimSpace(defaultDescriptionPrompt),
 		SystemPromptFile:      filepath.Join(cfgDir, DefaultSystemPromptFileName),
 		DescriptionPromptFile: filepath.Join(cfgDir, DefaultTitlePromptFileName),
+		PluginDir:             filepath.Join(cfgDir, DefaultPluginDirName),
+		TemplateDir:           filepath.Join(cfgDir, DefaultTemplateDirName),
 		SystemPromptSource:    "built-in default",
 		DescriptionSource:     "built-in default",
+		CommitPrompt:          strings.TrimSpace(defaultCommitPrompt),
+		CommitPromptFile:      filepath.Join(cfgDir, DefaultCommitPromptFileName),
+		CommitPromptSource:    "built-in default",
+		ReviewPrompt:          strings.TrimSpace(defaultReviewPrompt),
+		ReviewPromptFile:      filepath.Join(cfgDir, DefaultReviewPromptFileName),
+		ReviewPromptSource:    "built-in default",
+		PRPrompt:              strings.TrimSpace(defaultPRPrompt),
+		PRPromptFile:          filepath.Join(cfgDir, DefaultPRPromptFileName),
+		PRPromptSource:        "built-in default",
 	}
 
 	if err := bootstrapDefaults(cfg); err != nil {
@@ -117,6 +240,15 @@ func Load() (*Config, error) {
 	if err := loadPromptFile(&cfg.DescriptionPrompt, &cfg.DescriptionSource, cfg.DescriptionPromptFile); err != nil {
 		return nil, err
 	}
+	if err := loadPromptFile(&cfg.CommitPrompt, &cfg.CommitPromptSource, cfg.CommitPromptFile); err != nil {
+		return nil, err
+	}
+	if err := loadPromptFile(&cfg.ReviewPrompt, &cfg.ReviewPromptSource, cfg.ReviewPromptFile); err != nil {
+		return nil, err
+	}
+	if err := loadPromptFile(&cfg.PRPrompt, &cfg.PRPromptSource, cfg.PRPromptFile); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -134,6 +266,15 @@ func bootstrapDefaults(cfg *Config) error {
 	if err := writeFileIfMissing(cfg.DescriptionPromptFile, cfg.DescriptionPrompt+"\n"); err != nil {
 		return fmt.Errorf("bootstrap description prompt: %w", err)
 	}
+	if err := writeFileIfMissing(cfg.CommitPromptFile, cfg.CommitPrompt+"\n"); err != nil {
+		return fmt.Errorf("bootstrap commit prompt: %w", err)
+	}
+	if err := writeFileIfMissing(cfg.ReviewPromptFile, cfg.ReviewPrompt+"\n"); err != nil {
+		return fmt.Errorf("bootstrap review prompt: %w", err)
+	}
+	if err := writeFileIfMissing(cfg.PRPromptFile, cfg.PRPrompt+"\n"); err != nil {
+		return fmt.Errorf("bootstrap pull request prompt: %w", err)
+	}
 
 	return nil
 }
@@ -167,10 +308,128 @@ func applyFileConfig(cfg *Config) error {
 	if fc.DescriptionPromptFile != "" {
 		cfg.DescriptionPromptFile = expandPath(fc.DescriptionPromptFile, cfg.ConfigDir)
 	}
+	if fc.CommitPromptFile != "" {
+		cfg.CommitPromptFile = expandPath(fc.CommitPromptFile, cfg.ConfigDir)
+	}
+	if fc.ReviewPromptFile != "" {
+		cfg.ReviewPromptFile = expandPath(fc.ReviewPromptFile, cfg.ConfigDir)
+	}
+	if fc.PRPromptFile != "" {
+		cfg.PRPromptFile = expandPath(fc.PRPromptFile, cfg.ConfigDir)
+	}
+	if fc.PluginDir != "" {
+		cfg.PluginDir = expandPath(fc.PluginDir, cfg.ConfigDir)
+	}
+	if fc.TemplateDir != "" {
+		cfg.TemplateDir = expandPath(fc.TemplateDir, cfg.ConfigDir)
+	}
+	cfg.ProjectContext = fc.ProjectContext
+	cfg.ResumeLast = fc.ResumeLast
+
+	cfg.Agent.AgentPolicy = fc.Agent.AgentPolicy
+	cfg.Agent.CommandTimeout = fc.Agent.CommandTimeout
+	cfg.Agent.MaxOutputBytes = fc.Agent.MaxOutputBytes
+	cfg.Agent.Sandbox = fc.Agent.Sandbox
+	cfg.Agent.WritablePaths = nil
+	for _, path := range fc.Agent.WritablePaths {
+		cfg.Agent.WritablePaths = append(cfg.Agent.WritablePaths, expandPath(path, cfg.ConfigDir))
+	}
+	if fc.Agent.CommandTimeout != "" {
+		timeout, err := time.ParseDuration(fc.Agent.CommandTimeout)
+		if err != nil {
+			return fmt.Errorf("parse agent command_timeout: %w", err)
+		}
+		cfg.Agent.Timeout = timeout
+	}
+	cfg.Agent.Workspaces = make(map[string]AgentPolicy, len(fc.Agent.Workspaces))
+	for dir, policy := range fc.Agent.Workspaces {
+		cfg.Agent.Workspaces[filepath.Clean(expandPath(dir, cfg.ConfigDir))] = policy
+	}
+
+	cfg.MCPServers = make(map[string]MCPServer, len(fc.MCPServers))
+	for name, server := range fc.MCPServers {
+		if strings.Contains(server.Command, "/") {
+			server.Command = expandPath(server.Command, cfg.ConfigDir)
+		}
+		if server.Dir != "" {
+			server.Dir = expandPath(server.Dir, cfg.ConfigDir)
+		}
+		cfg.MCPServers[name] = server
+	}
+
+	cfg.Profiles = make(map[string]Profile, len(fc.Profiles))
+	for name, profile := range fc.Profiles {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("profile name %q must be a single word", name)
+		}
+		if profile.SystemPromptFile != "" {
+			profile.SystemPromptFile = expandPath(profile.SystemPromptFile, cfg.ConfigDir)
+			data, err := os.ReadFile(profile.SystemPromptFile)
+			if err != nil {
+				return fmt.Errorf("profile %s: read system prompt: %w", name, err)
+			}
+			profile.SystemPrompt = strings.TrimSpace(string(data))
+		}
+		cfg.Profiles[name] = profile
+	}
+	if fc.DefaultProfile != "" {
+		if _, ok := cfg.Profiles[fc.DefaultProfile]; !ok {
+			return fmt.Errorf("default_profile %q is not defined in profiles", fc.DefaultProfile)
+		}
+	}
+	cfg.DefaultProfile = fc.DefaultProfile
 
 	return nil
 }
 
+// ProfileNames returns the names of the configured profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AgentPolicyForProfile is AgentPolicyFor with the additions of the named
+// profile. An empty or unknown name adds nothing.
+func (c *Config) AgentPolicyForProfile(workspace, profile string) AgentPolicy {
+	policy := c.AgentPolicyFor(workspace)
+	if p, ok := c.Profiles[profile]; ok && p.Agent != nil {
+		policy.Commands = append(policy.Commands, p.Agent.Commands...)
+		policy.ReplaceDefaults = policy.ReplaceDefaults || p.Agent.ReplaceDefaults
+	}
+	return policy
+}
+
+// AgentPolicyFor merges the global agent policy with the policy of the most
+// specific configured workspace containing workspace.
+func (c *Config) AgentPolicyFor(workspace string) AgentPolicy {
+	policy := AgentPolicy{
+		Commands:        append([]CommandRule(nil), c.Agent.Commands...),
+		ReplaceDefaults: c.Agent.ReplaceDefaults,
+	}
+
+	workspace = filepath.Clean(workspace)
+	best := ""
+	for dir := range c.Agent.Workspaces {
+		if dir != workspace && !strings.HasPrefix(workspace, dir+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) > len(best) {
+			best = dir
+		}
+	}
+	if best != "" {
+		scoped := c.Agent.Workspaces[best]
+		policy.Commands = append(policy.Commands, scoped.Commands...)
+		policy.ReplaceDefaults = policy.ReplaceDefaults || scoped.ReplaceDefaults
+	}
+
+	return policy
+}
+
 func loadPromptFile(target *string, source *string, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
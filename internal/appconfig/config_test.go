@@ -74,3 +74,146 @@ func TestLoadReadsPromptFilesAndModels(t *testing.T) {
 		t.Fatalf("unexpected data dir: %s", cfg.DataDir)
 	}
 }
+
+func TestLoadReadsAgentWorkspacePolicies(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".config", "vybr", "vyai")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+  "agent": {
+    "commands": [{"command": "make", "subcommands": ["test"]}],
+    "workspaces": {
+      "~/src/app": {"commands": [{"command": "npm", "subcommands": ["test"]}]}
+    }
+  }
+}`), 0644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	policy := cfg.AgentPolicyFor(filepath.Join(home, "src", "app", "web"))
+	if len(policy.Commands) != 2 || policy.Commands[1].Command != "npm" {
+		t.Fatalf("expected global and workspace rules, got %+v", policy.Commands)
+	}
+
+	policy = cfg.AgentPolicyFor(filepath.Join(home, "src", "other"))
+	if len(policy.Commands) != 1 || policy.Commands[0].Command != "make" {
+		t.Fatalf("expected only global rules, got %+v", policy.Commands)
+	}
+}
+
+func TestLoadReadsMCPServers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".config", "vybr", "vyai")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+  "mcp_servers": {
+    "files": {"command": "npx", "args": ["-y", "server-files"], "dir": "~/src"},
+    "local": {"command": "bin/server", "env": {"TOKEN": "x"}}
+  }
+}`), 0644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	files := cfg.MCPServers["files"]
+	if files.Command != "npx" || len(files.Args) != 2 || files.Dir != filepath.Join(home, "src") {
+		t.Fatalf("unexpected files server: %+v", files)
+	}
+	local := cfg.MCPServers["local"]
+	if local.Command != filepath.Join(configDir, "bin", "server") || local.Env["TOKEN"] != "x" {
+		t.Fatalf("unexpected local server: %+v", local)
+	}
+}
+
+func TestLoadReadsProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".config", "vybr", "vyai")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+  "agent": {"commands": [{"command": "make", "subcommands": ["test"]}]},
+  "default_profile": "reviewer",
+  "profiles": {
+    "reviewer": {
+      "description": "Strict code review",
+      "system_prompt_file": "reviewer.md",
+      "chat_model": "gemini-custom-review",
+      "temperature": 0.2,
+      "agent": {"commands": [{"command": "go", "subcommands": ["vet"]}]}
+    },
+    "writer": {"max_output_tokens": 512}
+  }
+}`), 0644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "reviewer.md"), []byte("Review the code.\n"), 0644); err != nil {
+		t.Fatalf("write profile prompt: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if names := cfg.ProfileNames(); len(names) != 2 || names[0] != "reviewer" || names[1] != "writer" {
+		t.Fatalf("unexpected profile names: %v", names)
+	}
+	if cfg.DefaultProfile != "reviewer" {
+		t.Fatalf("unexpected default profile: %q", cfg.DefaultProfile)
+	}
+	reviewer := cfg.Profiles["reviewer"]
+	if reviewer.SystemPrompt != "Review the code." || reviewer.ChatModel != "gemini-custom-review" {
+		t.Fatalf("unexpected reviewer profile: %+v", reviewer)
+	}
+	if reviewer.Temperature == nil || *reviewer.Temperature != 0.2 {
+		t.Fatalf("unexpected reviewer temperature: %v", reviewer.Temperature)
+	}
+	if writer := cfg.Profiles["writer"]; writer.MaxOutputTokens == nil || *writer.MaxOutputTokens != 512 || writer.SystemPrompt != "" {
+		t.Fatalf("unexpected writer profile: %+v", writer)
+	}
+
+	policy := cfg.AgentPolicyForProfile(home, "reviewer")
+	if len(policy.Commands) != 2 || policy.Commands[1].Command != "go" {
+		t.Fatalf("expected global and profile rules, got %+v", policy.Commands)
+	}
+	if policy := cfg.AgentPolicyForProfile(home, "writer"); len(policy.Commands) != 1 {
+		t.Fatalf("expected only global rules, got %+v", policy.Commands)
+	}
+}
+
+func TestLoadRejectsUnknownDefaultProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".config", "vybr", "vyai")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"default_profile": "missing"}`), 0644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for an undefined default profile")
+	}
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/project"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+	"github.com/vybraan/vyai/internal/templates"
+)
+
+const (
+	// maxPipedInput bounds what is read from stdin. Longer input keeps its
+	// end, which is where logs usually say what went wrong.
+	maxPipedInput = 512 << 10
+
+	// titleWait is how long a saved conversation waits for its title.
+	titleWait = 15 * time.Second
+)
+
+func runAsk(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ask", "ask [--profile NAME] [--model NAME] [--system TEXT | --system-file PATH] [--save] [--template NAME [name=value ...]] [question]", stderr)
+	profile := fs.String("profile", "", "answer as this profile instead of the default one")
+	model := fs.String("model", "", "chat model to use instead of the configured one")
+	system := fs.String("system", "", "system prompt to use instead of system_prompt.md")
+	systemFile := fs.String("system-file", "", "read the system prompt from this file")
+	save := fs.Bool("save", false, "save the exchange as a new conversation")
+	template := fs.String("template", "", "fill in this prompt template with name=value arguments")
+	timeout := fs.Duration("timeout", 2*time.Minute, "give up when the answer takes longer than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *system != "" && *systemFile != "" {
+		return usageError(fs, "use either --system or --system-file")
+	}
+
+	input, err := readPipedInput(stdin)
+	if err != nil {
+		return fmt.Errorf("read stdin: %w", err)
+	}
+	prompt := askPrompt(strings.Join(fs.Args(), " "), input)
+	if prompt == "" && *template == "" {
+		return usageError(fs, "a question or piped input is required")
+	}
+
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		return errors.New("GOOGLE_API_KEY environment variable is not set")
+	}
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return err
+	}
+	if *profile != "" {
+		if _, ok := cfg.Profiles[*profile]; !ok {
+			return fmt.Errorf("profile %q is not defined in %s", *profile, cfg.ConfigFile)
+		}
+		cfg.DefaultProfile = *profile
+	}
+	// The flags win over the profile as well as over the configured values.
+	p, hasProfile := cfg.Profiles[cfg.DefaultProfile]
+	if *model != "" {
+		cfg.ChatModel = *model
+		p.ChatModel = ""
+	}
+	if *systemFile != "" {
+		data, err := os.ReadFile(*systemFile)
+		if err != nil {
+			return err
+		}
+		*system = string(data)
+	}
+	if strings.TrimSpace(*system) != "" {
+		cfg.SystemPrompt = *system
+		p.SystemPrompt = ""
+	}
+	if hasProfile {
+		cfg.Profiles[cfg.DefaultProfile] = p
+	}
+	if *template != "" {
+		workspace, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		prompt, err = templates.Prompt(cfg.TemplateDir, *template, templates.Vars(fs.Args()), workspace, input)
+		if err != nil {
+			return err
+		}
+	}
+
+	gs := newService(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	out := &lineTracker{w: stdout}
+	if !*save {
+		_, err = gs.Complete(ctx, gemini.CompletionRequest{Profile: cfg.DefaultProfile, Prompt: prompt}, out.printAnswer)
+		out.finishLine()
+		return err
+	}
+
+	conversation, err := gs.NewConversation(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = gs.SendMessageStream(ctx, prompt, out.printAnswer)
+	out.finishLine()
+	if err != nil {
+		return err
+	}
+	waitForTitle(ctx, gs)
+	fmt.Fprintf(stderr, "Saved as %s\n", conversation.ID)
+	return nil
+}
+
+// readPipedInput reads stdin unless it is a terminal.
+func readPipedInput(stdin io.Reader) (string, error) {
+	if stdin == nil {
+		return "", nil
+	}
+	if f, ok := stdin.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return "", nil
+		}
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPipedInput {
+		data = data[len(data)-maxPipedInput:]
+		return "[input truncated to its last 512 KiB]\n" + string(data), nil
+	}
+	return string(data), nil
+}
+
+// askPrompt combines the question with the piped input. Either one alone
+// is the whole prompt.
+func askPrompt(question, input string) string {
+	question = strings.TrimSpace(question)
+	input = strings.TrimSpace(input)
+	switch {
+	case input == "":
+		return question
+	case question == "":
+		return input
+	}
+
+	fence := "```"
+	if strings.Contains(input, "```") {
+		fence = "~~~~"
+	}
+	return question + "\n\n" + fence + "\n" + input + "\n" + fence
+}
+
+// waitForTitle gives the title of a new conversation a chance to be
+// generated before the process exits.
+func waitForTitle(ctx context.Context, gs *gemini.GeminiService) {
+	timer := time.NewTimer(titleWait)
+	defer timer.Stop()
+	select {
+	case <-gs.DescriptionUpdates():
+	case <-gs.Notices():
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// lineTracker remembers whether the output ends with a newline, so the
+// shell prompt does not end up behind the answer.
+type lineTracker struct {
+	w       io.Writer
+	written bool
+	atStart bool
+	// printed is how much of the streamed answer printAnswer wrote.
+	printed int
+}
+
+// printAnswer is a stream callback. Each call carries the whole answer so
+// far, so only the part not printed yet is written.
+func (l *lineTracker) printAnswer(answer string) {
+	if len(answer) <= l.printed {
+		return
+	}
+	fmt.Fprint(l, answer[l.printed:])
+	l.printed = len(answer)
+}
+
+func (l *lineTracker) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.written = true
+		l.atStart = p[len(p)-1] == '\n'
+	}
+	return l.w.Write(p)
+}
+
+func (l *lineTracker) finishLine() {
+	if l.written && !l.atStart {
+		fmt.Fprintln(l.w)
+	}
+}
+
+// newService returns the chat service the non-interactive commands share,
+// with the project brief of the working directory.
+func newService(cfg *appconfig.Config) *gemini.GeminiService {
+	gs := gemini.NewGeminiService(gemini.NewConversationManager(), cfg)
+	if workspace, err := os.Getwd(); err == nil {
+		gs.SetProjectBrief(project.Brief(project.Detect(workspace), cfg.ProjectContext.MaxTokens))
+	}
+	return gs
+}
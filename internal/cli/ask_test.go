@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAskPromptFencesPipedInput(t *testing.T) {
+	t.Parallel()
+
+	if got := askPrompt(" why did this fail ", "panic: boom\n"); got != "why did this fail\n\n```\npanic: boom\n```" {
+		t.Fatalf("unexpected prompt: %q", got)
+	}
+	if got := askPrompt("", "explain this\n"); got != "explain this" {
+		t.Fatalf("expected piped input alone to be the prompt, got %q", got)
+	}
+	if got := askPrompt("summarize", "```go\nx\n```"); !strings.HasPrefix(got, "summarize\n\n~~~~\n```go") {
+		t.Fatalf("expected a fence that does not clash with the input, got %q", got)
+	}
+}
+
+func TestReadPipedInputKeepsTheEnd(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Repeat("a", maxPipedInput) + "the error"
+	got, err := readPipedInput(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readPipedInput returned error: %v", err)
+	}
+	if !strings.HasPrefix(got, "[input truncated") || !strings.HasSuffix(got, "the error") {
+		t.Fatalf("unexpected truncation: %q...", got[:40])
+	}
+}
+
+func TestAskRequiresAQuestion(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"ask"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage exit code, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "a question or piped input is required") {
+		t.Fatalf("expected an explanation, got %q", stderr.String())
+	}
+}
+
+func TestPrintAnswerWritesOnlyNewText(t *testing.T) {
+	t.Parallel()
+
+	var stdout bytes.Buffer
+	out := &lineTracker{w: &stdout}
+	for _, token := range []string{"H", "Hel", "Hello", "Hello", "Hello, world"} {
+		out.printAnswer(token)
+	}
+	out.finishLine()
+	if got := stdout.String(); got != "Hello, world\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+const auditDateLayout = "2006-01-02"
+
+func runAudit(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("audit", "audit [--since DATE] [--until DATE] [--workspace PATH] [--tool NAME] [--json]", stderr)
+	since := fs.String("since", "", "only entries at or after this date (YYYY-MM-DD or RFC 3339)")
+	until := fs.String("until", "", "only entries up to this date, inclusive for YYYY-MM-DD (YYYY-MM-DD or RFC 3339)")
+	workspace := fs.String("workspace", "", "only entries from this workspace or its subdirectories")
+	tool := fs.String("tool", "", "only entries for this tool, e.g. run-bash")
+	conversation := fs.String("conversation", "", "only entries from conversations with this ID prefix")
+	limit := fs.Int("limit", 0, "show only the most recent N entries")
+	asJSON := fs.Bool("json", false, "print matching entries as JSON lines")
+	logFile := fs.String("file", "", "read this audit log instead of the one in the data directory")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	filter := agent.AuditFilter{Tool: *tool, ConversationID: *conversation}
+	var err error
+	if filter.Since, err = parseAuditTime(*since, false); err != nil {
+		return usageError(fs, "invalid --since: %v", err)
+	}
+	if filter.Until, err = parseAuditTime(*until, true); err != nil {
+		return usageError(fs, "invalid --until: %v", err)
+	}
+	if *workspace != "" {
+		if filter.Workspace, err = filepath.Abs(*workspace); err != nil {
+			return err
+		}
+	}
+
+	path := *logFile
+	if path == "" {
+		cfg, err := appconfig.Load()
+		if err != nil {
+			return err
+		}
+		path = agent.AuditLogPath(cfg.DataDir)
+	}
+
+	entries, err := agent.ReadAuditLog(path, filter)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No audit entries.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tTOOL\tDECISION\tEXIT\tBYTES\tINPUT\tWORKSPACE")
+	for _, entry := range entries {
+		exit := "-"
+		if entry.ExitCode != nil {
+			exit = fmt.Sprint(*entry.ExitCode)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Tool,
+			entry.Decision,
+			exit,
+			entry.OutputBytes,
+			auditInput(entry),
+			entry.Workspace,
+		)
+	}
+	return tw.Flush()
+}
+
+// parseAuditTime accepts a date or an RFC 3339 timestamp. A bare date used
+// as an upper bound covers that whole day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(auditDateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// auditInput summarizes what a tool was asked to do: the executed argv for
+// commands, otherwise its attributes.
+func auditInput(entry agent.AuditEntry) string {
+	if len(entry.Argv) > 0 {
+		return strings.Join(entry.Argv, " ")
+	}
+	if entry.Command != "" {
+		return entry.Command
+	}
+
+	keys := make([]string, 0, len(entry.Attrs))
+	for key := range entry.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", key, entry.Attrs[key]))
+	}
+	return strings.Join(parts, " ")
+}
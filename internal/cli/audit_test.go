@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vybraan/vyai/internal/agent"
+)
+
+func TestAuditFiltersAndPrintsEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := agent.NewAuditLog(path)
+	zero := 0
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	for _, entry := range []agent.AuditEntry{
+		{Time: day, Workspace: "/srv/app", Tool: "run-bash", Argv: []string{"go", "test", "./..."}, ExitCode: &zero, Decision: agent.DecisionAllowed},
+		{Time: day, Workspace: "/srv/app", Tool: "read-file", Attrs: map[string]string{"path": "main.go"}, Decision: agent.DecisionNotRequired},
+		{Time: day.AddDate(0, 0, 2), Workspace: "/srv/app", Tool: "run-bash", Command: "rm -rf x", Decision: agent.DecisionDenied},
+	} {
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"audit", "--file", path, "--tool", "run-bash", "--until", "2026-03-01"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("audit exited with %d: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "go test ./...") || strings.Contains(out, "rm -rf") || strings.Contains(out, "main.go") {
+		t.Fatalf("unexpected audit output:\n%s", out)
+	}
+
+	stdout.Reset()
+	code = Run([]string{"audit", "--file", path, "--json", "--since", "2026-03-02"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("audit exited with %d: %s", code, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"decision":"denied"`) {
+		t.Fatalf("unexpected JSON output:\n%s", stdout.String())
+	}
+}
+
+func TestAuditRejectsInvalidDates(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"audit", "--file", "unused", "--since", "yesterday"}, nil, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage exit code, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "invalid --since") {
+		t.Fatalf("expected an explanation, got %q", stderr.String())
+	}
+}
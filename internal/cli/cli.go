@@ -0,0 +1,123 @@
+// Package cli implements vyai's non-interactive subcommands.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrUsage reports invalid arguments; the message has already been printed.
+var ErrUsage = errors.New("usage error")
+
+// StartInterface launches the interactive interface with a conversation
+// open. main sets it, since the interface needs the whole application.
+var StartInterface func(conversationID string) error
+
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"ask":        {summary: "Ask a one-off question and print the answer", run: runAsk},
+	"audit":      {summary: "Show the agent audit log", run: runAudit},
+	"cmd":        {summary: "Turn a description into a shell command", run: runCmd},
+	"commit":     {summary: "Write a commit message, review or pull request description", run: runCommit},
+	"explain":    {summary: "Explain a failed shell command and suggest a fix", run: runExplain},
+	"export":     {summary: "Export a conversation as Markdown or JSON", run: runExport},
+	"list":       {summary: "List stored conversations", run: runList},
+	"rename":     {summary: "Rename a conversation", run: runRename},
+	"resume":     {summary: "Open a conversation in the interactive interface", run: runResume},
+	"rm":         {summary: "Delete conversations", run: runRemove},
+	"serve":      {summary: "Serve the HTTP API on a local port or Unix socket", run: runServe},
+	"shell-init": {summary: "Print the shell integration for bash, zsh or fish", run: runShellInit},
+	"show":       {summary: "Print a conversation", run: runShow},
+}
+
+// Run executes the subcommand named by args[0] and returns the process
+// exit code. stdin is the input piped to vyai, if any.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stdout)
+		return 0
+	}
+
+	// "vyai --resume REF" is the same as "vyai resume REF".
+	if ref, ok := strings.CutPrefix(args[0], "--resume="); ok {
+		args = []string{"resume", ref}
+	} else if args[0] == "--resume" || args[0] == "-resume" {
+		args = append([]string{"resume"}, args[1:]...)
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "vyai: unknown command %q\n\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+
+	if err := cmd.run(args[1:], stdin, stdout, stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if errors.Is(err, ErrUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "vyai %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: vyai [command] [flags]")
+	fmt.Fprintln(w, "       vyai --resume ID|last|TITLE")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command vyai starts the interactive interface.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-11s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "vyai <command> -h" for the flags of a command.`)
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("vyai "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: vyai %s\n\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, turning flag errors into ErrUsage since the flag
+// package has already printed them.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return ErrUsage
+	}
+	return nil
+}
+
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), "%s\n", strings.TrimSpace(fmt.Sprintf(format, args...)))
+	fs.Usage()
+	return ErrUsage
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/utils"
+)
+
+func runCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("cmd", "cmd [--print] [--model NAME] <what the command should do>", stderr)
+	printOnly := fs.Bool("print", false, "print the command and exit without offering to run it")
+	model := fs.String("model", "", "chat model to use instead of the configured one")
+	timeout := fs.Duration("timeout", time.Minute, "give up when the answer takes longer than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	request := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if request == "" {
+		return usageError(fs, "describe what the command should do")
+	}
+
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		return errors.New("GOOGLE_API_KEY environment variable is not set")
+	}
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return err
+	}
+	if *model != "" {
+		cfg.ChatModel = *model
+	}
+	workspace, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	policy, err := agent.PolicyFromConfig(cfg.AgentPolicyFor(workspace))
+	if err != nil {
+		return err
+	}
+	gs := newService(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	shell := userShell()
+	answer, err := gs.AskStream(ctx, cmdPrompt(request, filepath.Base(shell)), func(string) {})
+	if err != nil {
+		return err
+	}
+	command, explanation := splitSuggestion(answer)
+	if command == "" {
+		fmt.Fprintln(stdout, strings.TrimSpace(answer))
+		return errors.New("no single command was suggested")
+	}
+	if *printOnly {
+		fmt.Fprintln(stdout, command)
+		return nil
+	}
+
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	return offerCommand(command, explanation, policy, workspace, bufio.NewReader(stdin), stdout, cmdActions{
+		run:  func(command string) error { return runShellCommand(shell, command) },
+		copy: copyToClipboard,
+		edit: editCommand,
+	})
+}
+
+// cmdPrompt asks for one command with a short explanation after it.
+func cmdPrompt(request, shell string) string {
+	return fmt.Sprintf("Write a single %s command for %s that does the following: %s\n\n"+
+		"Reply with the command in one ```sh block, on a single line, followed by one or two sentences that explain it. "+
+		"Prefer commands that only read or report over ones that change the system. "+
+		"If it cannot be done with one command, say so instead and do not include a block.", shell, runtime.GOOS, request)
+}
+
+// splitSuggestion separates the suggested command from the explanation
+// around it.
+func splitSuggestion(answer string) (command, explanation string) {
+	command = suggestedCommand(answer)
+	if command == "" {
+		return "", ""
+	}
+	explanation = fencedBlock.ReplaceAllString(answer, "")
+	return command, strings.Join(strings.Fields(explanation), " ")
+}
+
+// cmdActions are the things offerCommand can do with the command.
+type cmdActions struct {
+	run  func(command string) error
+	copy func(command string) error
+	edit func(command string) (string, error)
+}
+
+// offerCommand shows the command with its risk in dir and asks what to do
+// with it. Nothing runs without an explicit choice, and dangerous commands need
+// "yes" on top of that. End of input is a refusal.
+func offerCommand(command, explanation string, policy *agent.CommandPolicy, dir string, in *bufio.Reader, out io.Writer, actions cmdActions) error {
+	for {
+		assessment := agent.ClassifyCommand(command, policy, dir)
+		fmt.Fprintf(out, "\n  %s\n\n", command)
+		if explanation != "" {
+			fmt.Fprintf(out, "%s\n", explanation)
+		}
+		fmt.Fprintf(out, "Risk: %s (%s)\n\n", assessment.Risk, strings.Join(assessment.Reasons, ", "))
+
+		choice, ok := ask(in, out, "Run, copy, edit or quit? [r/c/e/Q] ")
+		if !ok {
+			return nil
+		}
+		switch strings.ToLower(choice) {
+		case "r", "run":
+			if assessment.Risk == agent.RiskDangerous {
+				answer, _ := ask(in, out, "This command is dangerous. Type yes to run it anyway: ")
+				if answer != "yes" {
+					fmt.Fprintln(out, "Not run.")
+					return nil
+				}
+			}
+			return actions.run(command)
+		case "c", "copy":
+			if err := actions.copy(command); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "Copied to the clipboard.")
+			return nil
+		case "e", "edit":
+			edited, err := actions.edit(command)
+			if err != nil {
+				return err
+			}
+			if edited != command {
+				command, explanation = edited, ""
+			}
+		case "", "q", "quit":
+			return nil
+		}
+	}
+}
+
+// ask prints prompt and reads one line. ok is false at the end of input.
+func ask(in *bufio.Reader, out io.Writer, prompt string) (answer string, ok bool) {
+	fmt.Fprint(out, prompt)
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(out)
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
+
+func runShellCommand(shell, command string) error {
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// copyToClipboard uses the system clipboard, and the terminal's OSC 52
+// sequence when there is no clipboard tool, as over SSH.
+func copyToClipboard(text string) error {
+	if err := clipboard.WriteAll(text); err == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(os.Stderr, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// editCommand opens the command in the editor and returns what was saved.
+func editCommand(command string) (string, error) {
+	editor, err := utils.FindEditor()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "vyai-command_*.sh")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(command + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(editor, f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor exited with an error: %w", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	if edited := strings.TrimSpace(string(data)); edited != "" {
+		return edited, nil
+	}
+	return command, nil
+}
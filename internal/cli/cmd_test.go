@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitSuggestion(t *testing.T) {
+	t.Parallel()
+
+	command, explanation := splitSuggestion("```sh\nfind /var/log -name '*.log' -mtime +7 -size +100M\n```\nLists log files over 100 MB\nthat were last changed more than a week ago.")
+	if command != "find /var/log -name '*.log' -mtime +7 -size +100M" {
+		t.Fatalf("unexpected command: %q", command)
+	}
+	if explanation != "Lists log files over 100 MB that were last changed more than a week ago." {
+		t.Fatalf("unexpected explanation: %q", explanation)
+	}
+	if command, _ := splitSuggestion("That needs a script, not a single command."); command != "" {
+		t.Fatalf("expected no command, got %q", command)
+	}
+}
+
+func TestOfferCommandNeedsConfirmation(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		command string
+		input   string
+		ran     string
+		output  string
+	}{
+		"end of input":        {command: "du -sh /var/log", input: "", output: "Risk: review"},
+		"quit":                {command: "du -sh /var/log", input: "q\n", output: "Run, copy, edit or quit?"},
+		"run":                 {command: "ls -la", input: "r\n", ran: "ls -la", output: "Risk: safe"},
+		"dangerous declined":  {command: "rm -rf /tmp/build", input: "r\ny\n", output: "Not run."},
+		"dangerous confirmed": {command: "rm -rf /tmp/build", input: "r\nyes\n", ran: "rm -rf /tmp/build", output: "deletes files"},
+		"edited":              {command: "rm -rf /tmp/build", input: "e\nr\n", ran: "ls build", output: "Risk: safe"},
+	} {
+		var out bytes.Buffer
+		ran := ""
+		err := offerCommand(tc.command, "", nil, t.TempDir(), bufio.NewReader(strings.NewReader(tc.input)), &out, cmdActions{
+			run:  func(command string) error { ran = command; return nil },
+			copy: func(string) error { return nil },
+			edit: func(string) (string, error) { return "ls build", nil },
+		})
+		if err != nil {
+			t.Fatalf("%s: offerCommand returned error: %v", name, err)
+		}
+		if ran != tc.ran {
+			t.Fatalf("%s: expected %q to run, got %q", name, tc.ran, ran)
+		}
+		if !strings.Contains(out.String(), tc.output) {
+			t.Fatalf("%s: expected %q in output:\n%s", name, tc.output, out.String())
+		}
+	}
+}
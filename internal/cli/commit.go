@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/utils"
+)
+
+// maxDiffBytes bounds the diff sent to the model. Longer diffs keep their
+// beginning; the stat before them still lists every file.
+const maxDiffBytes = 256 << 10
+
+func runCommit(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("commit", "commit [--review | --pr BASE] [--print] [--model NAME]", stderr)
+	review := fs.Bool("review", false, "review the staged changes instead of committing them")
+	base := fs.String("pr", "", "describe the commits since `BASE` as a pull request instead")
+	printOnly := fs.Bool("print", false, "print the commit message instead of committing")
+	model := fs.String("model", "", "chat model to use instead of the configured one")
+	timeout := fs.Duration("timeout", 2*time.Minute, "give up when the answer takes longer than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if *review && *base != "" {
+		return usageError(fs, "use either --review or --pr")
+	}
+
+	var changes string
+	var err error
+	if *base != "" {
+		changes, err = branchChanges("", *base)
+	} else {
+		changes, err = stagedChanges("")
+	}
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		return errors.New("GOOGLE_API_KEY environment variable is not set")
+	}
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return err
+	}
+	if *model != "" {
+		cfg.ChatModel = *model
+	}
+	gs := newService(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	if *review || *base != "" {
+		prompt := cfg.ReviewPrompt
+		if *base != "" {
+			prompt = cfg.PRPrompt
+		}
+		out := &lineTracker{w: stdout}
+		_, err := gs.AskStream(ctx, prompt+"\n\n"+changes, out.printAnswer)
+		out.finishLine()
+		return err
+	}
+
+	answer, err := gs.AskStream(ctx, cfg.CommitPrompt+"\n\n"+changes, func(string) {})
+	if err != nil {
+		return err
+	}
+	message := commitMessage(answer)
+	if message == "" {
+		return errors.New("the model did not suggest a commit message")
+	}
+	if *printOnly {
+		fmt.Fprintln(stdout, message)
+		return nil
+	}
+	return editAndCommit(message, stdout, stderr)
+}
+
+// stagedChanges returns the stat and diff of what is staged in dir.
+func stagedChanges(dir string) (string, error) {
+	stat, err := git(dir, "diff", "--cached", "--stat", "--no-color")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(stat) == "" {
+		return "", errors.New("nothing is staged; add changes with git add first")
+	}
+	diff, err := git(dir, "diff", "--cached", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "", err
+	}
+	return "Staged changes:\n" + fencedDiff(stat, diff), nil
+}
+
+// branchChanges returns the commits and diff of HEAD since it left base.
+func branchChanges(dir, base string) (string, error) {
+	log, err := git(dir, "log", "--reverse", "--no-color", "--format=- %s%n%w(0,2,2)%b", base+"..HEAD")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(log) == "" {
+		return "", fmt.Errorf("HEAD has no commits that are not in %s", base)
+	}
+	stat, err := git(dir, "diff", "--stat", "--no-color", base+"...HEAD")
+	if err != nil {
+		return "", err
+	}
+	diff, err := git(dir, "diff", "--no-color", "--no-ext-diff", base+"...HEAD")
+	if err != nil {
+		return "", err
+	}
+	return "Commits:\n" + strings.TrimSpace(log) + "\n\nChanges:\n" + fencedDiff(stat, diff), nil
+}
+
+func fencedDiff(stat, diff string) string {
+	if len(diff) > maxDiffBytes {
+		diff = diff[:maxDiffBytes] + "\n[diff truncated]"
+	}
+	fence := "```"
+	if strings.Contains(diff, "```") {
+		fence = "~~~~"
+	}
+	return strings.TrimRight(stat, "\n") + "\n\n" + fence + "diff\n" + strings.TrimRight(diff, "\n") + "\n" + fence
+}
+
+// git runs git in dir and returns its output, with git's own message as
+// the error.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// commitMessage cleans up the model's answer: a message wrapped in a code
+// block is unwrapped.
+func commitMessage(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if blocks := fencedBlock.FindAllStringSubmatch(answer, -1); len(blocks) == 1 && strings.HasPrefix(answer, blocks[0][1]) {
+		answer = strings.TrimSpace(blocks[0][2])
+	}
+	return answer
+}
+
+const commitTemplateHelp = `
+# Save to commit with this message. Lines starting with '#' are ignored,
+# and quitting without saving or leaving the message empty aborts.
+`
+
+// editAndCommit opens the message in the editor and commits the staged
+// changes with it once the file is saved.
+func editAndCommit(message string, stdout, stderr io.Writer) error {
+	editor, err := utils.FindEditor()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "vyai-commit_*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(message + "\n" + commitTemplateHelp); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	before, err := os.Stat(f.Name())
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(editor, f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor exited with an error: %w", err)
+	}
+
+	after, err := os.Stat(f.Name())
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+	if after.ModTime().Equal(before.ModTime()) || stripComments(string(data)) == "" {
+		fmt.Fprintln(stderr, "Commit aborted.")
+		return nil
+	}
+
+	commit := exec.Command("git", "commit", "--cleanup=strip", "--file", f.Name())
+	commit.Stdout, commit.Stderr = stdout, stderr
+	return commit.Run()
+}
+
+// stripComments removes the lines git ignores in a commit message.
+func stripComments(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
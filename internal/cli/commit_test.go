@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitMessageUnwrapsCodeBlocks(t *testing.T) {
+	t.Parallel()
+
+	if got := commitMessage("```\nfix(server): reject foreign origins\n\nBrowsers could reach the API.\n```\n"); got != "fix(server): reject foreign origins\n\nBrowsers could reach the API." {
+		t.Fatalf("unexpected message: %q", got)
+	}
+	message := "docs: show how to run\n\nUse:\n```sh\nvyai serve\n```"
+	if got := commitMessage(message); got != message {
+		t.Fatalf("expected a block inside the body to be kept, got %q", got)
+	}
+	if got := stripComments("feat: add x\n" + commitTemplateHelp); got != "feat: add x" {
+		t.Fatalf("unexpected stripped message: %q", got)
+	}
+}
+
+func TestStagedAndBranchChanges(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("README.md", "hello\n")
+	run("add", "README.md")
+	if _, err := stagedChanges(dir); err != nil {
+		t.Fatalf("stagedChanges returned error: %v", err)
+	}
+	run("commit", "-q", "-m", "initial")
+	if _, err := stagedChanges(dir); err == nil || !strings.Contains(err.Error(), "nothing is staged") {
+		t.Fatalf("expected nothing to be staged, got %v", err)
+	}
+
+	run("checkout", "-q", "-b", "feature")
+	write("main.go", "package main\n")
+	run("add", "main.go")
+	staged, err := stagedChanges(dir)
+	if err != nil {
+		t.Fatalf("stagedChanges returned error: %v", err)
+	}
+	if !strings.Contains(staged, "main.go | 1 +") || !strings.Contains(staged, "```diff\ndiff --git a/main.go b/main.go") {
+		t.Fatalf("unexpected staged changes:\n%s", staged)
+	}
+
+	run("commit", "-q", "-m", "feat: add main", "-m", "Entry point.")
+	changes, err := branchChanges(dir, "main")
+	if err != nil {
+		t.Fatalf("branchChanges returned error: %v", err)
+	}
+	if !strings.HasPrefix(changes, "Commits:\n- feat: add main\n  Entry point.\n\nChanges:\n") || !strings.Contains(changes, "+package main") {
+		t.Fatalf("unexpected branch changes:\n%s", changes)
+	}
+	if _, err := branchChanges(dir, "feature"); err == nil {
+		t.Fatal("expected an error without new commits")
+	}
+}
@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+const conversationTimeLayout = "2006-01-02 15:04"
+
+// conversationSummary is the JSON form of a conversation in vyai list.
+type conversationSummary struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	ChatModel string    `json:"chat_model"`
+	Profile   string    `json:"profile,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  int       `json:"messages"`
+}
+
+// dataDirFlag adds the flag that points the conversation commands at
+// another data directory.
+func dataDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("data-dir", "", "read conversations from this data directory instead of the configured one")
+}
+
+func openStore(dataDir string) (*gemini.FileConversationStore, error) {
+	if dataDir == "" {
+		cfg, err := appconfig.Load()
+		if err != nil {
+			return nil, err
+		}
+		dataDir = cfg.DataDir
+	}
+	return gemini.NewFileConversationStore(dataDir), nil
+}
+
+// findRecord loads the conversations and picks the one ref refers to.
+func findRecord(store *gemini.FileConversationStore, ref string) (gemini.ConversationRecord, error) {
+	records, err := store.LoadAll()
+	if err != nil {
+		return gemini.ConversationRecord{}, err
+	}
+	return gemini.FindConversation(records, ref)
+}
+
+func runList(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("list", "list [--limit N] [--json]", stderr)
+	limit := fs.Int("limit", 0, "show only the N most recently updated conversations")
+	asJSON := fs.Bool("json", false, "print conversations as JSON lines, one object per conversation, so they can be streamed into jq")
+	dataDir := dataDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	store, err := openStore(*dataDir)
+	if err != nil {
+		return err
+	}
+	records, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(records) > *limit {
+		records = records[:*limit]
+	}
+
+	if *asJSON {
+		return writeSummariesJSON(stdout, records)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(stdout, "No conversations.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUPDATED\tMESSAGES\tMODEL\tTITLE")
+	for _, record := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			record.ID,
+			record.UpdatedAt.Local().Format(conversationTimeLayout),
+			len(record.Messages),
+			record.ChatModel,
+			record.Description,
+		)
+	}
+	return tw.Flush()
+}
+
+func runShow(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("show", "show [--json] ID", stderr)
+	asJSON := fs.Bool("json", false, "print the stored conversation as JSON")
+	dataDir := dataDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected one conversation ID")
+	}
+
+	store, err := openStore(*dataDir)
+	if err != nil {
+		return err
+	}
+	record, err := findRecord(store, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeRecordJSON(stdout, record)
+	}
+
+	fmt.Fprintf(stdout, "%s\n%s, %s, updated %s\n",
+		record.Description,
+		record.ID,
+		record.ChatModel,
+		record.UpdatedAt.Local().Format(conversationTimeLayout),
+	)
+	for _, message := range record.Messages {
+		fmt.Fprintf(stdout, "\n[%s]\n%s\n", speaker(message), strings.TrimSpace(message.Text))
+	}
+	return nil
+}
+
+func runRemove(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rm", "rm [--json] ID...", stderr)
+	asJSON := fs.Bool("json", false, "print the deleted conversations as JSON lines, like list --json")
+	dataDir := dataDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "expected at least one conversation ID")
+	}
+
+	store, err := openStore(*dataDir)
+	if err != nil {
+		return err
+	}
+	records, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	// Resolve every ID first so a typo deletes nothing.
+	var targets []gemini.ConversationRecord
+	for _, ref := range fs.Args() {
+		record, err := gemini.FindConversation(records, ref)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, record)
+	}
+	for _, record := range targets {
+		if err := store.Delete(record.ID); err != nil {
+			return err
+		}
+		if !*asJSON {
+			fmt.Fprintf(stdout, "Deleted %s (%s)\n", record.ID, record.Description)
+		}
+	}
+	if *asJSON {
+		return writeSummariesJSON(stdout, targets)
+	}
+	return nil
+}
+
+func runRename(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rename", "rename [--json] ID TITLE", stderr)
+	asJSON := fs.Bool("json", false, "print the renamed conversation as JSON, like list --json")
+	dataDir := dataDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError(fs, "expected a conversation ID and a title")
+	}
+	title := strings.TrimSpace(strings.Join(fs.Args()[1:], " "))
+	if title == "" {
+		return usageError(fs, "conversation title cannot be empty")
+	}
+
+	store, err := openStore(*dataDir)
+	if err != nil {
+		return err
+	}
+	record, err := findRecord(store, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// A title given by hand is never replaced by a generated one.
+	record.Description = title
+	record.DescriptionLocked = true
+	if err := store.Save(record); err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeSummariesJSON(stdout, []gemini.ConversationRecord{record})
+	}
+	fmt.Fprintf(stdout, "Renamed %s to %q\n", record.ID, title)
+	return nil
+}
+
+func runExport(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("export", "export [--format markdown|json] [--output FILE] ID", stderr)
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	dataDir := dataDirFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected one conversation ID")
+	}
+	if *format != "markdown" && *format != "json" {
+		return usageError(fs, "unknown format %q", *format)
+	}
+
+	store, err := openStore(*dataDir)
+	if err != nil {
+		return err
+	}
+	record, err := findRecord(store, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if *format == "json" {
+		if err := writeRecordJSON(&out, record); err != nil {
+			return err
+		}
+	} else {
+		out.WriteString(conversationMarkdown(record))
+	}
+	if *output == "" {
+		_, err = stdout.Write(out.Bytes())
+		return err
+	}
+	return os.WriteFile(*output, out.Bytes(), 0644)
+}
+
+func runResume(args []string, _ io.Reader, _, stderr io.Writer) error {
+	fs := newFlagSet("resume", "resume ID|last|TITLE", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "expected a conversation ID, last or part of a title")
+	}
+	if StartInterface == nil {
+		return errors.New("the interactive interface is not available")
+	}
+
+	store, err := openStore("")
+	if err != nil {
+		return err
+	}
+	records, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+	record, err := gemini.ResolveConversation(records, strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+	return StartInterface(record.ID)
+}
+
+func summarize(record gemini.ConversationRecord) conversationSummary {
+	return conversationSummary{
+		ID:        record.ID,
+		Title:     record.Description,
+		ChatModel: record.ChatModel,
+		Profile:   record.Profile,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Messages:  len(record.Messages),
+	}
+}
+
+// writeSummariesJSON prints one conversationSummary per line.
+func writeSummariesJSON(w io.Writer, records []gemini.ConversationRecord) error {
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(summarize(record)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeRecordJSON(w io.Writer, record gemini.ConversationRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(record)
+}
+
+// conversationMarkdown renders a conversation as a Markdown document.
+// Answers are Markdown already, so they are included as they are.
+func conversationMarkdown(record gemini.ConversationRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", record.Description)
+	fmt.Fprintf(&b, "- ID: %s\n", record.ID)
+	fmt.Fprintf(&b, "- Model: %s\n", record.ChatModel)
+	if record.Profile != "" {
+		fmt.Fprintf(&b, "- Profile: %s\n", record.Profile)
+	}
+	fmt.Fprintf(&b, "- Created: %s\n", record.CreatedAt.Local().Format(conversationTimeLayout))
+	fmt.Fprintf(&b, "- Updated: %s\n", record.UpdatedAt.Local().Format(conversationTimeLayout))
+	for _, message := range record.Messages {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", speaker(message), strings.TrimSpace(message.Text))
+	}
+	return b.String()
+}
+
+func speaker(message gemini.Message) string {
+	name := "vyai"
+	if message.Role == "user" {
+		name = "You"
+	}
+	if message.Kind == gemini.MessageKindAgent {
+		name += " (agent)"
+	}
+	return name
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+func seedConversations(t *testing.T) string {
+	t.Helper()
+	dataDir := t.TempDir()
+	store := gemini.NewFileConversationStore(dataDir)
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, record := range []gemini.ConversationRecord{
+		{ID: "CONVERSATION-AB12", Description: "Nginx reload", UpdatedAt: updated, ChatModel: "gemini-test", Messages: []gemini.Message{
+			{Role: "user", Text: "how do I reload nginx?"},
+			{Role: "model", Text: "Run `nginx -s reload`."},
+		}},
+		{ID: "CONVERSATION-AB34", Description: "Disk usage", UpdatedAt: updated.Add(time.Hour), ChatModel: "gemini-test"},
+	} {
+		if err := store.Save(record); err != nil {
+			t.Fatalf("Save returned error: %v", err)
+		}
+	}
+	return dataDir
+}
+
+func TestListAndShowConversations(t *testing.T) {
+	t.Parallel()
+
+	dataDir := seedConversations(t)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"list", "--data-dir", dataDir, "--json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("list exited with %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var first conversationSummary
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("parse list output: %v", err)
+	}
+	if len(lines) != 2 || first.ID != "CONVERSATION-AB34" {
+		t.Fatalf("expected the most recent conversation first, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"show", "--data-dir", dataDir, "ab1"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("show exited with %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.HasPrefix(out, "Nginx reload\n") || !strings.Contains(out, "[You]\nhow do I reload nginx?") {
+		t.Fatalf("unexpected show output:\n%s", out)
+	}
+
+	stderr.Reset()
+	if code := Run([]string{"show", "--data-dir", dataDir, "ab"}, nil, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "ambiguous") {
+		t.Fatalf("expected an ambiguous prefix to fail, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestRenameExportAndRemoveConversation(t *testing.T) {
+	t.Parallel()
+
+	dataDir := seedConversations(t)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"rename", "--data-dir", dataDir, "--json", "CONVERSATION-AB12", "Reloading", "nginx"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("rename exited with %d: %s", code, stderr.String())
+	}
+	var renamed conversationSummary
+	if err := json.Unmarshal(stdout.Bytes(), &renamed); err != nil || renamed.Title != "Reloading nginx" {
+		t.Fatalf("unexpected rename --json output %q: %v", stdout.String(), err)
+	}
+
+	output := filepath.Join(t.TempDir(), "export.md")
+	if code := Run([]string{"export", "--data-dir", dataDir, "--output", output, "ab12"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("export exited with %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Reloading nginx\n") || !strings.Contains(string(data), "## vyai\n\nRun `nginx -s reload`.") {
+		t.Fatalf("unexpected export:\n%s", data)
+	}
+
+	if code := Run([]string{"rm", "--data-dir", dataDir, "ab12", "missing"}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected rm with an unknown ID to fail, got %d", code)
+	}
+	stdout.Reset()
+	if code := Run([]string{"rm", "--data-dir", dataDir, "--json", "ab12"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("rm exited with %d: %s", code, stderr.String())
+	}
+	var removed conversationSummary
+	if err := json.Unmarshal(stdout.Bytes(), &removed); err != nil || removed.ID != "CONVERSATION-AB12" || removed.Title != "Reloading nginx" {
+		t.Fatalf("unexpected rm --json output %q: %v", stdout.String(), err)
+	}
+	records, err := gemini.NewFileConversationStore(dataDir).LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll returned error: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "CONVERSATION-AB34" {
+		t.Fatalf("unexpected records after rm: %+v", records)
+	}
+}
+
+func TestResumeFlagIsTheResumeCommand(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"--resume"}, nil, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage exit code, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "Usage: vyai resume") {
+		t.Fatalf("expected the resume usage, got %q", stderr.String())
+	}
+}
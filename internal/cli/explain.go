@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+func runExplain(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("explain", "explain --command CMD [--exit CODE] [--stderr-file PATH] [--fix-file PATH]", stderr)
+	command := fs.String("command", "", "the command line to explain")
+	exitCode := fs.Int("exit", -1, "exit status of the command")
+	stderrFile := fs.String("stderr-file", "", "read what the command wrote to stderr from this file")
+	fixFile := fs.String("fix-file", "", "write the suggested command to this file")
+	shell := fs.String("shell", filepath.Base(os.Getenv("SHELL")), "shell the command ran in")
+	model := fs.String("model", "", "chat model to use instead of the configured one")
+	timeout := fs.Duration("timeout", 2*time.Minute, "give up when the answer takes longer than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q; pass the command with --command", fs.Arg(0))
+	}
+	if strings.TrimSpace(*command) == "" {
+		return usageError(fs, "no command to explain; the shell integration from vyai shell-init passes it")
+	}
+
+	output, err := readPipedInput(stdin)
+	if err != nil {
+		return fmt.Errorf("read stdin: %w", err)
+	}
+	if *stderrFile != "" {
+		output, err = readStderrFile(*stderrFile)
+		if err != nil {
+			return err
+		}
+		output = trimEcho(output, *command)
+	}
+
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		return errors.New("GOOGLE_API_KEY environment variable is not set")
+	}
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return err
+	}
+	if *model != "" {
+		cfg.ChatModel = *model
+	}
+	gs := newService(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	out := &lineTracker{w: stdout}
+	answer, err := gs.AskStream(ctx, explainPrompt(*command, *exitCode, *shell, output), out.printAnswer)
+	out.finishLine()
+	if err != nil {
+		return err
+	}
+
+	if *fixFile != "" {
+		return os.WriteFile(*fixFile, []byte(suggestedCommand(answer)), 0600)
+	}
+	return nil
+}
+
+// readStderrFile reads what the shell integration captured. A missing
+// file means nothing was captured.
+func readStderrFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return readPipedInput(f)
+}
+
+// trimEcho drops the prompt and command line bash echoes to stderr, which
+// the capture can pick up before the command's own output.
+func trimEcho(output, command string) string {
+	command = strings.TrimSpace(command)
+	first, rest, _ := strings.Cut(output, "\n")
+	if command != "" && strings.HasSuffix(strings.TrimSpace(ansiEscape.ReplaceAllString(first, "")), command) {
+		return rest
+	}
+	return output
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// explainPrompt asks for an explanation of a command and, when another
+// command would fix it, for that command in a block of its own.
+func explainPrompt(command string, exitCode int, shell, output string) string {
+	var b strings.Builder
+	if exitCode == 0 {
+		b.WriteString("Explain what this shell command did and whether anything went wrong.\n\n")
+	} else {
+		b.WriteString("Explain why this shell command failed and how to fix it.\n\n")
+	}
+	if shell != "" && shell != "." {
+		fmt.Fprintf(&b, "Shell: %s\n", shell)
+	}
+	if exitCode >= 0 {
+		fmt.Fprintf(&b, "Exit status: %d%s\n", exitCode, exitStatusNote(exitCode))
+	}
+	b.WriteString("\n```sh\n" + strings.TrimSpace(command) + "\n```\n")
+
+	output = strings.TrimSpace(ansiEscape.ReplaceAllString(output, ""))
+	if output != "" {
+		fence := "```"
+		if strings.Contains(output, "```") {
+			fence = "~~~~"
+		}
+		b.WriteString("\nIt wrote this to stderr:\n" + fence + "\n" + output + "\n" + fence + "\n")
+	}
+
+	b.WriteString("\nKeep the explanation short. If a different command would fix the problem, " +
+		"end with exactly one ```sh block that holds only that command, on a single line.")
+	return b.String()
+}
+
+// exitStatusNote describes the exit statuses shells give a meaning to.
+func exitStatusNote(code int) string {
+	switch {
+	case code == 126:
+		return " (found but not executable)"
+	case code == 127:
+		return " (command not found)"
+	case code > 128 && code < 160:
+		return fmt.Sprintf(" (killed by signal %d)", code-128)
+	}
+	return ""
+}
+
+var fencedBlock = regexp.MustCompile("(?ms)^(```|~~~)[a-z]*[ \t]*\n(.*?)\n[ \t]*(```|~~~)[ \t]*$")
+
+// suggestedCommand returns the command in the last fenced block of answer,
+// or "" when that block is not a single command.
+func suggestedCommand(answer string) string {
+	blocks := fencedBlock.FindAllStringSubmatch(answer, -1)
+	if len(blocks) == 0 {
+		return ""
+	}
+	fix := strings.TrimSpace(blocks[len(blocks)-1][2])
+	fix = strings.TrimPrefix(fix, "$ ")
+	if fix == "" || strings.Contains(fix, "\n") {
+		return ""
+	}
+	return fix
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplainPromptDescribesTheFailure(t *testing.T) {
+	t.Parallel()
+
+	got := explainPrompt("  systemctl restart ngnix ", 5, "bash", "\x1b[31mFailed to restart ngnix.service: Unit ngnix.service not found.\x1b[0m\n")
+	for _, want := range []string{
+		"why this shell command failed",
+		"Shell: bash\nExit status: 5\n",
+		"```sh\nsystemctl restart ngnix\n```",
+		"```\nFailed to restart ngnix.service: Unit ngnix.service not found.\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in prompt:\n%s", want, got)
+		}
+	}
+	if got := explainPrompt("htop", 127, "", ""); !strings.Contains(got, "Exit status: 127 (command not found)") || strings.Contains(got, "stderr") {
+		t.Fatalf("unexpected prompt without output:\n%s", got)
+	}
+}
+
+func TestSuggestedCommandTakesTheLastBlock(t *testing.T) {
+	t.Parallel()
+
+	answer := "The unit is misspelled:\n```\nUnit ngnix.service not found\n```\nUse:\n```sh\n$ sudo systemctl restart nginx\n```\n"
+	if got := suggestedCommand(answer); got != "sudo systemctl restart nginx" {
+		t.Fatalf("unexpected fix: %q", got)
+	}
+	if got := suggestedCommand("Run these:\n```bash\napt update\napt install htop\n```"); got != "" {
+		t.Fatalf("expected no fix for several commands, got %q", got)
+	}
+	if got := suggestedCommand("Nothing to fix."); got != "" {
+		t.Fatalf("expected no fix, got %q", got)
+	}
+}
+
+func TestTrimEchoDropsThePromptLine(t *testing.T) {
+	t.Parallel()
+
+	if got := trimEcho("user@host:~$ ls /nope\nls: cannot access '/nope'\n", "ls /nope"); got != "ls: cannot access '/nope'\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	if got := trimEcho("error: boom\n", "make"); got != "error: boom\n" {
+		t.Fatalf("expected output without an echo to be kept, got %q", got)
+	}
+}
+
+func TestShellInitPrintsHooks(t *testing.T) {
+	t.Parallel()
+
+	for shell, want := range map[string]string{
+		"bash":         "bind -x '\"\\ee\": __vyai_fix'",
+		"/usr/bin/zsh": "add-zsh-hook precmd __vyai_precmd",
+		"fish":         "--on-event fish_postexec",
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := Run([]string{"shell-init", shell}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("%s: unexpected exit code %d: %s", shell, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), want) || !strings.Contains(stdout.String(), "explain --shell") {
+			t.Fatalf("%s: unexpected hook:\n%s", shell, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"shell-init", "tcsh"}, nil, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "bash, fish, zsh") {
+		t.Fatalf("expected tcsh to be refused, got %d: %s", code, stderr.String())
+	}
+}
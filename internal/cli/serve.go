@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+	"github.com/vybraan/vyai/internal/server"
+)
+
+const defaultServeAddr = "127.0.0.1:8765"
+
+func runServe(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("serve", "serve [--addr HOST:PORT | --socket PATH] [--token TOKEN]", stderr)
+	addr := fs.String("addr", defaultServeAddr, "loopback address to listen on")
+	socket := fs.String("socket", "", "listen on this Unix socket instead of a TCP port")
+	token := fs.String("token", os.Getenv("VYAI_SERVE_TOKEN"), "require this bearer token (default $VYAI_SERVE_TOKEN)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected argument %q", fs.Arg(0))
+	}
+	if *socket == "" {
+		if err := checkLoopbackAddr(*addr); err != nil {
+			return usageError(fs, "invalid --addr: %v", err)
+		}
+	}
+
+	if os.Getenv("GOOGLE_API_KEY") == "" {
+		return errors.New("GOOGLE_API_KEY environment variable is not set")
+	}
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return err
+	}
+	gs := newService(cfg)
+	if err := gs.LoadStoredConversations(); err != nil {
+		return err
+	}
+
+	listener, where, err := listen(*addr, *socket)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go logServiceEvents(ctx, gs, stderr)
+
+	srv := &http.Server{
+		Handler:           server.New(gs, *token).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stdout, "Serving the vyai API on %s\n", where)
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// checkLoopbackAddr refuses addresses other machines could reach: the API
+// has no authentication unless a token is set.
+func checkLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%s is not a loopback address", host)
+	}
+	return nil
+}
+
+// listen opens the TCP or Unix socket listener. A stale socket file left
+// by an earlier run is replaced, and the new one is private to the user.
+func listen(addr, socket string) (net.Listener, string, error) {
+	if socket == "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, "", err
+		}
+		return listener, "http://" + listener.Addr().String(), nil
+	}
+
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, "", fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, "", err
+		}
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, "", err
+	}
+	return listener, "unix:" + socket, nil
+}
+
+// logServiceEvents prints the notices the service would show in the TUI
+// and drains title updates, which nothing else reads.
+func logServiceEvents(ctx context.Context, gs *gemini.GeminiService, stderr io.Writer) {
+	for {
+		select {
+		case notice := <-gs.Notices():
+			fmt.Fprintln(stderr, notice.Message)
+		case <-gs.DescriptionUpdates():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
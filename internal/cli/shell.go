@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func runShellInit(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("shell-init", "shell-init bash|zsh|fish", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "name one shell: %s", shellNames())
+	}
+	snippet, ok := shellHooks[filepath.Base(fs.Arg(0))]
+	if !ok {
+		return usageError(fs, "unsupported shell %q; use one of %s", fs.Arg(0), shellNames())
+	}
+	_, err := fmt.Fprint(stdout, snippet)
+	return err
+}
+
+func shellNames() string {
+	names := make([]string, 0, len(shellHooks))
+	for name := range shellHooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// shellHooks remember the last command line and its exit status for
+// "vyai explain". "vx" explains the last command, and Alt-e also puts the
+// suggested fix on the command line. Commands run as vx are not recorded,
+// so asking twice explains the same command.
+//
+// bash and zsh capture stderr as well when VYAI_CAPTURE_STDERR is set. The
+// shell's stderr then goes through tee, which makes programs see a pipe
+// instead of the terminal, so it is off by default.
+var shellHooks = map[string]string{
+	"bash": bashHook,
+	"zsh":  zshHook,
+	"fish": fishHook,
+}
+
+const bashHook = `# vyai shell integration. Add to ~/.bashrc:
+#   eval "$(vyai shell-init bash)"
+__vyai_status=0
+__vyai_command=
+__vyai_dir=
+__vyai_at_prompt=1
+
+__vyai_precmd() {
+    local status=$? command
+    command=$(HISTTIMEFORMAT= builtin history 1 | sed 's/^ *[0-9]*[* ] *//')
+    case $command in
+        vx|vx\ *) return ;;
+    esac
+    __vyai_status=$status
+    __vyai_command=$command
+    if [ -n "$__vyai_dir" ]; then
+        cp -- "$__vyai_dir/live" "$__vyai_dir/last" 2>/dev/null
+    fi
+}
+
+__vyai_preexec() {
+    [ -n "$__vyai_at_prompt" ] || return
+    __vyai_at_prompt=
+    : >| "$__vyai_dir/live"
+}
+
+__vyai_explain() {
+    local args=(explain --shell bash --command "$__vyai_command" --exit "$__vyai_status")
+    [ -n "$__vyai_dir" ] && args+=(--stderr-file "$__vyai_dir/last")
+    command vyai "${args[@]}" "$@"
+}
+
+vx() {
+    __vyai_explain "$@"
+}
+
+__vyai_fix() {
+    local file fix
+    file=$(mktemp) || return
+    __vyai_explain --fix-file "$file" </dev/null
+    fix=$(<"$file")
+    rm -f -- "$file"
+    if [ -n "$fix" ]; then
+        READLINE_LINE=$fix
+        READLINE_POINT=${#fix}
+    fi
+}
+
+if [ -n "$VYAI_CAPTURE_STDERR" ] && __vyai_dir=$(mktemp -d); then
+    exec 2> >(tee -a "$__vyai_dir/live" >&2)
+    trap '__vyai_preexec' DEBUG
+fi
+PROMPT_COMMAND="__vyai_precmd;${PROMPT_COMMAND:+$PROMPT_COMMAND;}__vyai_at_prompt=1"
+bind -x '"\ee": __vyai_fix'
+`
+
+const zshHook = `# vyai shell integration. Add to ~/.zshrc:
+#   eval "$(vyai shell-init zsh)"
+typeset -g __vyai_status=0 __vyai_command= __vyai_pending= __vyai_dir=
+
+__vyai_preexec() {
+    __vyai_pending=$1
+    [[ -n $__vyai_dir ]] && : >| "$__vyai_dir/live"
+}
+
+__vyai_precmd() {
+    local exit_status=$?
+    [[ -n $__vyai_pending ]] || return
+    local command=$__vyai_pending
+    __vyai_pending=
+    [[ $command == vx || $command == "vx "* ]] && return
+    __vyai_status=$exit_status
+    __vyai_command=$command
+    [[ -n $__vyai_dir ]] && cp -- "$__vyai_dir/live" "$__vyai_dir/last" 2>/dev/null
+}
+
+__vyai_explain() {
+    local args=(explain --shell zsh --command "$__vyai_command" --exit "$__vyai_status")
+    [[ -n $__vyai_dir ]] && args+=(--stderr-file "$__vyai_dir/last")
+    command vyai "${args[@]}" "$@"
+}
+
+vx() {
+    __vyai_explain "$@"
+}
+
+__vyai_fix() {
+    local file fix
+    file=$(mktemp) || return
+    zle -I
+    __vyai_explain --fix-file "$file" </dev/null
+    fix=$(<"$file")
+    rm -f -- "$file"
+    if [[ -n $fix ]]; then
+        BUFFER=$fix
+        CURSOR=${#BUFFER}
+    fi
+}
+
+if [[ -n $VYAI_CAPTURE_STDERR ]] && __vyai_dir=$(mktemp -d); then
+    exec 2> >(tee -a "$__vyai_dir/live" >&2)
+fi
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __vyai_preexec
+add-zsh-hook precmd __vyai_precmd
+zle -N __vyai_fix
+bindkey '\ee' __vyai_fix
+`
+
+const fishHook = `# vyai shell integration. Add to ~/.config/fish/config.fish:
+#   vyai shell-init fish | source
+set -g __vyai_status 0
+set -g __vyai_command ''
+
+function __vyai_postexec --on-event fish_postexec
+    set -l exit_status $status
+    string match -qr '^vx(\s|$)' -- $argv[1]; and return
+    set -g __vyai_status $exit_status
+    set -g __vyai_command $argv[1]
+end
+
+function __vyai_explain
+    command vyai explain --shell fish --command "$__vyai_command" --exit "$__vyai_status" $argv
+end
+
+function vx
+    __vyai_explain $argv
+end
+
+function __vyai_fix
+    set -l file (mktemp); or return
+    echo
+    __vyai_explain --fix-file $file </dev/null
+    set -l fix (string collect < $file)
+    rm -f -- $file
+    if test -n "$fix"
+        commandline -r -- $fix
+    end
+    commandline -f repaint
+end
+
+bind \ee __vyai_fix
+`
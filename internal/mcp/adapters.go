@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// AgentTools exposes the tools as agent sections named
+// mcp-<server>-<tool>. Arguments are given as a JSON object in the section
+// content, or as attributes for simple tools.
+func (m *Manager) AgentTools() []agent.Tool {
+	var tools []agent.Tool
+	for _, st := range m.Tools() {
+		name := SectionName(st.Server, st.Tool.Name)
+		tools = append(tools, agent.Tool{
+			Name:        name,
+			Usage:       sectionUsage(name, st.Tool.InputSchema),
+			Description: toolDescription(st),
+			Run: func(ctx context.Context, attrs map[string]string, content string) (string, error) {
+				args, err := sectionArgs(st.Tool.InputSchema, attrs, content)
+				if err != nil {
+					return "", err
+				}
+				return m.Call(ctx, st.Server, st.Tool.Name, args)
+			},
+		})
+	}
+	return tools
+}
+
+// ChatTools exposes the tools as functions the chat model can call.
+func (m *Manager) ChatTools() []gemini.ChatTool {
+	var tools []gemini.ChatTool
+	for _, st := range m.Tools() {
+		tools = append(tools, gemini.ChatTool{
+			Name:        gemini.FunctionName(st.Server, st.Tool.Name),
+			Description: toolDescription(st),
+			Parameters:  st.Tool.InputSchema,
+			Call: func(ctx context.Context, args map[string]any) (string, error) {
+				return m.Call(ctx, st.Server, st.Tool.Name, args)
+			},
+		})
+	}
+	return tools
+}
+
+// SectionName builds a PromptWeaver tag name for a server tool.
+func SectionName(server, tool string) string {
+	parts := []string{"mcp"}
+	for _, part := range []string{server, tool} {
+		if part = strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(part), "-"), "-"); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func toolDescription(st ServerTool) string {
+	description := strings.TrimSpace(st.Tool.Description)
+	if description == "" {
+		description = st.Tool.Title
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (MCP server %s)", description, st.Server))
+}
+
+type argSchema struct {
+	Properties map[string]struct {
+		Type any `json:"type"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// sectionUsage shows the model how to call a tool: required arguments are
+// listed inside a JSON object.
+func sectionUsage(name string, schema json.RawMessage) string {
+	var parsed argSchema
+	_ = json.Unmarshal(schema, &parsed)
+	if len(parsed.Properties) == 0 {
+		return "<" + name + "></" + name + ">"
+	}
+
+	fields := make([]string, 0, len(parsed.Required))
+	for _, field := range parsed.Required {
+		fields = append(fields, fmt.Sprintf("%q: ...", field))
+	}
+	args := "{" + strings.Join(fields, ", ") + "}"
+	if len(fields) < len(parsed.Properties) {
+		args += " (optional: " + strings.Join(optionalFields(parsed), ", ") + ")"
+	}
+	return "<" + name + ">" + args + "</" + name + ">"
+}
+
+func optionalFields(schema argSchema) []string {
+	required := map[string]bool{}
+	for _, field := range schema.Required {
+		required[field] = true
+	}
+	var fields []string
+	for field := range schema.Properties {
+		if !required[field] {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sectionArgs reads the arguments of an agent section: a JSON object in the
+// content, otherwise attributes converted to the types in the schema.
+func sectionArgs(schema json.RawMessage, attrs map[string]string, content string) (map[string]any, error) {
+	args := map[string]any{}
+	if content = strings.TrimSpace(content); content != "" {
+		if err := json.Unmarshal([]byte(content), &args); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+
+	var parsed argSchema
+	_ = json.Unmarshal(schema, &parsed)
+	for key, value := range attrs {
+		if _, ok := args[key]; ok {
+			continue
+		}
+		args[key] = attrValue(value, parsed.Properties[key].Type)
+	}
+	return args, nil
+}
+
+func attrValue(value string, typ any) any {
+	name, _ := typ.(string)
+	switch name {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "array", "object":
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
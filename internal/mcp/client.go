@@ -0,0 +1,423 @@
+// Package mcp is a minimal Model Context Protocol client for servers
+// launched over stdio. It covers what vyai needs: the initialize handshake,
+// tool listing and tool calls.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the MCP revision sent during initialization.
+const ProtocolVersion = "2025-06-18"
+
+const (
+	// closeTimeout is how long a server gets to exit after stdin closes.
+	closeTimeout = 2 * time.Second
+	// stderrTail is how much server stderr is kept for error messages.
+	stderrTail = 2048
+)
+
+// ServerConfig describes how to launch one server.
+type ServerConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Env     map[string]string
+	Dir     string
+}
+
+// Tool is a tool advertised by a server. InputSchema is the JSON schema of
+// its arguments.
+type Tool struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is one block of a tool result.
+type Content struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	MimeType string           `json:"mimeType,omitempty"`
+	Data     string           `json:"data,omitempty"`
+	URI      string           `json:"uri,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// CallResult is the outcome of tools/call. IsError marks failures reported
+// by the tool itself, as opposed to protocol errors.
+type CallResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// RPCError is a JSON-RPC error returned by the server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// Client is a connection to one running server.
+type Client struct {
+	name string
+	cmd  *exec.Cmd
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan response
+	closed  bool
+	readErr error
+	done    chan struct{}
+
+	stderr *tailBuffer
+}
+
+// Start launches the server and performs the initialize handshake.
+func Start(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	if strings.TrimSpace(cfg.Command) == "" {
+		return nil, fmt.Errorf("mcp server %s: command is required", cfg.Name)
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(cfg.Env))
+	for key := range cfg.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd.Env = append(cmd.Env, key+"="+cfg.Env[key])
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tailBuffer{limit: stderrTail}
+	cmd.Stderr = stderr
+	cmd.WaitDelay = closeTimeout
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp server %s: %w", cfg.Name, err)
+	}
+
+	c := &Client{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: map[int64]chan response{},
+		done:    make(chan struct{}),
+		stderr:  stderr,
+	}
+	go c.readLoop(stdout)
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("mcp server %s: initialize: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "vyai", "version": "dev"},
+	}
+	if _, err := c.call(ctx, "initialize", params); err != nil {
+		return err
+	}
+	return c.notify("notifications/initialized", nil)
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := c.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("parse tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool with JSON arguments.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	raw, err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		return nil, err
+	}
+
+	var result CallResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("parse tools/call result: %w", err)
+	}
+	return &result, nil
+}
+
+// Close shuts the server down: stdin is closed so it can exit on its own,
+// and it is killed if it has not after closeTimeout.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(closeTimeout):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	ch := make(chan response, 1)
+
+	c.mu.Lock()
+	if c.closed || c.readErr != nil {
+		err := c.connErrLocked()
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.send(message{ID: &id, Method: method, Params: mustMarshal(params)}); err != nil {
+		c.forget(id)
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res.result, res.err
+	case <-ctx.Done():
+		c.forget(id)
+		c.notify("notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) notify(method string, params any) error {
+	msg := message{Method: method}
+	if params != nil {
+		msg.Params = mustMarshal(params)
+	}
+	return c.send(msg)
+}
+
+func (c *Client) send(msg message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write to mcp server %s: %w", c.name, err)
+	}
+	return nil
+}
+
+func (c *Client) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *Client) readLoop(stdout io.Reader) {
+	defer close(c.done)
+
+	reader := bufio.NewReader(stdout)
+	var err error
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			c.handle(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	if err == io.EOF {
+		err = errors.New("server exited")
+	}
+	c.readErr = err
+	pending := c.pending
+	c.pending = map[int64]chan response{}
+	connErr := c.connErrLocked()
+	c.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- response{err: connErr}
+	}
+}
+
+func (c *Client) handle(line []byte) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		// Servers sometimes log to stdout; skip anything that is not JSON-RPC.
+		return
+	}
+
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		c.answer(msg)
+	case msg.Method != "":
+		// Notifications such as logging or list changes are not used.
+	case msg.ID != nil:
+		c.mu.Lock()
+		ch, ok := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		if msg.Error != nil {
+			ch <- response{err: msg.Error}
+			return
+		}
+		ch <- response{result: msg.Result}
+	}
+}
+
+// answer replies to requests the server sends to the client. Only ping is
+// supported since vyai advertises no client capabilities.
+func (c *Client) answer(msg message) {
+	reply := message{ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage(`{}`)
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	c.send(reply)
+}
+
+func (c *Client) connErrLocked() error {
+	if c.closed {
+		return fmt.Errorf("mcp server %s is closed", c.name)
+	}
+	msg := fmt.Sprintf("mcp server %s: %v", c.name, c.readErr)
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		msg += ": " + tail
+	}
+	return errors.New(msg)
+}
+
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mcp: marshal %T: %v", v, err))
+	}
+	return data
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = b.buf[len(b.buf)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func startStub(t *testing.T) *Client {
+	t.Helper()
+
+	client, err := Start(context.Background(), ServerConfig{Name: "stub", Command: stubServer})
+	if err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientListsToolsAcrossPages(t *testing.T) {
+	t.Parallel()
+
+	tools, err := startStub(t).ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools returned error: %v", err)
+	}
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if strings.Join(names, ",") != "echo,add,fail" {
+		t.Fatalf("unexpected tools: %v", names)
+	}
+	if !strings.Contains(string(tools[1].InputSchema), `"integer"`) {
+		t.Fatalf("expected input schema, got %s", tools[1].InputSchema)
+	}
+}
+
+func TestClientCallsTools(t *testing.T) {
+	t.Parallel()
+
+	client := startStub(t)
+	result, err := client.CallTool(context.Background(), "add", map[string]any{"a": 2, "b": 3})
+	if err != nil {
+		t.Fatalf("CallTool returned error: %v", err)
+	}
+	if result.IsError || RenderResult(result) != "5" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	result, err = client.CallTool(context.Background(), "fail", nil)
+	if err != nil {
+		t.Fatalf("CallTool returned error: %v", err)
+	}
+	if !result.IsError || RenderResult(result) != "something went wrong" {
+		t.Fatalf("expected tool error result, got %+v", result)
+	}
+
+	_, err = client.CallTool(context.Background(), "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown tool: missing") {
+		t.Fatalf("expected protocol error, got %v", err)
+	}
+}
+
+func TestClientReportsClosedServer(t *testing.T) {
+	t.Parallel()
+
+	client := startStub(t)
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	_, err := client.CallTool(context.Background(), "echo", map[string]any{"text": "hi"})
+	if err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+}
+
+func TestStartFailsForMissingCommand(t *testing.T) {
+	t.Parallel()
+
+	_, err := Start(context.Background(), ServerConfig{Name: "ghost", Command: "vyai-no-such-mcp-server"})
+	if err == nil || !strings.Contains(err.Error(), "ghost") {
+		t.Fatalf("expected start error naming the server, got %v", err)
+	}
+}
+
+func TestRenderResultDescribesBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	got := RenderResult(&CallResult{Content: []Content{
+		{Type: "text", Text: "chart:"},
+		{Type: "image", MimeType: "image/png", Data: "aGVsbG8="},
+		{Type: "resource", Resource: &ResourceContent{URI: "file:///tmp/a.bin"}},
+	}})
+	want := "chart:\n\n[image: image/png, 5 bytes]\n\n[resource: file:///tmp/a.bin]"
+	if got != want {
+		t.Fatalf("unexpected rendering:\n%s", got)
+	}
+}
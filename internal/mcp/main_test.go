@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// stubServer is the path of the stub MCP server built for the tests.
+var stubServer string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "vyai-mcp-stub")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "create stub dir:", err)
+		os.Exit(1)
+	}
+
+	stubServer = filepath.Join(dir, "stub-server")
+	if runtime.GOOS == "windows" {
+		stubServer += ".exe"
+	}
+	build := exec.Command("go", "build", "-o", stubServer, "./testdata/stub-server")
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "build stub server:", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,198 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+// startTimeout bounds the handshake and tool listing of each server.
+const startTimeout = 20 * time.Second
+
+// ServerTool is a tool together with the server that offers it.
+type ServerTool struct {
+	Server string
+	Tool   Tool
+}
+
+// Manager owns the configured servers for the lifetime of the process.
+type Manager struct {
+	clients map[string]*Client
+	tools   []ServerTool
+}
+
+// Configs returns the enabled servers of the configuration, sorted by name.
+func Configs(servers map[string]appconfig.MCPServer) []ServerConfig {
+	names := make([]string, 0, len(servers))
+	for name, server := range servers {
+		if !server.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	configs := make([]ServerConfig, 0, len(names))
+	for _, name := range names {
+		server := servers[name]
+		configs = append(configs, ServerConfig{
+			Name:    name,
+			Command: server.Command,
+			Args:    server.Args,
+			Env:     server.Env,
+			Dir:     server.Dir,
+		})
+	}
+	return configs
+}
+
+// StartAll launches the servers in parallel. A server that fails to start or
+// list its tools is skipped and reported in the returned errors, so one
+// broken entry does not disable the others.
+func StartAll(ctx context.Context, configs []ServerConfig) (*Manager, []error) {
+	type started struct {
+		client *Client
+		tools  []Tool
+		err    error
+	}
+
+	results := make([]started, len(configs))
+	var wg sync.WaitGroup
+	for i, cfg := range configs {
+		wg.Add(1)
+		go func(i int, cfg ServerConfig) {
+			defer wg.Done()
+			startCtx, cancel := context.WithTimeout(ctx, startTimeout)
+			defer cancel()
+
+			client, err := Start(startCtx, cfg)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			tools, err := client.ListTools(startCtx)
+			if err != nil {
+				client.Close()
+				results[i].err = fmt.Errorf("mcp server %s: list tools: %w", cfg.Name, err)
+				return
+			}
+			results[i] = started{client: client, tools: tools}
+		}(i, cfg)
+	}
+	wg.Wait()
+
+	m := &Manager{clients: map[string]*Client{}}
+	var errs []error
+	for _, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		name := result.client.Name()
+		m.clients[name] = result.client
+		for _, tool := range result.tools {
+			m.tools = append(m.tools, ServerTool{Server: name, Tool: tool})
+		}
+	}
+	sort.SliceStable(m.tools, func(i, j int) bool {
+		if m.tools[i].Server != m.tools[j].Server {
+			return m.tools[i].Server < m.tools[j].Server
+		}
+		return m.tools[i].Tool.Name < m.tools[j].Tool.Name
+	})
+	return m, errs
+}
+
+// Tools returns every tool of the running servers, sorted by server and
+// tool name.
+func (m *Manager) Tools() []ServerTool {
+	if m == nil {
+		return nil
+	}
+	return append([]ServerTool(nil), m.tools...)
+}
+
+// Call invokes a tool and renders its result as text. Results the tool
+// marks as errors are returned together with an error.
+func (m *Manager) Call(ctx context.Context, server, tool string, args map[string]any) (string, error) {
+	client, ok := m.clients[server]
+	if !ok {
+		return "", fmt.Errorf("mcp server %s is not running", server)
+	}
+	result, err := client.CallTool(ctx, tool, args)
+	if err != nil {
+		return "", err
+	}
+	output := RenderResult(result)
+	if result.IsError {
+		if output == "" {
+			output = "tool reported an error"
+		}
+		return "", errors.New(output)
+	}
+	return output, nil
+}
+
+// Close shuts every server down.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, client := range m.clients {
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			client.Close()
+		}(client)
+	}
+	wg.Wait()
+}
+
+// RenderResult turns tool result content into text. Binary content is
+// described rather than included.
+func RenderResult(result *CallResult) string {
+	if result == nil {
+		return ""
+	}
+
+	var blocks []string
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			blocks = append(blocks, content.Text)
+		case "image", "audio":
+			blocks = append(blocks, fmt.Sprintf("[%s: %s, %d bytes]", content.Type, content.MimeType, base64Size(content.Data)))
+		case "resource":
+			if content.Resource == nil {
+				continue
+			}
+			if content.Resource.Text != "" {
+				blocks = append(blocks, content.Resource.Text)
+			} else {
+				blocks = append(blocks, fmt.Sprintf("[resource: %s]", content.Resource.URI))
+			}
+		case "resource_link":
+			label := content.URI
+			if content.Name != "" {
+				label = content.Name + " " + content.URI
+			}
+			blocks = append(blocks, fmt.Sprintf("[resource: %s]", label))
+		}
+	}
+	if len(blocks) == 0 && len(result.StructuredContent) > 0 {
+		blocks = append(blocks, string(result.StructuredContent))
+	}
+	return strings.TrimSpace(strings.Join(blocks, "\n\n"))
+}
+
+// base64Size is the decoded size of base64 data.
+func base64Size(data string) int {
+	data = strings.TrimRight(data, "=")
+	return len(data) * 3 / 4
+}
@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/agent"
+)
+
+func startManager(t *testing.T) *Manager {
+	t.Helper()
+
+	manager, errs := StartAll(context.Background(), []ServerConfig{
+		{Name: "stub", Command: stubServer},
+		{Name: "broken", Command: "vyai-no-such-mcp-server"},
+	})
+	t.Cleanup(manager.Close)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Fatalf("expected one start error for the broken server, got %v", errs)
+	}
+	return manager
+}
+
+func TestManagerExposesAgentTools(t *testing.T) {
+	t.Parallel()
+
+	tools := startManager(t).AgentTools()
+	if len(tools) != 3 || tools[0].Name != "mcp-stub-add" {
+		t.Fatalf("unexpected agent tools: %+v", tools)
+	}
+	if !strings.Contains(tools[0].Usage, `"a": ...`) {
+		t.Fatalf("expected usage with required arguments, got %q", tools[0].Usage)
+	}
+
+	var outputs []string
+	runner := agent.NewLocalRunner(agent.Env{Workspace: t.TempDir(), Tools: tools}, nil)
+	_, err := runner.Run(context.Background(), agent.RunRequest{
+		Input: `<mcp-stub-add a="2" b="40"></mcp-stub-add>` +
+			`<mcp-stub-echo>{"text": "hello"}</mcp-stub-echo>` +
+			`<mcp-stub-fail></mcp-stub-fail>` +
+			`<summary>done</summary>`,
+		OnEvent: func(ev agent.Event) {
+			if ev.Kind == agent.EventToolFinished {
+				outputs = append(outputs, ev.Output)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(outputs) != 3 || outputs[0] != "42" || outputs[1] != "hello" {
+		t.Fatalf("unexpected tool outputs: %q", outputs)
+	}
+	if !strings.Contains(outputs[2], "Tool error: something went wrong") {
+		t.Fatalf("expected tool error, got %q", outputs[2])
+	}
+}
+
+func TestManagerExposesChatTools(t *testing.T) {
+	t.Parallel()
+
+	tools := startManager(t).ChatTools()
+	if len(tools) != 3 || tools[1].Name != "stub__echo" {
+		t.Fatalf("unexpected chat tools: %+v", tools)
+	}
+
+	output, err := tools[1].Call(context.Background(), map[string]any{"text": "hi"})
+	if err != nil || output != "hi" {
+		t.Fatalf("unexpected call result %q, %v", output, err)
+	}
+	if _, err := tools[2].Call(context.Background(), nil); err == nil {
+		t.Fatal("expected error from failing tool")
+	}
+}
+
+func TestSectionNameIsValidTag(t *testing.T) {
+	t.Parallel()
+
+	if got := SectionName("GitHub Server", "list_issues"); got != "mcp-github-server-list-issues" {
+		t.Fatalf("unexpected section name %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Command stub-server is a tiny MCP server used by the mcp package tests. It
+// speaks newline-delimited JSON-RPC on stdio and offers a few tools.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+var tools = []map[string]any{
+	{
+		"name":        "echo",
+		"description": "Echo the given text.",
+		"inputSchema": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			"required":   []string{"text"},
+		},
+	},
+	{
+		"name":        "add",
+		"description": "Add two integers.",
+		"inputSchema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"a": map[string]any{"type": "integer"},
+				"b": map[string]any{"type": "integer"},
+			},
+			"required": []string{"a", "b"},
+		},
+	},
+	{
+		"name":        "fail",
+		"description": "Always report an error.",
+		"inputSchema": map[string]any{"type": "object"},
+	},
+}
+
+func main() {
+	out := json.NewEncoder(os.Stdout)
+	// Some servers log to stdout before speaking JSON-RPC.
+	fmt.Println("stub server starting")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		result, rpcErr := handle(req)
+		reply := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != nil {
+			reply["error"] = rpcErr
+		} else {
+			reply["result"] = result
+		}
+		out.Encode(reply)
+	}
+}
+
+func handle(req request) (any, map[string]any) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": "2025-06-18",
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "stub", "version": "1"},
+		}, nil
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(req.Params, &params)
+		// Serve the tools in two pages to exercise pagination.
+		if params.Cursor == "" {
+			return map[string]any{"tools": tools[:1], "nextCursor": "page-2"}, nil
+		}
+		return map[string]any{"tools": tools[1:]}, nil
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, map[string]any{"code": -32602, "message": err.Error()}
+		}
+		return callTool(params.Name, params.Arguments)
+	}
+	return nil, map[string]any{"code": -32601, "message": "method not found: " + req.Method}
+}
+
+func callTool(name string, args map[string]any) (any, map[string]any) {
+	switch name {
+	case "echo":
+		text, _ := args["text"].(string)
+		return textResult(strings.TrimSpace(text), false), nil
+	case "add":
+		a, _ := args["a"].(float64)
+		b, _ := args["b"].(float64)
+		return textResult(fmt.Sprint(a+b), false), nil
+	case "fail":
+		return textResult("something went wrong", true), nil
+	}
+	return nil, map[string]any{"code": -32602, "message": "unknown tool: " + name}
+}
+
+func textResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
@@ -0,0 +1,118 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxTokens is the budget of a brief when none is configured.
+const DefaultMaxTokens = 800
+
+// EstimateTokens approximates the token count of text at four bytes per
+// token, which is close enough for budgeting prompts.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Brief renders info as a compact markdown brief of at most maxTokens.
+// Sections are added in priority order, the hand-written context first and
+// the README last; the first one that does not fit is cut at a line
+// boundary and the rest are left out. It returns "" when nothing was
+// detected.
+func Brief(info Info, maxTokens int) string {
+	if info.Empty() {
+		return ""
+	}
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+	budget := maxTokens * 4
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project context for the workspace %s", info.Workspace)
+	if info.Root != info.Workspace {
+		fmt.Fprintf(&b, " (repository root %s)", info.Root)
+	}
+	b.WriteString(":\n")
+
+	for _, section := range info.sections() {
+		remaining := budget - b.Len()
+		if len(section) <= remaining {
+			b.WriteString("\n" + section + "\n")
+			continue
+		}
+		if cut := cutLines(section, remaining-len("\n...\n")); cut != "" {
+			b.WriteString("\n" + cut + "\n...\n")
+		}
+		break
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (i Info) sections() []string {
+	var sections []string
+	if i.Context != "" {
+		sections = append(sections, "## Project notes ("+ContextFile+")\n"+i.Context)
+	}
+	if i.GoModule != "" {
+		lines := []string{"## Go module", "- module " + i.GoModule}
+		if i.GoVersion != "" {
+			lines = append(lines, "- go "+i.GoVersion)
+		}
+		if len(i.GoRequires) > 0 {
+			lines = append(lines, "- requires: "+listSome(i.GoRequires))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if i.PackageName != "" || len(i.PackageScripts) > 0 {
+		lines := []string{"## Node package"}
+		if i.PackageName != "" {
+			name := "- name: " + i.PackageName
+			if i.PackageDescription != "" {
+				name += " - " + i.PackageDescription
+			}
+			lines = append(lines, name)
+		}
+		if len(i.PackageScripts) > 0 {
+			lines = append(lines, "- scripts: "+listSome(i.PackageScripts))
+		}
+		if len(i.PackageDeps) > 0 {
+			lines = append(lines, "- dependencies: "+listSome(i.PackageDeps))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if len(i.MakeTargets) > 0 {
+		sections = append(sections, "## Make targets\n"+listSome(i.MakeTargets))
+	}
+	if i.Readme != "" {
+		sections = append(sections, "## README excerpt\n"+i.Readme)
+	}
+	return sections
+}
+
+// listSome joins the first few items and counts the rest.
+func listSome(items []string) string {
+	if len(items) <= maxListed {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s (+%d more)", strings.Join(items[:maxListed], ", "), len(items)-maxListed)
+}
+
+// cutLines keeps the whole lines of text that fit in limit bytes, or ""
+// when not even the heading and one more line fit.
+func cutLines(text string, limit int) string {
+	lines := strings.Split(text, "\n")
+	size := 0
+	kept := 0
+	for _, line := range lines {
+		if size+len(line)+1 > limit {
+			break
+		}
+		size += len(line) + 1
+		kept++
+	}
+	if kept < 2 {
+		return ""
+	}
+	return strings.Join(lines[:kept], "\n")
+}
@@ -0,0 +1,224 @@
+// Package project detects what kind of project a workspace is and renders
+// a compact brief of it for the model.
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// ContextFile is a hand-written brief kept in the repository.
+	ContextFile = ".vyai/context.md"
+
+	maxReadmeBytes = 1500
+	maxListed      = 12
+)
+
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*)\s*:([^=]|$)`)
+
+// Info is the metadata found in a workspace. Files are looked up from the
+// workspace towards the repository root, so the nearest one wins.
+type Info struct {
+	Workspace string
+	Root      string
+
+	GoModule   string
+	GoVersion  string
+	GoRequires []string
+
+	PackageName        string
+	PackageDescription string
+	PackageScripts     []string
+	PackageDeps        []string
+
+	MakeTargets []string
+	Readme      string
+	Context     string
+
+	// Sources lists the files the metadata was read from, relative to Root.
+	Sources []string
+}
+
+// Detect reads the project metadata of workspace. Missing or unreadable
+// files are skipped.
+func Detect(workspace string) Info {
+	workspace = filepath.Clean(workspace)
+	info := Info{Workspace: workspace, Root: repositoryRoot(workspace)}
+
+	if path, data := info.find(ContextFile); data != nil {
+		info.Context = strings.TrimSpace(string(data))
+		info.addSource(path)
+	}
+	if path, data := info.find("go.mod"); data != nil {
+		info.parseGoMod(data)
+		info.addSource(path)
+	}
+	if path, data := info.find("package.json"); data != nil {
+		if info.parsePackageJSON(data) {
+			info.addSource(path)
+		}
+	}
+	for _, name := range []string{"Makefile", "makefile", "GNUmakefile"} {
+		if path, data := info.find(name); data != nil {
+			info.MakeTargets = makeTargets(data)
+			info.addSource(path)
+			break
+		}
+	}
+	for _, name := range []string{"README.md", "README", "readme.md", "README.rst", "README.txt"} {
+		if path, data := info.find(name); data != nil {
+			info.Readme = readmeExcerpt(string(data))
+			info.addSource(path)
+			break
+		}
+	}
+	return info
+}
+
+// Empty reports whether nothing was detected.
+func (i Info) Empty() bool {
+	return len(i.Sources) == 0
+}
+
+// repositoryRoot is the nearest ancestor containing .git, or workspace
+// itself outside a repository.
+func repositoryRoot(workspace string) string {
+	for dir := workspace; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return workspace
+		}
+		dir = parent
+	}
+}
+
+// find reads the nearest name between the workspace and the root.
+func (i *Info) find(name string) (string, []byte) {
+	for dir := i.Workspace; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if data, err := os.ReadFile(path); err == nil {
+			return path, data
+		}
+		if dir == i.Root || filepath.Dir(dir) == dir {
+			return "", nil
+		}
+	}
+}
+
+func (i *Info) addSource(path string) {
+	if rel, err := filepath.Rel(i.Root, path); err == nil {
+		path = filepath.ToSlash(rel)
+	}
+	i.Sources = append(i.Sources, path)
+}
+
+func (i *Info) parseGoMod(data []byte) {
+	inRequire := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "module "):
+			i.GoModule = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		case strings.HasPrefix(line, "go "):
+			i.GoVersion = strings.TrimSpace(strings.TrimPrefix(line, "go "))
+		case line == "require (":
+			inRequire = true
+		case inRequire && line == ")":
+			inRequire = false
+		case strings.HasPrefix(line, "require "):
+			i.addGoRequire(strings.TrimPrefix(line, "require "))
+		case inRequire:
+			i.addGoRequire(line)
+		}
+	}
+}
+
+func (i *Info) addGoRequire(line string) {
+	if line == "" || strings.HasPrefix(line, "//") || strings.Contains(line, "// indirect") {
+		return
+	}
+	if fields := strings.Fields(line); len(fields) > 0 {
+		i.GoRequires = append(i.GoRequires, fields[0])
+	}
+}
+
+func (i *Info) parsePackageJSON(data []byte) bool {
+	var pkg struct {
+		Name            string            `json:"name"`
+		Description     string            `json:"description"`
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return false
+	}
+	i.PackageName = pkg.Name
+	i.PackageDescription = pkg.Description
+	i.PackageScripts = sortedKeys(pkg.Scripts)
+	i.PackageDeps = sortedKeys(pkg.Dependencies)
+	return true
+}
+
+func makeTargets(data []byte) []string {
+	seen := map[string]bool{}
+	var targets []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		match := makeTargetPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		target := match[1]
+		if strings.HasPrefix(target, ".") || strings.Contains(target, "%") || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// readmeExcerpt keeps the opening prose of a README: badges, images and
+// HTML are dropped and the text is cut at a paragraph boundary.
+func readmeExcerpt(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "![") || strings.HasPrefix(trimmed, "[![") || strings.HasPrefix(trimmed, "<") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	excerpt := strings.TrimSpace(strings.Join(lines, "\n"))
+	for strings.Contains(excerpt, "\n\n\n") {
+		excerpt = strings.ReplaceAll(excerpt, "\n\n\n", "\n\n")
+	}
+	if len(excerpt) <= maxReadmeBytes {
+		return excerpt
+	}
+	cut := excerpt[:maxReadmeBytes]
+	if i := strings.LastIndex(cut, "\n\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package project
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func TestDetectReadsProjectMetadata(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	testutil.WriteTree(t, root, map[string]string{
+		".git/HEAD":        "ref: refs/heads/main\n",
+		".vyai/context.md": "Services talk over NATS.\n",
+		"go.mod": `module example.com/shop
+
+go 1.25.0
+
+require (
+	github.com/nats-io/nats.go v1.40.0
+	golang.org/x/sys v0.30.0 // indirect
+)
+`,
+		"Makefile":         ".PHONY: build test\nVERSION := 1\nbuild: deps\n\tgo build ./...\ntest:\n\tgo test ./...\n%.o: %.c\n",
+		"README.md":        "# Shop\n[![ci](badge.svg)](ci)\n\nAn online shop.\n",
+		"web/package.json": `{"name": "shop-web", "description": "Storefront", "scripts": {"test": "vitest", "build": "vite build"}, "dependencies": {"react": "^19"}}`,
+	})
+
+	info := Detect(filepath.Join(root, "web"))
+	if info.Root != root {
+		t.Fatalf("expected repository root %s, got %s", root, info.Root)
+	}
+	if info.GoModule != "example.com/shop" || info.GoVersion != "1.25.0" || strings.Join(info.GoRequires, ",") != "github.com/nats-io/nats.go" {
+		t.Fatalf("unexpected go module info: %+v", info)
+	}
+	if info.PackageName != "shop-web" || strings.Join(info.PackageScripts, ",") != "build,test" {
+		t.Fatalf("unexpected package info: %+v", info)
+	}
+	if strings.Join(info.MakeTargets, ",") != "build,test" {
+		t.Fatalf("unexpected make targets: %v", info.MakeTargets)
+	}
+	if info.Readme != "# Shop\n\nAn online shop." {
+		t.Fatalf("unexpected readme excerpt: %q", info.Readme)
+	}
+	if strings.Join(info.Sources, ",") != ".vyai/context.md,go.mod,web/package.json,Makefile,README.md" {
+		t.Fatalf("unexpected sources: %v", info.Sources)
+	}
+
+	brief := Brief(info, DefaultMaxTokens)
+	for _, want := range []string{"Services talk over NATS.", "- module example.com/shop", "- scripts: build, test", "## Make targets\nbuild, test", "An online shop."} {
+		if !strings.Contains(brief, want) {
+			t.Fatalf("expected %q in brief:\n%s", want, brief)
+		}
+	}
+}
+
+func TestBriefRespectsTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	info := Info{
+		Workspace: "/src/app",
+		Root:      "/src/app",
+		Context:   strings.Repeat("Remember the deployment checklist.\n", 40),
+		Readme:    "A long readme.",
+		Sources:   []string{ContextFile, "README.md"},
+	}
+
+	brief := Brief(info, 100)
+	if EstimateTokens(brief) > 100 {
+		t.Fatalf("brief of %d tokens exceeds the budget:\n%s", EstimateTokens(brief), brief)
+	}
+	if !strings.Contains(brief, "Remember the deployment checklist.") || !strings.HasSuffix(brief, "...") {
+		t.Fatalf("expected truncated notes, got:\n%s", brief)
+	}
+	if strings.Contains(brief, "A long readme.") {
+		t.Fatalf("expected lower priority sections to be dropped, got:\n%s", brief)
+	}
+
+	if Brief(Info{Workspace: "/empty", Root: "/empty"}, 100) != "" {
+		t.Fatal("expected no brief for an empty project")
+	}
+}
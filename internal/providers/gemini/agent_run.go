@@ -0,0 +1,107 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageKindAgent marks the request/result message pair that records an
+// agent run in the chat history.
+const MessageKindAgent = "agent"
+
+const (
+	condensedOutputLines = 3
+	condensedOutputBytes = 400
+)
+
+// AgentRun is the full record of one /agent request: what was asked, the
+// program it was translated into, each tool result and the summary.
+type AgentRun struct {
+	ID        string            `json:"id"`
+	Request   string            `json:"request"`
+	Program   string            `json:"program,omitempty"`
+	Results   []AgentToolResult `json:"results,omitempty"`
+	Summary   string            `json:"summary,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type AgentToolResult struct {
+	Tool   string `json:"tool"`
+	Detail string `json:"detail,omitempty"`
+	Output string `json:"output,omitempty"`
+	Failed bool   `json:"failed,omitempty"`
+}
+
+// CondenseAgentRun renders a run compactly enough to keep in the chat
+// context, so later questions can refer to what the agent did without
+// replaying every tool output.
+func CondenseAgentRun(run AgentRun) string {
+	var b strings.Builder
+	b.WriteString("Agent run (tools executed locally in the workspace):\n")
+	for _, result := range run.Results {
+		status := "ok"
+		if result.Failed {
+			status = "failed"
+		}
+		fmt.Fprintf(&b, "- %s", result.Tool)
+		if result.Detail != "" {
+			fmt.Fprintf(&b, " %s", result.Detail)
+		}
+		fmt.Fprintf(&b, ": %s\n", status)
+		if excerpt := condenseOutput(result.Output); excerpt != "" {
+			for _, line := range strings.Split(excerpt, "\n") {
+				b.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	if run.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", run.Error)
+	}
+	if run.Summary != "" {
+		fmt.Fprintf(&b, "Summary: %s\n", run.Summary)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// condenseOutput keeps the first few lines of a tool output within a byte
+// budget.
+func condenseOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ""
+	}
+
+	lines := strings.Split(output, "\n")
+	truncated := false
+	if len(lines) > condensedOutputLines {
+		lines = lines[:condensedOutputLines]
+		truncated = true
+	}
+	excerpt := strings.Join(lines, "\n")
+	if len(excerpt) > condensedOutputBytes {
+		cut := condensedOutputBytes
+		for cut > 0 && !utf8.RuneStart(excerpt[cut]) {
+			cut--
+		}
+		excerpt = excerpt[:cut]
+		truncated = true
+	}
+	if truncated {
+		excerpt += "\n..."
+	}
+	return excerpt
+}
+
+func agentRunMessages(run AgentRun) []Message {
+	return []Message{
+		{Role: "user", Text: "/agent " + run.Request, Kind: MessageKindAgent, AgentRunID: run.ID},
+		{Role: "model", Text: CondenseAgentRun(run), Kind: MessageKindAgent, AgentRunID: run.ID},
+	}
+}
+
+func generateAgentRunID() string {
+	return strings.Replace(GenerateRandomConversationID(), "CONVERSATION-", "AGENT-", 1)
+}
@@ -0,0 +1,133 @@
+package gemini
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/vybraan/vyai/internal/appconfig"
+)
+
+func TestRecordAgentRunPersistsRunAndCondensedHistory(t *testing.T) {
+	t.Parallel()
+
+	cfg := &appconfig.Config{DataDir: t.TempDir(), ChatModel: "gemini-test"}
+	gs := NewGeminiService(NewConversationManager(), cfg)
+	conv, err := gs.NewConversation(context.Background())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	conv.SetDescription("Existing thread")
+
+	run, err := gs.RecordAgentRun(context.Background(), conv.ID, AgentRun{
+		Request: "run the tests",
+		Program: "<run-bash>go test ./...</run-bash><summary>all green</summary>",
+		Results: []AgentToolResult{{Tool: "run-bash", Detail: "go test ./...", Output: "ok  \texample/pkg"}},
+		Summary: "all green",
+	})
+	if err != nil {
+		t.Fatalf("RecordAgentRun returned error: %v", err)
+	}
+
+	records, err := gs.store.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll returned error: %v", err)
+	}
+	if len(records) != 1 || len(records[0].AgentRuns) != 1 {
+		t.Fatalf("expected one stored agent run, got %+v", records)
+	}
+	if records[0].AgentRuns[0].ID != run.ID || records[0].AgentRuns[0].Program == "" {
+		t.Fatalf("unexpected stored run: %+v", records[0].AgentRuns[0])
+	}
+
+	messages := records[0].Messages
+	if len(messages) != 2 || messages[0].Kind != MessageKindAgent || messages[1].AgentRunID != run.ID {
+		t.Fatalf("unexpected agent messages: %+v", messages)
+	}
+	if !strings.Contains(messages[1].Text, "run-bash go test ./...: ok") || !strings.Contains(messages[1].Text, "Summary: all green") {
+		t.Fatalf("unexpected condensed run: %q", messages[1].Text)
+	}
+}
+
+func TestRecordAgentRunSavesIntoTheRunsConversation(t *testing.T) {
+	t.Parallel()
+
+	cfg := &appconfig.Config{DataDir: t.TempDir(), ChatModel: "gemini-test"}
+	gs := NewGeminiService(NewConversationManager(), cfg)
+	started, err := gs.NewConversation(context.Background())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	// The user starts another conversation while the agent is running.
+	other, err := gs.NewConversation(context.Background())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	if active, _ := gs.GetActiveConversation(); active != other {
+		t.Fatal("expected the new conversation to be active")
+	}
+
+	run, err := gs.RecordAgentRun(context.Background(), started.ID, AgentRun{Request: "list files", Summary: "done"})
+	if err != nil {
+		t.Fatalf("RecordAgentRun returned error: %v", err)
+	}
+	if _, ok := started.AgentRun(run.ID); !ok {
+		t.Fatal("expected the run in the conversation it started in")
+	}
+	if _, ok := other.AgentRun(run.ID); ok {
+		t.Fatal("expected the active conversation to be left alone")
+	}
+	if messages, err := other.Repo.GetMessages(); err == nil && len(messages) > 0 {
+		t.Fatalf("expected no messages in the active conversation, got %+v", messages)
+	}
+
+	if _, err := gs.RecordAgentRun(context.Background(), "CONVERSATION-GONE", AgentRun{Request: "ls"}); err == nil {
+		t.Fatal("expected an unknown conversation to be an error")
+	}
+}
+
+func TestAppendMessagesKeepsKindsAcrossHistoryRebuild(t *testing.T) {
+	t.Parallel()
+
+	repo := NewPersistentHistoryRepository([]Message{
+		{Role: "user", Text: "hello"},
+		{Role: "model", Text: "world"},
+	}, nil, nil)
+	repo.chatSession = &genai.ChatSession{History: historyFromMessages(repo.cachedMessages)}
+	repo.messageLimit = 3
+
+	repo.AppendMessages(
+		Message{Role: "user", Text: "/agent ls", Kind: MessageKindAgent, AgentRunID: "AGENT-1"},
+		Message{Role: "model", Text: "Agent run", Kind: MessageKindAgent, AgentRunID: "AGENT-1"},
+	)
+
+	messages, err := repo.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages returned error: %v", err)
+	}
+	if len(messages) != 3 || messages[0].Text != "world" {
+		t.Fatalf("expected pruned history, got %+v", messages)
+	}
+	if messages[0].Kind != "" || messages[1].AgentRunID != "AGENT-1" || messages[2].Kind != MessageKindAgent {
+		t.Fatalf("expected agent metadata to survive, got %+v", messages)
+	}
+	if len(repo.chatSession.History) != 3 {
+		t.Fatalf("expected agent turns in the model context, got %d entries", len(repo.chatSession.History))
+	}
+}
+
+func TestCondenseAgentRunTruncatesOutput(t *testing.T) {
+	t.Parallel()
+
+	got := CondenseAgentRun(AgentRun{
+		Results: []AgentToolResult{{Tool: "read-file", Detail: "big.go", Output: "1\n2\n3\n4\n5"}},
+		Error:   "agent run cancelled",
+	})
+	if !strings.Contains(got, "    3\n    ...") || strings.Contains(got, "4") {
+		t.Fatalf("expected output excerpt, got %q", got)
+	}
+	if !strings.Contains(got, "Error: agent run cancelled") {
+		t.Fatalf("expected error line, got %q", got)
+	}
+}
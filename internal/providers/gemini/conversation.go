@@ -16,8 +16,10 @@ type Conversation struct {
 	Repo              HistoryRepository
 	descriptionLocked bool
 	ChatModel         string
+	Profile           string
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+	agentRuns         []AgentRun
 
 	mu sync.RWMutex
 }
@@ -63,8 +65,10 @@ func NewConversationFromRecord(repo HistoryRepository, record ConversationRecord
 		description:       description,
 		descriptionLocked: record.DescriptionLocked,
 		ChatModel:         record.ChatModel,
+		Profile:           record.Profile,
 		CreatedAt:         createdAt,
 		UpdatedAt:         updatedAt,
+		agentRuns:         append([]AgentRun(nil), record.AgentRuns...),
 	}
 }
 
@@ -93,6 +97,45 @@ func (c *Conversation) Touch() {
 	c.UpdatedAt = time.Now().UTC()
 }
 
+func (c *Conversation) addAgentRun(run AgentRun) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.agentRuns = append(c.agentRuns, run)
+}
+
+// AgentRun returns the stored run referenced by an agent message.
+func (c *Conversation) AgentRun(id string) (AgentRun, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, run := range c.agentRuns {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return AgentRun{}, false
+}
+
+// agentRunsFor drops runs whose messages were pruned from the history.
+func (c *Conversation) agentRunsFor(messages []Message) []AgentRun {
+	referenced := map[string]bool{}
+	for _, message := range messages {
+		if message.AgentRunID != "" {
+			referenced[message.AgentRunID] = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := c.agentRuns[:0]
+	for _, run := range c.agentRuns {
+		if referenced[run.ID] {
+			kept = append(kept, run)
+		}
+	}
+	c.agentRuns = kept
+	return append([]AgentRun(nil), kept...)
+}
+
 func (c *Conversation) IsDescriptionLocked() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -54,6 +54,17 @@ func (cm *ConversationManager) SwitchConversation(id string) error {
 	return nil
 }
 
+func (cm *ConversationManager) get(id string) (*Conversation, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	conversation, exists := cm.conversations[id]
+	if !exists {
+		return nil, fmt.Errorf("conversation with ID %s does not exist", id)
+	}
+	return conversation, nil
+}
+
 func (cm *ConversationManager) GetActiveConversation() (*Conversation, error) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
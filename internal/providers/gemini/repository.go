@@ -14,6 +14,10 @@ import (
 type Message struct {
 	Role string
 	Text string
+	// Kind is empty for chat turns and MessageKindAgent for the pair that
+	// records an agent run, whose full details are kept under AgentRunID.
+	Kind       string `json:",omitempty"`
+	AgentRunID string `json:",omitempty"`
 }
 
 var (
@@ -25,6 +29,10 @@ type HistoryRepository interface {
 	SendMessage(c context.Context, text genai.Text) (string, error)
 	SendMessageStream(c context.Context, text genai.Text, onToken func(string)) (string, error)
 	GetMessages() ([]Message, error)
+	AppendMessages(messages ...Message)
+	AmendLastAnswer(suffix string)
+	ReplaceLastPrompt(sent, stored string)
+	SetTools(tools []ChatTool)
 	ResetSession()
 }
 
@@ -36,6 +44,7 @@ type MemoryHistoryRepository struct {
 	messageLimit     int
 	sessionFactory   func(context.Context) (interface{ Close() error }, *genai.ChatSession, error)
 	onChange         func([]Message)
+	tools            []ChatTool
 	mu               sync.RWMutex
 }
 
@@ -83,17 +92,7 @@ func (mhr *MemoryHistoryRepository) GetMessages() ([]Message, error) {
 		return nil, ErrNoMessagesInHistory
 	}
 
-	var messages []Message
-	for _, content := range mhr.chatSession.History {
-		for _, part := range content.Parts {
-			if text, ok := part.(genai.Text); ok {
-				messages = append(messages, Message{
-					Role: content.Role,
-					Text: string(text),
-				})
-			}
-		}
-	}
+	messages := messagesFromHistory(mhr.chatSession.History)
 	mhr.cachedMessages = messages
 	mhr.needsCacheUpdate = false
 	return append([]Message(nil), messages...), nil
@@ -104,18 +103,98 @@ func (mhr *MemoryHistoryRepository) SendMessage(c context.Context, text genai.Te
 		return "", err
 	}
 
-	result, err := mhr.chatSession.SendMessage(c, text)
+	var response strings.Builder
+	parts := []genai.Part{text}
+	for round := 0; ; round++ {
+		result, err := mhr.chatSession.SendMessage(c, parts...)
+		if err != nil {
+			mhr.commitHistory()
+			return "", fmt.Errorf("send message: %w", err)
+		}
+		if result == nil {
+			mhr.commitHistory()
+			return "", fmt.Errorf("send message: nil response")
+		}
+		for _, cand := range result.Candidates {
+			if cand.Content != nil {
+				for _, part := range cand.Content.Parts {
+					if _, ok := part.(genai.FunctionCall); ok {
+						continue
+					}
+					fmt.Fprintln(&response, part)
+				}
+			}
+		}
 
-	if err != nil {
-		return "", fmt.Errorf("send message: %w", err)
+		calls := functionCalls(result)
+		if len(calls) == 0 || round > maxToolRounds {
+			break
+		}
+		parts = callTools(c, mhr.toolsSnapshot(), calls, round == maxToolRounds)
 	}
 
-	mhr.mu.Lock()
-	// Prune older messages if the limit is exceeded
-	if len(mhr.chatSession.History) > mhr.messageLimit {
-		mhr.chatSession.History = mhr.chatSession.History[len(mhr.chatSession.History)-mhr.messageLimit:]
+	mhr.commitHistory()
+	return response.String(), nil
+}
+
+func (mhr *MemoryHistoryRepository) SendMessageStream(c context.Context, text genai.Text, onToken func(string)) (string, error) {
+	if err := mhr.ensureSession(c); err != nil {
+		return "", err
 	}
-	mhr.cachedMessages = messagesFromHistory(mhr.chatSession.History)
+
+	var fullResponse strings.Builder
+	parts := []genai.Part{text}
+	for round := 0; ; round++ {
+		iter := mhr.chatSession.SendMessageStream(c, parts...)
+		if iter == nil {
+			mhr.commitHistory()
+			return "", fmt.Errorf("send message: stream returned nil iterator")
+		}
+		if round > 0 && fullResponse.Len() > 0 {
+			fullResponse.WriteString("\n\n")
+		}
+
+		for {
+			resp, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				mhr.commitHistory()
+				return "", fmt.Errorf("send message: %w", err)
+			}
+			if resp == nil {
+				continue
+			}
+			for _, cand := range resp.Candidates {
+				if cand.Content != nil {
+					for _, part := range cand.Content.Parts {
+						if t, ok := part.(genai.Text); ok {
+							fullResponse.WriteString(string(t))
+						}
+					}
+				}
+			}
+			onToken(fullResponse.String())
+		}
+
+		calls := functionCalls(iter.MergedResponse())
+		if len(calls) == 0 || round > maxToolRounds {
+			break
+		}
+		parts = callTools(c, mhr.toolsSnapshot(), calls, round == maxToolRounds)
+	}
+
+	mhr.commitHistory()
+	return fullResponse.String(), nil
+}
+
+// commitHistory prunes the session history after an exchange and publishes
+// the resulting messages.
+func (mhr *MemoryHistoryRepository) commitHistory() {
+	mhr.mu.Lock()
+	mhr.chatSession.History = pruneHistory(mhr.chatSession.History, mhr.messageLimit)
+	mhr.cachedMessages = mergeMessageMetadata(mhr.cachedMessages, messagesFromHistory(mhr.chatSession.History))
 	mhr.needsCacheUpdate = false
 	snapshot := append([]Message(nil), mhr.cachedMessages...)
 	onChange := mhr.onChange
@@ -124,64 +203,90 @@ func (mhr *MemoryHistoryRepository) SendMessage(c context.Context, text genai.Te
 	if onChange != nil {
 		onChange(snapshot)
 	}
+}
 
-	var response strings.Builder
+// SetTools replaces the functions the model may call. The session is reset
+// so the next message declares them.
+func (mhr *MemoryHistoryRepository) SetTools(tools []ChatTool) {
+	mhr.mu.Lock()
+	mhr.tools = append([]ChatTool(nil), tools...)
+	mhr.mu.Unlock()
+	mhr.ResetSession()
+}
 
-	if result == nil {
-		return "", fmt.Errorf("send message: nil response")
-	}
-	for _, cand := range result.Candidates {
-		if cand.Content != nil {
-			for _, part := range cand.Content.Parts {
-				fmt.Fprintln(&response, part)
-			}
+func (mhr *MemoryHistoryRepository) toolsSnapshot() []ChatTool {
+	mhr.mu.RLock()
+	defer mhr.mu.RUnlock()
+	return mhr.tools
+}
+
+// AppendMessages adds turns that did not come from the model, such as an
+// agent run, so they are persisted and sent as context with the next
+// message.
+func (mhr *MemoryHistoryRepository) AppendMessages(messages ...Message) {
+	mhr.mu.Lock()
+	if mhr.chatSession != nil {
+		mhr.chatSession.History = pruneHistory(append(mhr.chatSession.History, historyFromMessages(messages)...), mhr.messageLimit)
+		mhr.cachedMessages = mergeMessageMetadata(append(mhr.cachedMessages, messages...), messagesFromHistory(mhr.chatSession.History))
+	} else {
+		mhr.cachedMessages = append(mhr.cachedMessages, messages...)
+		if len(mhr.cachedMessages) > mhr.messageLimit {
+			mhr.cachedMessages = mhr.cachedMessages[len(mhr.cachedMessages)-mhr.messageLimit:]
 		}
 	}
-	finalResponse := response.String()
-	return finalResponse, nil
+	mhr.needsCacheUpdate = false
+	snapshot := append([]Message(nil), mhr.cachedMessages...)
+	onChange := mhr.onChange
+	mhr.mu.Unlock()
 
+	if onChange != nil {
+		onChange(snapshot)
+	}
 }
 
-func (mhr *MemoryHistoryRepository) SendMessageStream(c context.Context, text genai.Text, onToken func(string)) (string, error) {
-	if err := mhr.ensureSession(c); err != nil {
-		return "", err
+// AmendLastAnswer adds suffix to the model's last answer, such as the
+// sources a retrieval answer cites, so it is persisted with the answer.
+// Nothing changes when the last message is not from the model.
+func (mhr *MemoryHistoryRepository) AmendLastAnswer(suffix string) {
+	mhr.mu.Lock()
+	n := len(mhr.cachedMessages)
+	if n == 0 || mhr.cachedMessages[n-1].Role != "model" {
+		mhr.mu.Unlock()
+		return
+	}
+	mhr.cachedMessages[n-1].Text += suffix
+	if mhr.chatSession != nil {
+		amendLastText(mhr.chatSession.History, suffix)
 	}
+	snapshot := append([]Message(nil), mhr.cachedMessages...)
+	onChange := mhr.onChange
+	mhr.mu.Unlock()
 
-	iter := mhr.chatSession.SendMessageStream(c, text)
-	if iter == nil {
-		return "", fmt.Errorf("send message: stream returned nil iterator")
+	if onChange != nil {
+		onChange(snapshot)
 	}
-	var fullResponse strings.Builder
+}
 
-	for {
-		resp, err := iter.Next()
-		if err == iterator.Done {
+// ReplaceLastPrompt keeps stored in place of the last prompt that was sent
+// as sent, such as a question without the excerpts that were attached to it
+// for one request, and saves the change.
+func (mhr *MemoryHistoryRepository) ReplaceLastPrompt(sent, stored string) {
+	mhr.mu.Lock()
+	replaced := false
+	for i := len(mhr.cachedMessages) - 1; i >= 0; i-- {
+		if message := &mhr.cachedMessages[i]; message.Role == "user" && message.Text == sent {
+			message.Text = stored
+			replaced = true
 			break
 		}
-		if err != nil {
-			return "", fmt.Errorf("send message: %w", err)
-		}
-		if resp == nil {
-			continue
-		}
-		for _, cand := range resp.Candidates {
-			if cand.Content != nil {
-				for _, part := range cand.Content.Parts {
-					if t, ok := part.(genai.Text); ok {
-						fullResponse.WriteString(string(t))
-					}
-				}
-			}
-		}
-		onToken(fullResponse.String())
 	}
-
-	mhr.mu.Lock()
-	if len(mhr.chatSession.History) > mhr.messageLimit {
-		mhr.chatSession.History = mhr.chatSession.History[len(mhr.chatSession.History)-mhr.messageLimit:]
+	if mhr.chatSession != nil {
+		replaced = replaceLastPrompt(mhr.chatSession.History, sent, stored) || replaced
+	}
+	if !replaced {
+		mhr.mu.Unlock()
+		return
 	}
-	mhr.cachedMessages = messagesFromHistory(mhr.chatSession.History)
-	mhr.needsCacheUpdate = false
 	snapshot := append([]Message(nil), mhr.cachedMessages...)
 	onChange := mhr.onChange
 	mhr.mu.Unlock()
@@ -189,8 +294,39 @@ func (mhr *MemoryHistoryRepository) SendMessageStream(c context.Context, text ge
 	if onChange != nil {
 		onChange(snapshot)
 	}
+}
 
-	return fullResponse.String(), nil
+func replaceLastPrompt(history []*genai.Content, sent, stored string) bool {
+	for i := len(history) - 1; i >= 0; i-- {
+		content := history[i]
+		if content.Role != "user" || hasToolParts(content) {
+			continue
+		}
+		for j, part := range content.Parts {
+			if text, ok := part.(genai.Text); ok && string(text) == sent {
+				content.Parts[j] = genai.Text(stored)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// amendLastText adds suffix to the last text part of the last model turn
+// that messagesFromHistory keeps.
+func amendLastText(history []*genai.Content, suffix string) {
+	for i := len(history) - 1; i >= 0; i-- {
+		content := history[i]
+		if content.Role != "model" || hasToolParts(content) {
+			continue
+		}
+		for j := len(content.Parts) - 1; j >= 0; j-- {
+			if text, ok := content.Parts[j].(genai.Text); ok {
+				content.Parts[j] = text + genai.Text(suffix)
+				return
+			}
+		}
+	}
 }
 
 func (mhr *MemoryHistoryRepository) ensureSession(c context.Context) error {
@@ -217,9 +353,15 @@ func (mhr *MemoryHistoryRepository) ensureSession(c context.Context) error {
 	return nil
 }
 
+// messagesFromHistory keeps the text of the history. Turns that are part of
+// a tool exchange are left out: the final answer carries what the tools
+// returned, and the saved history stays a plain user/model alternation.
 func messagesFromHistory(history []*genai.Content) []Message {
 	var messages []Message
 	for _, content := range history {
+		if hasToolParts(content) {
+			continue
+		}
 		for _, part := range content.Parts {
 			if text, ok := part.(genai.Text); ok {
 				messages = append(messages, Message{
@@ -232,6 +374,35 @@ func messagesFromHistory(history []*genai.Content) []Message {
 	return messages
 }
 
+// mergeMessageMetadata carries Kind and AgentRunID over to messages rebuilt
+// from the genai history, which only keeps roles and text. The history
+// only grows at the end and is pruned at the front, so the rebuilt list
+// starts with some suffix of the previous one.
+func mergeMessageMetadata(previous, rebuilt []Message) []Message {
+	for offset := 0; offset < len(previous); offset++ {
+		overlap := previous[offset:]
+		if len(overlap) > len(rebuilt) {
+			continue
+		}
+		matched := true
+		for i, message := range overlap {
+			if rebuilt[i].Role != message.Role || rebuilt[i].Text != message.Text {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for i, message := range overlap {
+			rebuilt[i].Kind = message.Kind
+			rebuilt[i].AgentRunID = message.AgentRunID
+		}
+		break
+	}
+	return rebuilt
+}
+
 func historyFromMessages(messages []Message) []*genai.Content {
 	var history []*genai.Content
 	for _, message := range messages {
@@ -77,3 +77,71 @@ func TestMemoryHistoryRepositoryResetSessionPreservesMessages(t *testing.T) {
 		t.Fatalf("expected 2 cached messages, got %d", len(messages))
 	}
 }
+
+func TestMemoryHistoryRepositoryAmendLastAnswerPersists(t *testing.T) {
+	t.Parallel()
+
+	var saved []Message
+	repo := NewPersistentHistoryRepository([]Message{
+		{Role: "user", Text: "where is main?"},
+		{Role: "model", Text: "In main.go."},
+	}, nil, func(messages []Message) {
+		saved = messages
+	})
+
+	repo.AmendLastAnswer("\n\nSources: main.go:1")
+	if len(saved) != 2 || saved[1].Text != "In main.go.\n\nSources: main.go:1" {
+		t.Fatalf("expected the amended answer to be saved, got %#v", saved)
+	}
+
+	repo.AppendMessages(Message{Role: "user", Text: "thanks"})
+	saved = nil
+	repo.AmendLastAnswer("ignored")
+	if saved != nil {
+		t.Fatalf("expected no change after a user message, got %#v", saved)
+	}
+
+	live := NewMemoryHistoryRepository(&genai.ChatSession{History: []*genai.Content{
+		{Role: "user", Parts: []genai.Part{genai.Text("q")}},
+		{Role: "model", Parts: []genai.Part{genai.Text("a")}},
+	}})
+	if _, err := live.GetMessages(); err != nil {
+		t.Fatalf("GetMessages returned error: %v", err)
+	}
+	live.AmendLastAnswer(" [1]")
+	messages, err := live.GetMessages()
+	if err != nil {
+		t.Fatalf("GetMessages returned error: %v", err)
+	}
+	if messages[1].Text != "a [1]" {
+		t.Fatalf("expected the session history to be amended, got %#v", messages)
+	}
+}
+
+func TestMemoryHistoryRepositoryReplaceLastPromptPersists(t *testing.T) {
+	t.Parallel()
+
+	var saved []Message
+	repo := NewPersistentHistoryRepository(nil, nil, func(messages []Message) {
+		saved = messages
+	})
+	repo.chatSession = &genai.ChatSession{History: []*genai.Content{
+		{Role: "user", Parts: []genai.Part{genai.Text("where is main?\n\n[1] main.go:1")}},
+		{Role: "model", Parts: []genai.Part{genai.Text("In main.go.")}},
+	}}
+	repo.cachedMessages = messagesFromHistory(repo.chatSession.History)
+
+	repo.ReplaceLastPrompt("where is main?\n\n[1] main.go:1", "where is main?")
+	if len(saved) != 2 || saved[0].Text != "where is main?" || saved[1].Text != "In main.go." {
+		t.Fatalf("expected the stored prompt to be saved, got %#v", saved)
+	}
+	if text := repo.chatSession.History[0].Parts[0]; text != genai.Text("where is main?") {
+		t.Fatalf("expected the session history to keep the stored prompt, got %q", text)
+	}
+
+	saved = nil
+	repo.ReplaceLastPrompt("never sent", "x")
+	if saved != nil {
+		t.Fatalf("expected no change for an unknown prompt, got %#v", saved)
+	}
+}
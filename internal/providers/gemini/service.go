@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/vybraan/vyai/internal/appconfig"
@@ -26,6 +28,7 @@ type ConversationSummary struct {
 	ID          string
 	Description string
 	ChatModel   string
+	Profile     string
 	UpdatedAt   string
 }
 
@@ -35,6 +38,10 @@ type GeminiService struct {
 	store              *FileConversationStore
 	descriptionUpdates chan DescriptionUpdate
 	notices            chan Notice
+
+	sessionMu    sync.RWMutex
+	chatTools    []ChatTool
+	projectBrief string
 }
 
 func NewGeminiService(cm *ConversationManager, cfg *appconfig.Config) *GeminiService {
@@ -111,25 +118,34 @@ func (gs *GeminiService) NewConversation(c context.Context) (*Conversation, erro
 		gs.cm.active.Close()
 	}
 
-	conversation := gs.cm.StartNewConversationWithModel(nil, gs.cfg.ChatModel)
+	profile := gs.cfg.DefaultProfile
+	conversation := gs.cm.StartNewConversationWithModel(nil, gs.profileModel(profile))
+	conversation.Profile = profile
 	memRepo := NewPersistentHistoryRepository(nil, func(ctx context.Context) (interface{ Close() error }, *genai.ChatSession, error) {
-		return NewChatSession(ctx, gs.cfg.ChatModel, gs.cfg)
+		return NewChatSession(ctx, conversation.ChatModel, gs.sessionOptionsFor(conversation.Profile))
 	}, func(_ []Message) {
 		conversation.Touch()
 		gs.persistConversation(conversation)
 	})
+	memRepo.tools = gs.ChatTools()
 	conversation.Repo = memRepo
 	return conversation, nil
 }
 
+// EnsureConversation returns the active conversation, starting a new one
+// when there is none yet.
+func (gs *GeminiService) EnsureConversation(c context.Context) (*Conversation, error) {
+	if conversation, err := gs.cm.GetActiveConversation(); err == nil {
+		return conversation, nil
+	}
+	return gs.NewConversation(c)
+}
+
 func (gs *GeminiService) SendMessage(c context.Context, message string) (string, error) {
 
-	conversation, err := gs.cm.GetActiveConversation()
+	conversation, err := gs.EnsureConversation(c)
 	if err != nil {
-		conversation, err = gs.NewConversation(c)
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 
 	result, err := conversation.Repo.SendMessage(c, genai.Text(message))
@@ -147,18 +163,26 @@ func (gs *GeminiService) SendMessage(c context.Context, message string) (string,
 }
 
 func (gs *GeminiService) SendMessageStream(c context.Context, message string, onToken func(string)) (string, error) {
-	conversation, err := gs.cm.GetActiveConversation()
+	return gs.SendMessageStreamAs(c, message, message, onToken)
+}
+
+// SendMessageStreamAs sends message to the active conversation but keeps
+// stored in its history, e.g. a question without the excerpts attached to
+// it for this request only, so they do not weigh on later exchanges or the
+// title.
+func (gs *GeminiService) SendMessageStreamAs(c context.Context, message, stored string, onToken func(string)) (string, error) {
+	conversation, err := gs.EnsureConversation(c)
 	if err != nil {
-		conversation, err = gs.NewConversation(c)
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 
 	result, err := conversation.Repo.SendMessageStream(c, genai.Text(message), onToken)
 	if err != nil {
 		return "", err
 	}
+	if stored != message {
+		conversation.Repo.ReplaceLastPrompt(message, stored)
+	}
 	conversation.Touch()
 
 	if conversation.GetDescription() == "New Conversation..." {
@@ -168,6 +192,231 @@ func (gs *GeminiService) SendMessageStream(c context.Context, message string, on
 	return result, nil
 }
 
+// AskStream sends message in a one-off chat session with the configured
+// model and system prompt. Nothing is stored and the active conversation is
+// left alone.
+func (gs *GeminiService) AskStream(c context.Context, message string, onToken func(string)) (string, error) {
+	return gs.Complete(c, CompletionRequest{Prompt: message}, onToken)
+}
+
+// CompletionRequest is a stateless exchange: History precedes Prompt and
+// System is added after the configured system prompt. Profile names the
+// profile to answer as; an empty Model uses its chat model, or the
+// configured one, and Generation overrides its parameters.
+type CompletionRequest struct {
+	Model      string
+	Profile    string
+	System     string
+	History    []Message
+	Prompt     string
+	Generation GenerationParams
+}
+
+// Complete answers req in a one-off chat session. Nothing is stored.
+func (gs *GeminiService) Complete(c context.Context, req CompletionRequest, onToken func(string)) (string, error) {
+	if err := gs.checkProfile(req.Profile); err != nil {
+		return "", err
+	}
+	modelID := req.Model
+	if modelID == "" {
+		modelID = gs.profileModel(req.Profile)
+	}
+	opts := gs.sessionOptionsFor(req.Profile)
+	if system := strings.TrimSpace(req.System); system != "" {
+		opts.SystemPrompt = strings.TrimSpace(opts.SystemPrompt + "\n\n" + system)
+	}
+	if req.Generation.Temperature != nil {
+		opts.Generation.Temperature = req.Generation.Temperature
+	}
+	if req.Generation.TopP != nil {
+		opts.Generation.TopP = req.Generation.TopP
+	}
+	if req.Generation.MaxOutputTokens != nil {
+		opts.Generation.MaxOutputTokens = req.Generation.MaxOutputTokens
+	}
+	opts.Generation.StopSequences = req.Generation.StopSequences
+
+	repo := NewPersistentHistoryRepository(req.History, func(ctx context.Context) (interface{ Close() error }, *genai.ChatSession, error) {
+		return NewChatSession(ctx, modelID, opts)
+	}, nil)
+	repo.tools = opts.Tools
+	defer repo.ResetSession()
+
+	return repo.SendMessageStream(c, genai.Text(req.Prompt), onToken)
+}
+
+// AmendLastAnswer adds suffix to the last answer of the active
+// conversation and saves it.
+func (gs *GeminiService) AmendLastAnswer(suffix string) error {
+	conversation, err := gs.cm.GetActiveConversation()
+	if err != nil {
+		return err
+	}
+	conversation.Repo.AmendLastAnswer(suffix)
+	return nil
+}
+
+// RecordAgentRun stores a finished agent run in the conversation it ran in,
+// which need not be the active one any more, and appends its condensed form
+// to the history so the chat model knows what the agent did.
+func (gs *GeminiService) RecordAgentRun(c context.Context, conversationID string, run AgentRun) (AgentRun, error) {
+	conversation, err := gs.cm.get(conversationID)
+	if err != nil {
+		return AgentRun{}, err
+	}
+
+	if run.ID == "" {
+		run.ID = generateAgentRunID()
+	}
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now().UTC()
+	}
+
+	conversation.addAgentRun(run)
+	conversation.Repo.AppendMessages(agentRunMessages(run)...)
+	conversation.Touch()
+
+	// Titles are generated for the active conversation only.
+	if active, err := gs.cm.GetActiveConversation(); err == nil && active == conversation && conversation.GetDescription() == "New Conversation..." {
+		gs.SetConversationDescription(c, false)
+	}
+
+	return run, nil
+}
+
+// SetChatTools makes tools, such as those of MCP servers, callable by the
+// chat model in every conversation.
+func (gs *GeminiService) SetChatTools(tools []ChatTool) {
+	gs.sessionMu.Lock()
+	gs.chatTools = append([]ChatTool(nil), tools...)
+	gs.sessionMu.Unlock()
+
+	for _, conv := range gs.cm.All() {
+		conv.Repo.SetTools(tools)
+	}
+}
+
+func (gs *GeminiService) ChatTools() []ChatTool {
+	gs.sessionMu.RLock()
+	defer gs.sessionMu.RUnlock()
+	return gs.chatTools
+}
+
+// SetProjectBrief sets the description of the workspace project that is
+// added to the system instruction while project context is enabled.
+func (gs *GeminiService) SetProjectBrief(brief string) {
+	gs.sessionMu.Lock()
+	gs.projectBrief = strings.TrimSpace(brief)
+	gs.sessionMu.Unlock()
+}
+
+// ProjectBrief returns the project brief, or "" when project context is
+// disabled.
+func (gs *GeminiService) ProjectBrief() string {
+	if !gs.cfg.ProjectContext.Enabled {
+		return ""
+	}
+	gs.sessionMu.RLock()
+	defer gs.sessionMu.RUnlock()
+	return gs.projectBrief
+}
+
+// SetProjectContext turns the project brief on or off and restarts the
+// chat sessions so the next message uses the new system instruction.
+func (gs *GeminiService) SetProjectContext(enabled bool) error {
+	old := gs.cfg.ProjectContext.Enabled
+	gs.cfg.ProjectContext.Enabled = enabled
+	if err := gs.persistConfig(); err != nil {
+		gs.cfg.ProjectContext.Enabled = old
+		return err
+	}
+	for _, conv := range gs.cm.All() {
+		conv.Repo.ResetSession()
+	}
+	return nil
+}
+
+// sessionOptionsFor returns the session options of the named profile. The
+// profile's system prompt replaces the configured one; an empty or unknown
+// name uses the configured settings.
+func (gs *GeminiService) sessionOptionsFor(profile string) SessionOptions {
+	p := gs.cfg.Profiles[profile]
+	prompt := gs.cfg.SystemPrompt
+	if p.SystemPrompt != "" {
+		prompt = p.SystemPrompt
+	}
+	if brief := gs.ProjectBrief(); brief != "" {
+		prompt = strings.TrimSpace(prompt + "\n\n" + brief)
+	}
+	return SessionOptions{
+		SystemPrompt: prompt,
+		Tools:        gs.ChatTools(),
+		Generation: GenerationParams{
+			Temperature:     p.Temperature,
+			TopP:            p.TopP,
+			MaxOutputTokens: p.MaxOutputTokens,
+		},
+	}
+}
+
+// profileModel returns the chat model of the named profile, or the
+// configured one when the profile does not set one.
+func (gs *GeminiService) profileModel(profile string) string {
+	if model := gs.cfg.Profiles[profile].ChatModel; model != "" {
+		return model
+	}
+	return gs.cfg.ChatModel
+}
+
+func (gs *GeminiService) checkProfile(name string) error {
+	if _, ok := gs.cfg.Profiles[name]; name != "" && !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	return nil
+}
+
+// SetProfile switches the active conversation to the named profile, or to
+// the configured settings when name is empty. The conversation takes the
+// profile's chat model and starts a new session with its prompt.
+func (gs *GeminiService) SetProfile(name string) error {
+	if err := gs.checkProfile(name); err != nil {
+		return err
+	}
+	conv, err := gs.cm.GetActiveConversation()
+	if err != nil {
+		return err
+	}
+	conv.Profile = name
+	conv.ChatModel = gs.profileModel(name)
+	conv.Repo.ResetSession()
+	gs.persistConversation(conv)
+	return nil
+}
+
+// SetDefaultProfile sets the profile new conversations start with and
+// saves it in the config file.
+func (gs *GeminiService) SetDefaultProfile(name string) error {
+	if err := gs.checkProfile(name); err != nil {
+		return err
+	}
+	old := gs.cfg.DefaultProfile
+	gs.cfg.DefaultProfile = name
+	if err := gs.persistConfig(); err != nil {
+		gs.cfg.DefaultProfile = old
+		return err
+	}
+	return nil
+}
+
+// ActiveProfile returns the profile of the active conversation, or the
+// default profile when there is no conversation yet.
+func (gs *GeminiService) ActiveProfile() string {
+	if conv, err := gs.cm.GetActiveConversation(); err == nil {
+		return conv.Profile
+	}
+	return gs.cfg.DefaultProfile
+}
+
 func (gs *GeminiService) GetAllConversations() ([]ConversationSummary, error) {
 	conversations := gs.cm.All()
 	if len(conversations) == 0 {
@@ -180,6 +429,7 @@ func (gs *GeminiService) GetAllConversations() ([]ConversationSummary, error) {
 			ID:          conv.ID,
 			Description: conv.GetDescription(),
 			ChatModel:   conv.ChatModel,
+			Profile:     conv.Profile,
 			UpdatedAt:   conv.UpdatedAtSnapshot().Format("2006-01-02 15:04"),
 		})
 	}
@@ -206,6 +456,17 @@ func (gs *GeminiService) SwitchConversation(c context.Context, id string) error
 	return nil
 }
 
+// Conversations returns every loaded conversation, most recently updated
+// first.
+func (gs *GeminiService) Conversations() []*Conversation {
+	return gs.cm.All()
+}
+
+// Conversation returns the loaded conversation with the given ID.
+func (gs *GeminiService) Conversation(id string) (*Conversation, error) {
+	return gs.cm.get(id)
+}
+
 func (gs *GeminiService) GetActiveConversation() (*Conversation, error) {
 	conversation, err := gs.cm.GetActiveConversation()
 	if err != nil {
@@ -241,7 +502,7 @@ func (gs *GeminiService) ReloadConfig() error {
 	gs.cfg = cfg
 	gs.store = NewFileConversationStore(cfg.DataDir)
 	for _, conv := range gs.cm.All() {
-		if conv.ChatModel == oldCfg.ChatModel {
+		if conv.ChatModel == oldCfg.ChatModel && cfg.Profiles[conv.Profile].ChatModel == "" {
 			conv.ChatModel = cfg.ChatModel
 		}
 		conv.Repo.ResetSession()
@@ -250,6 +511,9 @@ func (gs *GeminiService) ReloadConfig() error {
 	return nil
 }
 
+// LoadStoredConversations loads the stored conversations that are not
+// loaded yet, so calling it again picks up conversations another vyai
+// process has saved since.
 func (gs *GeminiService) LoadStoredConversations() error {
 	records, err := gs.store.LoadAll()
 	if err != nil {
@@ -258,17 +522,22 @@ func (gs *GeminiService) LoadStoredConversations() error {
 
 	for _, record := range records {
 		record := record
+		if _, err := gs.cm.get(record.ID); err == nil {
+			// Already loaded, possibly with newer messages than the file.
+			continue
+		}
 		if record.ChatModel == "" {
-			record.ChatModel = gs.cfg.ChatModel
+			record.ChatModel = gs.profileModel(record.Profile)
 		}
 		var conv *Conversation
 		repo := NewPersistentHistoryRepository(record.Messages, func(ctx context.Context) (interface{ Close() error }, *genai.ChatSession, error) {
-			modelID := record.ChatModel
+			modelID, profile := record.ChatModel, record.Profile
 			if conv != nil && conv.ChatModel != "" {
-				modelID = conv.ChatModel
+				modelID, profile = conv.ChatModel, conv.Profile
 			}
-			return NewChatSession(ctx, modelID, gs.cfg)
+			return NewChatSession(ctx, modelID, gs.sessionOptionsFor(profile))
 		}, nil)
+		repo.tools = gs.ChatTools()
 		conv = NewConversationFromRecord(repo, record)
 		repo.onChange = func(_ []Message) {
 			conv.Touch()
@@ -297,7 +566,9 @@ func (gs *GeminiService) persistConversation(conv *Conversation) {
 		CreatedAt:         conv.CreatedAt,
 		UpdatedAt:         conv.UpdatedAtSnapshot(),
 		ChatModel:         conv.ChatModel,
+		Profile:           conv.Profile,
 		Messages:          messages,
+		AgentRuns:         conv.agentRunsFor(messages),
 	}
 	gs.store.Save(record)
 }
@@ -333,7 +604,7 @@ func (gs *GeminiService) SetChatModel(model string) error {
 		return err
 	}
 	for _, conv := range gs.cm.All() {
-		if conv.ChatModel == old || conv.ChatModel == "" {
+		if (conv.ChatModel == old || conv.ChatModel == "") && gs.cfg.Profiles[conv.Profile].ChatModel == "" {
 			conv.ChatModel = model
 			conv.Repo.ResetSession()
 			gs.persistConversation(conv)
@@ -349,19 +620,30 @@ func (gs *GeminiService) SetDescriptionModel(model string) error {
 
 func (gs *GeminiService) persistConfig() error {
 	cfg := gs.cfg
-	fc := struct {
-		ChatModel             string `json:"chat_model"`
-		DescriptionModel      string `json:"description_model"`
-		SystemPromptFile      string `json:"system_prompt_file"`
-		DescriptionPromptFile string `json:"description_prompt_file"`
-		DataDir               string `json:"data_dir"`
-	}{
-		ChatModel:             cfg.ChatModel,
-		DescriptionModel:      cfg.DescriptionModel,
-		SystemPromptFile:      cfg.SystemPromptFile,
-		DescriptionPromptFile: cfg.DescriptionPromptFile,
-		DataDir:               cfg.DataDir,
+
+	// Start from the file on disk so sections edited by hand, such as the
+	// agent policy, survive a model change from the Settings tab.
+	fc := map[string]any{}
+	if data, err := os.ReadFile(cfg.ConfigFile); err == nil {
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return fmt.Errorf("parse config file: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	fc["chat_model"] = cfg.ChatModel
+	fc["description_model"] = cfg.DescriptionModel
+	fc["system_prompt_file"] = cfg.SystemPromptFile
+	fc["description_prompt_file"] = cfg.DescriptionPromptFile
+	fc["data_dir"] = cfg.DataDir
+	fc["project_context"] = cfg.ProjectContext
+	if cfg.DefaultProfile != "" {
+		fc["default_profile"] = cfg.DefaultProfile
+	} else {
+		delete(fc, "default_profile")
 	}
+
 	data, err := json.MarshalIndent(fc, "", "  ")
 	if err != nil {
 		return err
@@ -396,6 +678,11 @@ func buildDescriptionPrompt(messages []Message) string {
 	return strings.Join(parts, "\n")
 }
 
+// Notify shows message to the user as a notice.
+func (gs *GeminiService) Notify(message string) {
+	gs.publishNotice(message)
+}
+
 func (gs *GeminiService) publishNotice(message string) {
 	message = strings.TrimSpace(message)
 	if message == "" {
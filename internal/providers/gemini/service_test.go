@@ -1,6 +1,13 @@
 package gemini
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+)
 
 func TestSummarizeGeminiErrorQuota(t *testing.T) {
 	t.Parallel()
@@ -15,3 +22,92 @@ func TestSummarizeGeminiErrorQuota(t *testing.T) {
 type errString string
 
 func (e errString) Error() string { return string(e) }
+
+func TestProjectContextTogglesSystemInstructionBrief(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := &appconfig.Config{DataDir: dir, ConfigFile: filepath.Join(dir, "config.json"), SystemPrompt: "Be brief."}
+	gs := NewGeminiService(NewConversationManager(), cfg)
+	gs.SetProjectBrief("Project context for the workspace /src/app:")
+
+	if got := gs.sessionOptionsFor("").SystemPrompt; got != "Be brief." {
+		t.Fatalf("expected no brief while disabled, got %q", got)
+	}
+
+	if err := gs.SetProjectContext(true); err != nil {
+		t.Fatalf("SetProjectContext returned error: %v", err)
+	}
+	if got := gs.sessionOptionsFor("").SystemPrompt; got != "Be brief.\n\nProject context for the workspace /src/app:" {
+		t.Fatalf("expected brief after the system prompt, got %q", got)
+	}
+	data, err := os.ReadFile(cfg.ConfigFile)
+	if err != nil {
+		t.Fatalf("read config file: %v", err)
+	}
+	if !strings.Contains(string(data), `"enabled": true`) {
+		t.Fatalf("expected project context to be persisted, got %s", data)
+	}
+}
+
+func TestProfilesSetPromptModelAndGeneration(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	temperature := float32(0.2)
+	cfg := &appconfig.Config{
+		DataDir:      dir,
+		ConfigFile:   filepath.Join(dir, "config.json"),
+		ChatModel:    "gemini-chat",
+		SystemPrompt: "Be brief.",
+		Profiles: map[string]appconfig.Profile{
+			"reviewer": {SystemPrompt: "Review the code.", ChatModel: "gemini-review", Temperature: &temperature},
+		},
+	}
+	gs := NewGeminiService(NewConversationManager(), cfg)
+
+	opts := gs.sessionOptionsFor("reviewer")
+	if opts.SystemPrompt != "Review the code." || opts.Generation.Temperature == nil || *opts.Generation.Temperature != 0.2 {
+		t.Fatalf("unexpected reviewer options: %+v", opts)
+	}
+	if opts := gs.sessionOptionsFor(""); opts.SystemPrompt != "Be brief." || opts.Generation.Temperature != nil {
+		t.Fatalf("unexpected options without a profile: %+v", opts)
+	}
+
+	conv, err := gs.NewConversation(t.Context())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	if err := gs.SetProfile("missing"); err == nil {
+		t.Fatal("expected an error for an undefined profile")
+	}
+	if err := gs.SetProfile("reviewer"); err != nil {
+		t.Fatalf("SetProfile returned error: %v", err)
+	}
+	if conv.Profile != "reviewer" || conv.ChatModel != "gemini-review" {
+		t.Fatalf("unexpected conversation profile %q and model %q", conv.Profile, conv.ChatModel)
+	}
+
+	// A chat model change leaves conversations whose profile sets one alone.
+	if err := gs.SetChatModel("gemini-other"); err != nil {
+		t.Fatalf("SetChatModel returned error: %v", err)
+	}
+	if conv.ChatModel != "gemini-review" {
+		t.Fatalf("expected the profile model to stay, got %q", conv.ChatModel)
+	}
+
+	records, err := gs.store.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll returned error: %v", err)
+	}
+	if len(records) != 1 || records[0].Profile != "reviewer" {
+		t.Fatalf("expected the profile to be stored, got %+v", records)
+	}
+
+	if err := gs.SetProfile(""); err != nil {
+		t.Fatalf("SetProfile returned error: %v", err)
+	}
+	if conv.Profile != "" || conv.ChatModel != "gemini-other" {
+		t.Fatalf("unexpected conversation profile %q and model %q", conv.Profile, conv.ChatModel)
+	}
+}
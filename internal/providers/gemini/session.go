@@ -7,16 +7,28 @@ import (
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
-	"github.com/vybraan/vyai/internal/appconfig"
 	"google.golang.org/api/option"
 )
 
-// NewChatSession initializes a new ChatSession with proper error handling.
-func NewChatSession(c context.Context, modelID string, cfg *appconfig.Config) (*genai.Client, *genai.ChatSession, error) {
-	if cfg == nil {
-		return nil, nil, fmt.Errorf("config is required")
-	}
+// SessionOptions configures a chat session beyond its model. Tools are
+// declared to the model as callable functions.
+type SessionOptions struct {
+	SystemPrompt string
+	Tools        []ChatTool
+	Generation   GenerationParams
+}
 
+// GenerationParams overrides the sampling defaults of the model. Nil
+// fields keep the model's defaults.
+type GenerationParams struct {
+	Temperature     *float32
+	TopP            *float32
+	MaxOutputTokens *int32
+	StopSequences   []string
+}
+
+// NewChatSession initializes a new ChatSession with proper error handling.
+func NewChatSession(c context.Context, modelID string, opts SessionOptions) (*genai.Client, *genai.ChatSession, error) {
 	apiKey := os.Getenv("GOOGLE_API_KEY")
 	if apiKey == "" {
 		return nil, nil, fmt.Errorf("GOOGLE_API_KEY environment variable is not set")
@@ -33,12 +45,18 @@ func NewChatSession(c context.Context, modelID string, cfg *appconfig.Config) (*
 		return nil, nil, fmt.Errorf("failed to get generative model: %s", modelID)
 	}
 
-	if strings.TrimSpace(cfg.SystemPrompt) != "" {
+	if strings.TrimSpace(opts.SystemPrompt) != "" {
 		model.SystemInstruction = &genai.Content{
-			Parts: []genai.Part{genai.Text(cfg.SystemPrompt)},
+			Parts: []genai.Part{genai.Text(opts.SystemPrompt)},
 		}
 	}
 
+	model.Tools = genaiTools(opts.Tools)
+	model.Temperature = opts.Generation.Temperature
+	model.TopP = opts.Generation.TopP
+	model.MaxOutputTokens = opts.Generation.MaxOutputTokens
+	model.StopSequences = opts.Generation.StopSequences
+
 	cs := model.StartChat()
 	if cs == nil {
 		_ = client.Close()
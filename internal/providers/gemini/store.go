@@ -2,22 +2,26 @@ package gemini
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 )
 
 type ConversationRecord struct {
-	ID                string    `json:"id"`
-	Description       string    `json:"description"`
-	DescriptionLocked bool      `json:"description_locked"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
-	ChatModel         string    `json:"chat_model"`
-	Messages          []Message `json:"messages"`
+	ID                string     `json:"id"`
+	Description       string     `json:"description"`
+	DescriptionLocked bool       `json:"description_locked"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ChatModel         string     `json:"chat_model"`
+	Profile           string     `json:"profile,omitempty"`
+	Messages          []Message  `json:"messages"`
+	AgentRuns         []AgentRun `json:"agent_runs,omitempty"`
 }
 
 type FileConversationStore struct {
@@ -26,6 +30,11 @@ type FileConversationStore struct {
 
 var conversationIDPattern = regexp.MustCompile(`^CONVERSATION-[A-F0-9]+$`)
 
+const conversationIDPrefix = "CONVERSATION-"
+
+// ErrConversationNotFound reports that no stored conversation matches.
+var ErrConversationNotFound = errors.New("conversation not found")
+
 func NewFileConversationStore(dataDir string) *FileConversationStore {
 	return &FileConversationStore{dir: filepath.Join(dataDir, "conversations")}
 }
@@ -111,6 +120,68 @@ func (s *FileConversationStore) LoadAll() ([]ConversationRecord, error) {
 	return records, nil
 }
 
+// FindConversation returns the record whose ID starts with ref. The
+// CONVERSATION- prefix may be left out and case does not matter, so the
+// first few hex digits are enough. An exact match wins over prefixes.
+func FindConversation(records []ConversationRecord, ref string) (ConversationRecord, error) {
+	prefix := strings.ToUpper(strings.TrimSpace(ref))
+	if prefix == "" {
+		return ConversationRecord{}, errors.New("empty conversation ID")
+	}
+	if !strings.HasPrefix(prefix, conversationIDPrefix) {
+		prefix = conversationIDPrefix + prefix
+	}
+
+	var matches []ConversationRecord
+	for _, record := range records {
+		if record.ID == prefix {
+			return record, nil
+		}
+		if strings.HasPrefix(record.ID, prefix) {
+			matches = append(matches, record)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ConversationRecord{}, fmt.Errorf("%w: %s", ErrConversationNotFound, ref)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	return ConversationRecord{}, fmt.Errorf("conversation ID %s is ambiguous: %s", ref, strings.Join(ids, ", "))
+}
+
+// ResolveConversation picks a conversation the way --resume names it:
+// "last" is the most recently updated one, then ref is tried as an ID
+// prefix and finally as part of a title, where the most recently updated
+// match wins. records must be sorted as LoadAll returns them.
+func ResolveConversation(records []ConversationRecord, ref string) (ConversationRecord, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "last" {
+		if len(records) == 0 {
+			return ConversationRecord{}, fmt.Errorf("%w: there are no conversations yet", ErrConversationNotFound)
+		}
+		return records[0], nil
+	}
+
+	record, err := FindConversation(records, ref)
+	if !errors.Is(err, ErrConversationNotFound) {
+		return record, err
+	}
+
+	query := strings.ToLower(ref)
+	for _, record := range records {
+		if strings.Contains(strings.ToLower(record.Description), query) {
+			return record, nil
+		}
+	}
+	return ConversationRecord{}, fmt.Errorf("%w: no ID or title matches %q", ErrConversationNotFound, ref)
+}
+
 func (s *FileConversationStore) pathFor(id string) (string, error) {
 	if err := validateConversationID(id); err != nil {
 		return "", err
@@ -1,8 +1,10 @@
 package gemini
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -83,3 +85,57 @@ func TestFileConversationStoreDeleteRemovesRecord(t *testing.T) {
 		t.Fatalf("expected no records after delete, got %d", len(records))
 	}
 }
+
+func TestFindConversationMatchesIDPrefixes(t *testing.T) {
+	t.Parallel()
+
+	records := []ConversationRecord{
+		{ID: "CONVERSATION-AB12"},
+		{ID: "CONVERSATION-AB34"},
+		{ID: "CONVERSATION-C0"},
+		{ID: "CONVERSATION-C0FF"},
+	}
+
+	if record, err := FindConversation(records, "ab1"); err != nil || record.ID != "CONVERSATION-AB12" {
+		t.Fatalf("expected a unique prefix match, got %+v, %v", record, err)
+	}
+	if record, err := FindConversation(records, "CONVERSATION-c0"); err != nil || record.ID != "CONVERSATION-C0" {
+		t.Fatalf("expected the exact match to win, got %+v, %v", record, err)
+	}
+	if _, err := FindConversation(records, "ab"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected an ambiguity error, got %v", err)
+	}
+	if _, err := FindConversation(records, "ff"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestResolveConversationFallsBackToTitles(t *testing.T) {
+	t.Parallel()
+
+	// Sorted like LoadAll: most recently updated first.
+	records := []ConversationRecord{
+		{ID: "CONVERSATION-F1", Description: "Postgres vacuum tuning"},
+		{ID: "CONVERSATION-AB", Description: "Nginx reload"},
+		{ID: "CONVERSATION-C2", Description: "Postgres backups"},
+	}
+
+	for ref, want := range map[string]string{
+		"last":     "CONVERSATION-F1",
+		"ab":       "CONVERSATION-AB",
+		"NGINX":    "CONVERSATION-AB",
+		"postgres": "CONVERSATION-F1",
+		"backups":  "CONVERSATION-C2",
+	} {
+		record, err := ResolveConversation(records, ref)
+		if err != nil || record.ID != want {
+			t.Fatalf("ResolveConversation(%q) = %s, %v; want %s", ref, record.ID, err, want)
+		}
+	}
+	if _, err := ResolveConversation(records, "kubernetes"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("expected ErrConversationNotFound, got %v", err)
+	}
+	if _, err := ResolveConversation(nil, "last"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("expected ErrConversationNotFound without conversations, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const (
+	// maxToolRounds bounds how many times one message may go back and forth
+	// between the model and its tools.
+	maxToolRounds = 5
+	// maxFunctionNameLength is the limit Gemini puts on function names.
+	maxFunctionNameLength = 63
+)
+
+var invalidFunctionNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ChatTool is a function the chat model may call, such as a tool offered by
+// an MCP server. Parameters is the JSON schema of its arguments.
+type ChatTool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+	Call        func(ctx context.Context, args map[string]any) (string, error)
+}
+
+// FunctionName turns parts into a valid Gemini function name.
+func FunctionName(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = strings.Trim(invalidFunctionNameChars.ReplaceAllString(part, "_"), "_")
+	}
+	name := strings.Join(parts, "__")
+	if len(name) > maxFunctionNameLength {
+		name = name[:maxFunctionNameLength]
+	}
+	return name
+}
+
+func genaiTools(tools []ChatTool) []*genai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  schemaFromJSON(tool.Parameters),
+		})
+	}
+	return []*genai.Tool{{FunctionDeclarations: declarations}}
+}
+
+// jsonSchema is the subset of JSON schema that Gemini function declarations
+// can express.
+type jsonSchema struct {
+	Type        any                    `json:"type"`
+	Format      string                 `json:"format"`
+	Description string                 `json:"description"`
+	Enum        []any                  `json:"enum"`
+	Items       *jsonSchema            `json:"items"`
+	Properties  map[string]*jsonSchema `json:"properties"`
+	Required    []string               `json:"required"`
+}
+
+// schemaFromJSON converts a JSON schema into a genai schema. Parameters
+// without properties are omitted since Gemini rejects empty objects.
+func schemaFromJSON(raw json.RawMessage) *genai.Schema {
+	if len(raw) == 0 {
+		return nil
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+	return convertSchema(&schema)
+}
+
+func convertSchema(schema *jsonSchema) *genai.Schema {
+	typ, nullable := schemaType(schema.Type)
+	out := &genai.Schema{
+		Type:        typ,
+		Description: schema.Description,
+		Nullable:    nullable,
+	}
+
+	switch typ {
+	case genai.TypeString:
+		// Gemini only accepts the enum and date-time formats for strings.
+		if schema.Format == "enum" || schema.Format == "date-time" {
+			out.Format = schema.Format
+		}
+		for _, value := range schema.Enum {
+			if text, ok := value.(string); ok {
+				out.Enum = append(out.Enum, text)
+			}
+		}
+	case genai.TypeArray:
+		items := schema.Items
+		if items == nil {
+			items = &jsonSchema{Type: "string"}
+		}
+		out.Items = convertSchema(items)
+	case genai.TypeObject:
+		if len(schema.Properties) == 0 {
+			// Free-form objects cannot be declared; take them as JSON text.
+			out.Type = genai.TypeString
+			out.Description = strings.TrimSpace(out.Description + " (JSON object)")
+			break
+		}
+		out.Properties = make(map[string]*genai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			if property == nil {
+				property = &jsonSchema{Type: "string"}
+			}
+			out.Properties[name] = convertSchema(property)
+		}
+		for _, name := range schema.Required {
+			if _, ok := schema.Properties[name]; ok {
+				out.Required = append(out.Required, name)
+			}
+		}
+		sort.Strings(out.Required)
+	}
+	return out
+}
+
+// schemaType maps a JSON schema type, which may be a list such as
+// ["string", "null"], to a genai type.
+func schemaType(value any) (genai.Type, bool) {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = []string{v}
+	case []any:
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	nullable := false
+	typ := genai.TypeString
+	found := false
+	for _, name := range names {
+		if name == "null" {
+			nullable = true
+			continue
+		}
+		if found {
+			continue
+		}
+		switch name {
+		case "string":
+			typ, found = genai.TypeString, true
+		case "number":
+			typ, found = genai.TypeNumber, true
+		case "integer":
+			typ, found = genai.TypeInteger, true
+		case "boolean":
+			typ, found = genai.TypeBoolean, true
+		case "array":
+			typ, found = genai.TypeArray, true
+		case "object":
+			typ, found = genai.TypeObject, true
+		}
+	}
+	return typ, nullable
+}
+
+func functionCalls(resp *genai.GenerateContentResponse) []genai.FunctionCall {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil
+	}
+	var calls []genai.FunctionCall
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if call, ok := part.(genai.FunctionCall); ok {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// callTools runs the requested calls and returns the responses to send
+// back. Failures are reported to the model rather than aborting the
+// message. When final is set the model is asked to answer without calling
+// more tools.
+func callTools(c context.Context, tools []ChatTool, calls []genai.FunctionCall, final bool) []genai.Part {
+	byName := make(map[string]ChatTool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+
+	parts := make([]genai.Part, 0, len(calls))
+	for _, call := range calls {
+		response := map[string]any{}
+		tool, ok := byName[call.Name]
+		switch {
+		case final:
+			response["error"] = "tool call limit reached; answer with the information gathered so far"
+		case !ok:
+			response["error"] = fmt.Sprintf("unknown tool %q", call.Name)
+		default:
+			output, err := tool.Call(c, decodeObjectArgs(tool.Parameters, call.Args))
+			if err != nil {
+				response["error"] = err.Error()
+				if output != "" {
+					response["output"] = output
+				}
+			} else {
+				response["output"] = output
+			}
+		}
+		parts = append(parts, genai.FunctionResponse{Name: call.Name, Response: response})
+	}
+	return parts
+}
+
+// decodeObjectArgs parses the arguments that schemaFromJSON declared as JSON
+// text because they are free-form objects.
+func decodeObjectArgs(raw json.RawMessage, args map[string]any) map[string]any {
+	var schema jsonSchema
+	if len(raw) == 0 || json.Unmarshal(raw, &schema) != nil {
+		return args
+	}
+	for name, property := range schema.Properties {
+		if property == nil || len(property.Properties) > 0 {
+			continue
+		}
+		if typ, _ := schemaType(property.Type); typ != genai.TypeObject {
+			continue
+		}
+		text, ok := args[name].(string)
+		if !ok {
+			continue
+		}
+		var value map[string]any
+		if err := json.Unmarshal([]byte(text), &value); err == nil {
+			args[name] = value
+		}
+	}
+	return args
+}
+
+// pruneHistory keeps at most limit contents, making sure the history does
+// not start in the middle of a tool exchange or end with a call that was
+// never answered.
+func pruneHistory(history []*genai.Content, limit int) []*genai.Content {
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	for len(history) > 0 && hasToolParts(history[0]) {
+		history = history[1:]
+	}
+	for len(history) > 0 && hasFunctionCall(history[len(history)-1]) {
+		history = history[:len(history)-1]
+	}
+	return history
+}
+
+func hasToolParts(content *genai.Content) bool {
+	for _, part := range content.Parts {
+		switch part.(type) {
+		case genai.FunctionCall, genai.FunctionResponse:
+			return true
+		}
+	}
+	return false
+}
+
+func hasFunctionCall(content *genai.Content) bool {
+	for _, part := range content.Parts {
+		if _, ok := part.(genai.FunctionCall); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestSchemaFromJSONConvertsToolParameters(t *testing.T) {
+	t.Parallel()
+
+	schema := schemaFromJSON([]byte(`{
+  "type": "object",
+  "properties": {
+    "path": {"type": "string", "description": "File path"},
+    "limit": {"type": ["integer", "null"]},
+    "tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}},
+    "options": {"type": "object"}
+  },
+  "required": ["path", "missing"]
+}`))
+	if schema == nil || schema.Type != genai.TypeObject {
+		t.Fatalf("expected object schema, got %+v", schema)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "path" {
+		t.Fatalf("expected only declared required fields, got %v", schema.Required)
+	}
+	if limit := schema.Properties["limit"]; limit.Type != genai.TypeInteger || !limit.Nullable {
+		t.Fatalf("unexpected limit schema: %+v", limit)
+	}
+	if tags := schema.Properties["tags"]; tags.Type != genai.TypeArray || len(tags.Items.Enum) != 2 {
+		t.Fatalf("unexpected tags schema: %+v", tags)
+	}
+	if options := schema.Properties["options"]; options.Type != genai.TypeString {
+		t.Fatalf("expected free-form object as JSON text, got %+v", options)
+	}
+
+	if schemaFromJSON([]byte(`{"type": "object"}`)) != nil {
+		t.Fatal("expected no schema for tools without parameters")
+	}
+}
+
+func TestCallToolsDecodesObjectArguments(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	tools := []ChatTool{{
+		Name:       "srv__tool",
+		Parameters: []byte(`{"type": "object", "properties": {"options": {"type": "object"}}}`),
+		Call: func(_ context.Context, args map[string]any) (string, error) {
+			got = args
+			return "ok", nil
+		},
+	}}
+
+	parts := callTools(context.Background(), tools, []genai.FunctionCall{
+		{Name: "srv__tool", Args: map[string]any{"options": `{"deep": true}`}},
+		{Name: "srv__missing"},
+	}, false)
+
+	if options, ok := got["options"].(map[string]any); !ok || options["deep"] != true {
+		t.Fatalf("expected decoded object argument, got %#v", got)
+	}
+	if response := parts[0].(genai.FunctionResponse).Response; response["output"] != "ok" {
+		t.Fatalf("unexpected response: %v", response)
+	}
+	if response := parts[1].(genai.FunctionResponse).Response; response["error"] == nil {
+		t.Fatalf("expected error for unknown tool, got %v", response)
+	}
+}
+
+func TestPruneHistoryDropsPartialToolExchanges(t *testing.T) {
+	t.Parallel()
+
+	call := genai.FunctionCall{Name: "srv__tool"}
+	history := []*genai.Content{
+		{Role: "user", Parts: []genai.Part{genai.Text("weather?")}},
+		{Role: "model", Parts: []genai.Part{call}},
+		{Role: "user", Parts: []genai.Part{genai.FunctionResponse{Name: "srv__tool"}}},
+		{Role: "model", Parts: []genai.Part{genai.Text("sunny")}},
+		{Role: "user", Parts: []genai.Part{genai.Text("and tomorrow?")}},
+		{Role: "model", Parts: []genai.Part{call}},
+	}
+
+	pruned := pruneHistory(history, 4)
+	if len(pruned) != 2 || pruned[0].Parts[0] != genai.Text("sunny") {
+		t.Fatalf("unexpected pruned history: %+v", pruned)
+	}
+
+	messages := messagesFromHistory(history)
+	if len(messages) != 3 || messages[1].Text != "sunny" {
+		t.Fatalf("expected tool turns left out of messages, got %+v", messages)
+	}
+}
+
+func TestFunctionNameSanitizes(t *testing.T) {
+	t.Parallel()
+
+	if got := FunctionName("my server", "read.file"); got != "my_server__read_file" {
+		t.Fatalf("unexpected function name %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ranker scores chunks against a query. BM25 is built in; an embedding
+// model can be plugged in by implementing the same two methods.
+type Ranker interface {
+	// Build replaces the ranked chunks.
+	Build(ctx context.Context, chunks []Chunk) error
+	// Search returns at most k chunks with a positive score, best first.
+	Search(ctx context.Context, query string, k int) ([]Hit, error)
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stopwords are too common in questions and code to tell chunks apart.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "do": true, "does": true, "for": true, "from": true,
+	"how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "this": true, "that": true, "to": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true,
+	"with": true, "we": true, "you": true, "can": true, "i": true,
+}
+
+// BM25 ranks chunks by Okapi BM25 over their words and path.
+type BM25 struct {
+	chunks  []Chunk
+	terms   []map[string]int
+	lengths []int
+	avgLen  float64
+	df      map[string]int
+}
+
+func NewBM25() *BM25 {
+	return &BM25{}
+}
+
+func (r *BM25) Build(ctx context.Context, chunks []Chunk) error {
+	r.chunks = chunks
+	r.terms = make([]map[string]int, len(chunks))
+	r.lengths = make([]int, len(chunks))
+	r.df = map[string]int{}
+
+	total := 0
+	for i, chunk := range chunks {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		counts := map[string]int{}
+		tokens := append(tokenize(chunk.Path), tokenize(chunk.Text)...)
+		for _, token := range tokens {
+			counts[token]++
+		}
+		for term := range counts {
+			r.df[term]++
+		}
+		r.terms[i] = counts
+		r.lengths[i] = len(tokens)
+		total += len(tokens)
+	}
+	if len(chunks) > 0 {
+		r.avgLen = float64(total) / float64(len(chunks))
+	}
+	return nil
+}
+
+func (r *BM25) Search(ctx context.Context, query string, k int) ([]Hit, error) {
+	queryTerms := map[string]bool{}
+	for _, token := range tokenize(query) {
+		queryTerms[token] = true
+	}
+	if len(queryTerms) == 0 || len(r.chunks) == 0 {
+		return nil, nil
+	}
+
+	n := float64(len(r.chunks))
+	var hits []Hit
+	for i, counts := range r.terms {
+		score := 0.0
+		for term := range queryTerms {
+			tf := float64(counts[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(r.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(r.lengths[i])/r.avgLen
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+		if score > 0 {
+			hits = append(hits, Hit{Chunk: r.chunks[i], Score: score})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// tokenize lowercases the words of text. Identifiers are kept whole and
+// also split at camelCase and snake_case boundaries, so "parseConfig"
+// matches both "parseconfig" and "config".
+func tokenize(text string) []string {
+	var tokens []string
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			tokens = appendToken(tokens, strings.ToLower(strings.ReplaceAll(word, "_", "")))
+		}
+		for _, part := range parts {
+			tokens = appendToken(tokens, strings.ToLower(part))
+		}
+	}
+	return tokens
+}
+
+func appendToken(tokens []string, token string) []string {
+	if len(token) < 2 || stopwords[token] {
+		return tokens
+	}
+	return append(tokens, token)
+}
+
+// splitIdentifier splits at underscores and at lower-to-upper case
+// changes, keeping acronyms together: "HTTPServer_addr" becomes "HTTP",
+// "Server" and "addr".
+func splitIdentifier(word string) []string {
+	var parts []string
+	runes := []rune(word)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' {
+			if i > start {
+				parts = append(parts, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i > start && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+	}
+	if start < len(runes) {
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
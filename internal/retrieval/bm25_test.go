@@ -0,0 +1,47 @@
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSplitsIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	got := strings.Join(tokenize("How does parseHTTPConfig read max_retries in a file?"), ",")
+	want := "parsehttpconfig,parse,http,config,read,maxretries,max,retries,file"
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestBM25RanksRareTermsHigher(t *testing.T) {
+	t.Parallel()
+
+	chunks := []Chunk{
+		{Path: "a.go", Start: 1, End: 2, Text: "func handle() { log.Print(request) }"},
+		{Path: "b.go", Start: 1, End: 2, Text: "func retry(request) { backoff(request) }"},
+		{Path: "c.go", Start: 1, End: 2, Text: "func serve(request) { handle(request) }"},
+	}
+	ranker := NewBM25()
+	if err := ranker.Build(context.Background(), chunks); err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	hits, err := ranker.Search(context.Background(), "request backoff", 2)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 2 || hits[0].Path != "b.go" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+
+	hits, err = ranker.Search(context.Background(), "what is the", 5)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("expected stopwords to match nothing, got %+v", hits)
+	}
+}
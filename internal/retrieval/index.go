@@ -0,0 +1,319 @@
+// Package retrieval keeps an offline index of workspace files and finds
+// the chunks most relevant to a question.
+package retrieval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vybraan/vyai/internal/agent"
+)
+
+const (
+	indexVersion = 1
+
+	// chunkLines is the size of a chunk and chunkOverlap how many lines
+	// consecutive chunks share, so code near a boundary is found in one.
+	chunkLines   = 40
+	chunkOverlap = 8
+	maxChunkSize = 4000
+
+	maxFileSize  = 512 << 10
+	maxFiles     = 20000
+	binarySniff  = 8000
+	DefaultLimit = 8
+)
+
+// skippedFiles are generated files that would crowd out real code.
+var skippedFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+}
+
+// Chunk is a line range of a workspace file. Path is slash-separated and
+// relative to the workspace; Start and End are 1-based and inclusive.
+type Chunk struct {
+	Path  string `json:"path"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// Location is the path:line citation of the chunk.
+func (c Chunk) Location() string {
+	if c.Start == c.End {
+		return fmt.Sprintf("%s:%d", c.Path, c.Start)
+	}
+	return fmt.Sprintf("%s:%d-%d", c.Path, c.Start, c.End)
+}
+
+// Hit is a chunk returned by a search.
+type Hit struct {
+	Chunk
+	Score float64
+}
+
+// RefreshStats describes what a refresh changed.
+type RefreshStats struct {
+	Files   int
+	Chunks  int
+	Added   int
+	Updated int
+	Removed int
+	// Truncated is set when the workspace has more files than are indexed.
+	Truncated bool
+}
+
+func (s RefreshStats) Changed() bool {
+	return s.Added+s.Updated+s.Removed > 0
+}
+
+type fileEntry struct {
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+type indexFile struct {
+	Version   int                   `json:"version"`
+	Workspace string                `json:"workspace"`
+	Files     map[string]*fileEntry `json:"files"`
+}
+
+// Index is the chunk index of one workspace, stored as JSON at path. It is
+// safe for concurrent use.
+type Index struct {
+	path      string
+	workspace string
+	ranker    Ranker
+
+	mu     sync.Mutex
+	files  map[string]*fileEntry
+	loaded bool
+	built  bool
+}
+
+// IndexPath is where the index of workspace lives inside the data
+// directory.
+func IndexPath(dataDir, workspace string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(workspace)))
+	return filepath.Join(dataDir, "index", hex.EncodeToString(sum[:8])+".json")
+}
+
+// NewIndex returns the index of workspace stored at path. A nil ranker
+// uses BM25.
+func NewIndex(path, workspace string, ranker Ranker) *Index {
+	if ranker == nil {
+		ranker = NewBM25()
+	}
+	return &Index{path: path, workspace: filepath.Clean(workspace), ranker: ranker}
+}
+
+// Refresh brings the index up to date with the workspace. Files whose
+// modification time and size are unchanged keep their chunks; only new and
+// changed files are read again.
+func (x *Index) Refresh(ctx context.Context) (RefreshStats, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := x.loadLocked(); err != nil {
+		return RefreshStats{}, err
+	}
+
+	var stats RefreshStats
+	seen := map[string]bool{}
+	err := agent.WalkWorkspace(ctx, x.workspace, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !d.Type().IsRegular() || !indexable(rel) {
+			return nil
+		}
+		if len(seen) >= maxFiles {
+			stats.Truncated = true
+			return fs.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		seen[rel] = true
+
+		entry, ok := x.files[rel]
+		if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+			return nil
+		}
+		chunks, err := chunkFile(filepath.Join(x.workspace, filepath.FromSlash(rel)), rel)
+		if err != nil {
+			return nil
+		}
+		x.files[rel] = &fileEntry{ModTime: info.ModTime(), Size: info.Size(), Chunks: chunks}
+		if ok {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.SkipAll) {
+		return RefreshStats{}, err
+	}
+
+	for rel := range x.files {
+		if !seen[rel] {
+			delete(x.files, rel)
+			stats.Removed++
+		}
+	}
+	stats.Files = len(x.files)
+	for _, entry := range x.files {
+		stats.Chunks += len(entry.Chunks)
+	}
+
+	if stats.Changed() {
+		x.built = false
+		if err := x.saveLocked(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Search returns the limit chunks that best match query, using the index
+// as of the last refresh.
+func (x *Index) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := x.loadLocked(); err != nil {
+		return nil, err
+	}
+	if !x.built {
+		if err := x.ranker.Build(ctx, x.chunksLocked()); err != nil {
+			return nil, err
+		}
+		x.built = true
+	}
+	return x.ranker.Search(ctx, query, limit)
+}
+
+// chunksLocked lists every chunk in path order.
+func (x *Index) chunksLocked() []Chunk {
+	paths := make([]string, 0, len(x.files))
+	for rel := range x.files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	var chunks []Chunk
+	for _, rel := range paths {
+		chunks = append(chunks, x.files[rel].Chunks...)
+	}
+	return chunks
+}
+
+func (x *Index) loadLocked() error {
+	if x.loaded {
+		return nil
+	}
+	x.files = map[string]*fileEntry{}
+	x.loaded = true
+
+	data, err := os.ReadFile(x.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read index: %w", err)
+	}
+	var stored indexFile
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != indexVersion || stored.Workspace != x.workspace {
+		// An unreadable or outdated index is rebuilt from scratch.
+		return nil
+	}
+	if stored.Files != nil {
+		x.files = stored.Files
+	}
+	return nil
+}
+
+func (x *Index) saveLocked() error {
+	data, err := json.Marshal(indexFile{Version: indexVersion, Workspace: x.workspace, Files: x.files})
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0700); err != nil {
+		return fmt.Errorf("create index dir: %w", err)
+	}
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	return nil
+}
+
+func indexable(rel string) bool {
+	name := path.Base(rel)
+	return !skippedFiles[name] && !strings.HasSuffix(name, ".min.js") && !strings.HasSuffix(name, ".min.css")
+}
+
+// chunkFile splits a text file into overlapping line windows. Binary files
+// have no chunks.
+func chunkFile(filename, rel string) ([]Chunk, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniff)], 0) >= 0 {
+		return nil, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxFileSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += chunkLines - chunkOverlap {
+		end := min(start+chunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if len(text) > maxChunkSize {
+			text = text[:maxChunkSize]
+		}
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{Path: rel, Start: start + 1, End: end, Text: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks, nil
+}
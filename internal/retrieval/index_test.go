@@ -0,0 +1,129 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vybraan/vyai/internal/testutil"
+)
+
+func TestIndexRefreshesIncrementally(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	testutil.WriteTree(t, workspace, map[string]string{
+		".gitignore":      "build/\n",
+		"auth/token.go":   "package auth\n\n// RefreshToken renews an expired session token.\nfunc RefreshToken() {}\n",
+		"store/db.go":     "package store\n\nfunc OpenDatabase() {}\n",
+		"build/output.go": "package build\n\nfunc RefreshToken() {}\n",
+		"go.sum":          "example.com/x v1.0.0 h1:RefreshToken\n",
+		"logo.png":        "\x89PNG\x00\x00RefreshToken",
+	})
+	path := IndexPath(t.TempDir(), workspace)
+
+	index := NewIndex(path, workspace, nil)
+	stats, err := index.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if stats.Added != 4 || stats.Chunks != 3 {
+		t.Fatalf("unexpected first refresh stats: %+v", stats)
+	}
+
+	hits, err := index.Search(context.Background(), "how are session tokens refreshed?", 5)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Location() != "auth/token.go:1-4" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+
+	// A fresh index over the same file reuses the stored chunks.
+	reopened := NewIndex(path, workspace, nil)
+	stats, err = reopened.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if stats.Changed() || stats.Files != 4 {
+		t.Fatalf("expected an unchanged index, got %+v", stats)
+	}
+
+	testutil.WriteTree(t, workspace, map[string]string{
+		"store/db.go": "package store\n\n// RefreshToken stores a renewed session token.\nfunc SaveToken() {}\n",
+	})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(workspace, "store", "db.go"), later, later); err != nil {
+		t.Fatalf("Chtimes returned error: %v", err)
+	}
+	if err := os.Remove(filepath.Join(workspace, "auth", "token.go")); err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+
+	stats, err = reopened.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if stats.Added != 0 || stats.Updated != 1 || stats.Removed != 1 {
+		t.Fatalf("unexpected incremental stats: %+v", stats)
+	}
+	hits, err = reopened.Search(context.Background(), "refresh token", 5)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Path != "store/db.go" {
+		t.Fatalf("unexpected hits after reindexing: %+v", hits)
+	}
+}
+
+func TestChunkFileOverlapsWindows(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := 1; i <= 70; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	path := filepath.Join(t.TempDir(), "long.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	chunks, err := chunkFile(path, "long.txt")
+	if err != nil {
+		t.Fatalf("chunkFile returned error: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %+v", chunks)
+	}
+	if chunks[0].Location() != "long.txt:1-40" || chunks[1].Location() != "long.txt:33-70" {
+		t.Fatalf("unexpected chunk ranges: %s, %s", chunks[0].Location(), chunks[1].Location())
+	}
+	if !strings.HasPrefix(chunks[1].Text, "line 33\n") {
+		t.Fatalf("unexpected second chunk: %q", chunks[1].Text)
+	}
+}
+
+func TestBuildPromptCitesExcerpts(t *testing.T) {
+	t.Parallel()
+
+	hits := []Hit{
+		{Chunk: Chunk{Path: "main.go", Start: 3, End: 9, Text: "func main() {}"}},
+		{Chunk: Chunk{Path: "README.md", Start: 1, End: 1, Text: "```sh\nmake\n```"}},
+	}
+	prompt := BuildPrompt(" where is main? ", hits)
+	if !strings.HasPrefix(prompt, "/ask-code where is main?\n") {
+		t.Fatalf("unexpected prompt start: %q", prompt)
+	}
+	for _, want := range []string{"[1] main.go:3-9\n```\nfunc main() {}\n```", "[2] README.md:1\n~~~\n"} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("expected prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if sources := FormatSources(hits); sources != "**Sources**\n1. `main.go:3-9`\n2. `README.md:1`" {
+		t.Fatalf("unexpected sources: %q", sources)
+	}
+}
@@ -0,0 +1,46 @@
+package retrieval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Command is the chat command that asks a question about the workspace.
+const Command = "/ask-code"
+
+// BuildPrompt asks question with the retrieved excerpts attached. The
+// first line repeats the command as the user typed it; it is all the
+// conversation keeps of the prompt.
+func BuildPrompt(question string, hits []Hit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n\n", Command, strings.Join(strings.Fields(question), " "))
+	if len(hits) == 0 {
+		b.WriteString("No excerpts of the workspace matched the question. Say so, and answer only if you can without seeing the code.\n")
+		return b.String()
+	}
+
+	b.WriteString("Answer the question using the excerpts of the current workspace below. ")
+	b.WriteString("Cite the excerpts you rely on as path:line or path:start-end. ")
+	b.WriteString("If the excerpts do not contain the answer, say so instead of guessing.\n")
+	for i, hit := range hits {
+		fence := "```"
+		if strings.Contains(hit.Text, "```") {
+			fence = "~~~"
+		}
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n%s\n%s\n", i+1, hit.Location(), fence, hit.Text, fence)
+	}
+	return b.String()
+}
+
+// FormatSources renders the locations of hits as a markdown footer.
+func FormatSources(hits []Hit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("**Sources**\n")
+	for i, hit := range hits {
+		fmt.Fprintf(&b, "%d. `%s`\n", i+1, hit.Location())
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+// defaultModel is the model name that stands for the configured chat
+// model, so clients do not have to know which one that is.
+const defaultModel = "vyai"
+
+// The OpenAI-compatible endpoints translate chat completions to the
+// configured backend. Each request is a one-off exchange: the client sends
+// the whole history and nothing is stored.
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	ids := []string{defaultModel}
+	if model := s.gs.Config().ChatModel; model != "" && model != defaultModel {
+		ids = append(ids, model)
+	}
+	models := []openAIModel{}
+	for _, id := range ids {
+		models = append(models, openAIModel{ID: id, Object: "model", OwnedBy: "vyai"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": models})
+}
+
+type chatCompletionRequest struct {
+	Model               string           `json:"model"`
+	Messages            []chatMessage    `json:"messages"`
+	Stream              bool             `json:"stream"`
+	Temperature         *float32         `json:"temperature"`
+	TopP                *float32         `json:"top_p"`
+	MaxTokens           *int32           `json:"max_tokens"`
+	MaxCompletionTokens *int32           `json:"max_completion_tokens"`
+	Stop                stopSequences    `json:"stop"`
+	N                   *int             `json:"n"`
+	Tools               []map[string]any `json:"tools"`
+}
+
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content chatContent `json:"content"`
+}
+
+// chatContent is message content given as a string or as an array of
+// parts. Only text parts are supported.
+type chatContent string
+
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = chatContent(text)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content must be a string or an array of parts")
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Type != "text" {
+			return fmt.Errorf("content parts of type %q are not supported", part.Type)
+		}
+		b.WriteString(part.Text)
+	}
+	*c = chatContent(b.String())
+	return nil
+}
+
+// stopSequences accepts a single stop string or an array of them.
+type stopSequences []string
+
+func (s *stopSequences) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = stopSequences{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("stop must be a string or an array of strings")
+	}
+	*s = many
+	return nil
+}
+
+// completionRequest translates the OpenAI messages: system and developer
+// messages extend the system prompt, assistant turns become model turns
+// and the last message, which must come from the user, is the prompt.
+func completionRequest(req chatCompletionRequest) (gemini.CompletionRequest, error) {
+	out := gemini.CompletionRequest{
+		Generation: gemini.GenerationParams{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: req.MaxCompletionTokens,
+			StopSequences:   req.Stop,
+		},
+	}
+	if out.Generation.MaxOutputTokens == nil {
+		out.Generation.MaxOutputTokens = req.MaxTokens
+	}
+	if req.Model != defaultModel {
+		out.Model = req.Model
+	}
+	if req.N != nil && *req.N != 1 {
+		return out, errors.New("only n=1 is supported")
+	}
+	if len(req.Tools) > 0 {
+		return out, errors.New("tools are not supported")
+	}
+
+	var system []string
+	var turns []gemini.Message
+	for i, message := range req.Messages {
+		text := string(message.Content)
+		var role string
+		switch message.Role {
+		case "system", "developer":
+			if strings.TrimSpace(text) != "" {
+				system = append(system, text)
+			}
+			continue
+		case "user":
+			role = "user"
+		case "assistant":
+			role = "model"
+		default:
+			return out, fmt.Errorf("messages[%d]: role %q is not supported", i, message.Role)
+		}
+		// Consecutive turns of the same role are merged; the backend
+		// expects users and the model to take turns.
+		if n := len(turns); n > 0 && turns[n-1].Role == role {
+			turns[n-1].Text += "\n\n" + text
+			continue
+		}
+		turns = append(turns, gemini.Message{Role: role, Text: text})
+	}
+	if len(turns) == 0 || turns[len(turns)-1].Role != "user" {
+		return out, errors.New("the last message must come from the user")
+	}
+	if strings.TrimSpace(turns[len(turns)-1].Text) == "" {
+		return out, errors.New("the last message is empty")
+	}
+
+	out.System = strings.Join(system, "\n\n")
+	out.History = turns[:len(turns)-1]
+	out.Prompt = turns[len(turns)-1].Text
+	return out, nil
+}
+
+type chatCompletion struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []completionChoice `json:"choices"`
+}
+
+type completionChoice struct {
+	Index        int              `json:"index"`
+	Message      *completionDelta `json:"message,omitempty"`
+	Delta        *completionDelta `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type completionDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+// chatCompletions answers an OpenAI chat completion request. With "stream"
+// set, the answer is sent as chat.completion.chunk events ending in
+// [DONE], as OpenAI clients expect.
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	completion, err := completionRequest(req)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	model := req.Model
+	if model == "" {
+		model = defaultModel
+	}
+	base := chatCompletion{ID: newCompletionID(), Created: time.Now().Unix(), Model: model}
+	stop := "stop"
+
+	if !req.Stream {
+		text, err := s.complete(r.Context(), completion, func(string) {})
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadGateway, "%v", err)
+			return
+		}
+		base.Object = "chat.completion"
+		base.Choices = []completionChoice{{Message: &completionDelta{Role: "assistant", Content: text}, FinishReason: &stop}}
+		writeJSON(w, http.StatusOK, base)
+		return
+	}
+
+	base.Object = "chat.completion.chunk"
+	events := newEventStream(w)
+	chunk := func(delta completionDelta, finish *string) {
+		c := base
+		c.Choices = []completionChoice{{Delta: &delta, FinishReason: finish}}
+		events.data(c)
+	}
+	chunk(completionDelta{Role: "assistant"}, nil)
+	_, err = s.complete(r.Context(), completion, deltas(func(token string) {
+		chunk(completionDelta{Content: token}, nil)
+	}))
+	if err != nil {
+		events.data(openAIError(err.Error()))
+	} else {
+		chunk(completionDelta{}, &stop)
+	}
+	events.done()
+}
+
+func newCompletionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+func openAIError(message string) map[string]any {
+	return map[string]any{"error": map[string]string{"message": message, "type": "invalid_request_error"}}
+}
+
+// writeOpenAIError answers with an error in the shape OpenAI clients parse.
+func writeOpenAIError(w http.ResponseWriter, status int, format string, args ...any) {
+	body := openAIError(fmt.Sprintf(format, args...))
+	if status >= http.StatusInternalServerError {
+		body["error"].(map[string]string)["type"] = "api_error"
+	}
+	writeJSON(w, status, body)
+}
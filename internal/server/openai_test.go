@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+func TestModelsListsTheConfiguredModel(t *testing.T) {
+	t.Parallel()
+
+	_, h := newTestServer(t, "")
+	rec := serve(h, http.MethodGet, "/v1/models", "", nil)
+	var list struct {
+		Object string        `json:"object"`
+		Data   []openAIModel `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected models response %d: %s", rec.Code, rec.Body)
+	}
+	if list.Object != "list" || len(list.Data) != 2 || list.Data[0].ID != "vyai" || list.Data[1].ID != "gemini-test" {
+		t.Fatalf("unexpected models: %+v", list)
+	}
+}
+
+func TestCompletionRequestTranslatesMessages(t *testing.T) {
+	t.Parallel()
+
+	var req chatCompletionRequest
+	body := `{
+		"model": "vyai",
+		"messages": [
+			{"role": "system", "content": "Answer briefly."},
+			{"role": "user", "content": "hi"},
+			{"role": "assistant", "content": "Hello."},
+			{"role": "user", "content": [{"type": "text", "text": "what is "}, {"type": "text", "text": "nginx?"}]},
+			{"role": "user", "content": "in one line"}
+		],
+		"temperature": 0.2,
+		"max_tokens": 100,
+		"stop": "END",
+		"user": "ignored"
+	}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	got, err := completionRequest(req)
+	if err != nil {
+		t.Fatalf("completionRequest returned error: %v", err)
+	}
+	if got.Model != "" || got.System != "Answer briefly." {
+		t.Fatalf("unexpected model or system prompt: %+v", got)
+	}
+	if len(got.History) != 2 || got.History[0].Role != "user" || got.History[1].Role != "model" || got.History[1].Text != "Hello." {
+		t.Fatalf("unexpected history: %+v", got.History)
+	}
+	if got.Prompt != "what is nginx?\n\nin one line" {
+		t.Fatalf("unexpected prompt: %q", got.Prompt)
+	}
+	gen := got.Generation
+	if gen.Temperature == nil || *gen.Temperature != 0.2 || gen.MaxOutputTokens == nil || *gen.MaxOutputTokens != 100 ||
+		len(gen.StopSequences) != 1 || gen.StopSequences[0] != "END" {
+		t.Fatalf("unexpected generation params: %+v", gen)
+	}
+}
+
+func TestChatCompletionsRejectsInvalidRequests(t *testing.T) {
+	t.Parallel()
+
+	_, h := newTestServer(t, "secret")
+	auth := map[string]string{"Authorization": "Bearer secret"}
+	for name, tc := range map[string]struct {
+		body   string
+		header map[string]string
+		want   int
+	}{
+		"no token":        {body: `{"messages": [{"role": "user", "content": "hi"}]}`, want: http.StatusUnauthorized},
+		"no messages":     {body: `{"model": "vyai", "messages": []}`, header: auth, want: http.StatusBadRequest},
+		"ends with reply": {body: `{"messages": [{"role": "user", "content": "hi"}, {"role": "assistant", "content": "Hello."}]}`, header: auth, want: http.StatusBadRequest},
+		"image part":      {body: `{"messages": [{"role": "user", "content": [{"type": "image_url"}]}]}`, header: auth, want: http.StatusBadRequest},
+		"tool role":       {body: `{"messages": [{"role": "tool", "content": "42"}]}`, header: auth, want: http.StatusBadRequest},
+	} {
+		rec := serve(h, http.MethodPost, "/v1/chat/completions", tc.body, tc.header)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", name, tc.want, rec.Code, rec.Body)
+		}
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || strings.TrimSpace(body.Error.Message) == "" {
+			t.Fatalf("%s: expected an OpenAI error, got %s", name, rec.Body)
+		}
+	}
+}
+
+func TestChatCompletionsStreamsNewTextOnly(t *testing.T) {
+	t.Parallel()
+
+	gs, _ := newTestServer(t, "")
+	s := New(gs, "")
+	var answer string
+	s.complete = func(_ context.Context, _ gemini.CompletionRequest, onToken func(string)) (string, error) {
+		answer = fakeStream(onToken, "Hel", "lo, ", "world.")
+		return answer, nil
+	}
+
+	rec := serve(s.Handler(), http.MethodPost, "/v1/chat/completions", `{"model": "vyai", "stream": true, "messages": [{"role": "user", "content": "hi"}]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+
+	_, data := readEvents(t, rec.Body.String())
+	if len(data) == 0 || data[len(data)-1] != "[DONE]" {
+		t.Fatalf("expected the stream to end with [DONE], got %q", rec.Body)
+	}
+	var joined strings.Builder
+	var finish string
+	for _, event := range data[:len(data)-1] {
+		var chunk chatCompletion
+		if err := json.Unmarshal([]byte(event), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", event, err)
+		}
+		if chunk.Object != "chat.completion.chunk" || len(chunk.Choices) != 1 || chunk.Choices[0].Delta == nil {
+			t.Fatalf("unexpected chunk: %s", event)
+		}
+		joined.WriteString(chunk.Choices[0].Delta.Content)
+		if reason := chunk.Choices[0].FinishReason; reason != nil {
+			finish = *reason
+		}
+	}
+	if joined.String() != answer || finish != "stop" {
+		t.Fatalf("expected the deltas to add up to %q, got %q (finish %q)", answer, joined.String(), finish)
+	}
+}
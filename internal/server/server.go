@@ -0,0 +1,363 @@
+// Package server exposes conversations and messaging over a local
+// HTTP/JSON API, along with OpenAI-compatible chat completions.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+// maxBodyBytes bounds request bodies.
+const maxBodyBytes = 4 << 20
+
+// Server serves the API on top of a GeminiService.
+type Server struct {
+	gs    *gemini.GeminiService
+	token string
+
+	// send sends a message to the active conversation and complete answers
+	// a one-off request; tests replace them.
+	send     func(ctx context.Context, content string, onToken func(string)) (string, error)
+	complete func(ctx context.Context, req gemini.CompletionRequest, onToken func(string)) (string, error)
+
+	// mu serializes requests that use the service's active conversation.
+	mu sync.Mutex
+}
+
+// New returns a server for gs. A non-empty token must be sent as a bearer
+// token with every request.
+func New(gs *gemini.GeminiService, token string) *Server {
+	return &Server{gs: gs, token: token, send: gs.SendMessageStream, complete: gs.Complete}
+}
+
+// Handler routes the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/conversations", s.listConversations)
+	mux.HandleFunc("POST /api/conversations", s.createConversation)
+	mux.HandleFunc("GET /api/conversations/{id}", s.getConversation)
+	mux.HandleFunc("DELETE /api/conversations/{id}", s.deleteConversation)
+	mux.HandleFunc("POST /api/conversations/{id}/messages", s.sendMessage)
+	mux.HandleFunc("GET /v1/models", s.listModels)
+	mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	return s.guard(mux)
+}
+
+// guard rejects requests that do not come from a local client. The API
+// spends the user's quota, so browsers must not reach it from other sites:
+// the Host and Origin must be loopback names, and bodies must be JSON,
+// which a cross-site form cannot send without a preflight.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError := writeError
+		if strings.HasPrefix(r.URL.Path, "/v1/") {
+			writeError = writeOpenAIError
+		}
+		if !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "host %q is not allowed", r.Host)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !isLoopbackHost(u.Host) {
+				writeError(w, http.StatusForbidden, "origin %q is not allowed", origin)
+				return
+			}
+		}
+		if s.token != "" {
+			got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost accepts localhost and loopback addresses, with or without
+// a port.
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Conversation is the JSON form of a conversation. Messages are only
+// included when a single conversation is requested.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	ChatModel string    `json:"chat_model"`
+	Profile   string    `json:"profile,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  []Message `json:"messages,omitempty"`
+}
+
+type Message struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+	Kind string `json:"kind,omitempty"`
+}
+
+func conversationJSON(conv *gemini.Conversation, withMessages bool) Conversation {
+	out := Conversation{
+		ID:        conv.ID,
+		Title:     conv.GetDescription(),
+		ChatModel: conv.ChatModel,
+		Profile:   conv.Profile,
+		CreatedAt: conv.CreatedAt,
+		UpdatedAt: conv.UpdatedAtSnapshot(),
+	}
+	if !withMessages {
+		return out
+	}
+	out.Messages = []Message{}
+	if conv.Repo == nil {
+		return out
+	}
+	messages, _ := conv.Repo.GetMessages()
+	for _, message := range messages {
+		out.Messages = append(out.Messages, Message{Role: message.Role, Text: message.Text, Kind: message.Kind})
+	}
+	return out
+}
+
+func (s *Server) listConversations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Pick up conversations saved by the TUI since the server started.
+	if err := s.gs.LoadStoredConversations(); err != nil {
+		writeError(w, http.StatusInternalServerError, "load conversations: %v", err)
+		return
+	}
+	conversations := []Conversation{}
+	for _, conv := range s.gs.Conversations() {
+		conversations = append(conversations, conversationJSON(conv, false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conversations": conversations})
+}
+
+func (s *Server) createConversation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := decodeBody(r, &req, true); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, err := s.gs.NewConversation(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "create conversation: %v", err)
+		return
+	}
+	if title := strings.TrimSpace(req.Title); title != "" {
+		if err := s.gs.RenameConversation(conv.ID, title); err != nil {
+			writeError(w, http.StatusInternalServerError, "rename conversation: %v", err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusCreated, conversationJSON(conv, true))
+}
+
+func (s *Server) getConversation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, conversationJSON(conv, true))
+}
+
+func (s *Server) deleteConversation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.gs.DeleteConversation(conv.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "delete conversation: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendMessage sends a message to a conversation. The answer is streamed as
+// server-sent events when the client accepts text/event-stream or sets
+// "stream": token events carry the text added to the answer since the last
+// one, and a final done or error event ends the stream.
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Content string `json:"content"`
+		Stream  bool   `json:"stream"`
+	}
+	if err := decodeBody(r, &req, false); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+	stream := req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.gs.SwitchConversation(r.Context(), conv.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "open conversation: %v", err)
+		return
+	}
+
+	if !stream {
+		text, err := s.send(r.Context(), req.Content, func(string) {})
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "%v", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Message{Role: "model", Text: text})
+		return
+	}
+
+	events := newEventStream(w)
+	text, err := s.send(r.Context(), req.Content, deltas(func(token string) {
+		events.send("token", map[string]string{"text": token})
+	}))
+	if err != nil {
+		events.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	events.send("done", Message{Role: "model", Text: text})
+}
+
+// deltas adapts send to stream callbacks, which carry the whole answer so
+// far: send only gets the text added since the previous call.
+func deltas(send func(string)) func(string) {
+	sent := 0
+	return func(answer string) {
+		if len(answer) <= sent {
+			return
+		}
+		send(answer[sent:])
+		sent = len(answer)
+	}
+}
+
+// lookup finds the conversation named in the path, loading conversations
+// saved elsewhere first, and answers 404 when there is none.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*gemini.Conversation, bool) {
+	if err := s.gs.LoadStoredConversations(); err != nil {
+		writeError(w, http.StatusInternalServerError, "load conversations: %v", err)
+		return nil, false
+	}
+	conv, err := s.gs.Conversation(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "%v", err)
+		return nil, false
+	}
+	return conv, true
+}
+
+// decodeBody reads a JSON request body. An empty body is accepted when
+// optional is set.
+func decodeBody(r *http.Request, v any, optional bool) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) && optional {
+			return nil
+		}
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// eventStream writes server-sent events, flushing each one.
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return &eventStream{w: w, rc: http.NewResponseController(w)}
+}
+
+func (e *eventStream) send(event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data)
+	_ = e.rc.Flush()
+}
+
+// data writes an unnamed event, the form OpenAI streams use.
+func (e *eventStream) data(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(e.w, "data: %s\n\n", data)
+	_ = e.rc.Flush()
+}
+
+// done ends an OpenAI stream.
+func (e *eventStream) done() {
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	_ = e.rc.Flush()
+}
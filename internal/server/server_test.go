@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+func newTestServer(t *testing.T, token string) (*gemini.GeminiService, http.Handler) {
+	t.Helper()
+	dataDir := t.TempDir()
+	store := gemini.NewFileConversationStore(dataDir)
+	if err := store.Save(gemini.ConversationRecord{
+		ID:          "CONVERSATION-AB12",
+		Description: "Nginx reload",
+		UpdatedAt:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Messages: []gemini.Message{
+			{Role: "user", Text: "how do I reload nginx?"},
+			{Role: "model", Text: "Run `nginx -s reload`."},
+		},
+	}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	gs := gemini.NewGeminiService(gemini.NewConversationManager(), &appconfig.Config{DataDir: dataDir, ChatModel: "gemini-test"})
+	return gs, New(gs, token).Handler()
+}
+
+func serve(h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:8765"
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerManagesConversations(t *testing.T) {
+	t.Parallel()
+
+	gs, h := newTestServer(t, "")
+
+	rec := serve(h, http.MethodGet, "/api/conversations", "", nil)
+	var list struct {
+		Conversations []Conversation `json:"conversations"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected list response %d: %s", rec.Code, rec.Body)
+	}
+	if len(list.Conversations) != 1 || list.Conversations[0].Title != "Nginx reload" || list.Conversations[0].Messages != nil {
+		t.Fatalf("unexpected conversations: %+v", list.Conversations)
+	}
+
+	rec = serve(h, http.MethodGet, "/api/conversations/CONVERSATION-AB12", "", nil)
+	var conv Conversation
+	if err := json.Unmarshal(rec.Body.Bytes(), &conv); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected get response %d: %s", rec.Code, rec.Body)
+	}
+	if len(conv.Messages) != 2 || conv.Messages[1].Role != "model" {
+		t.Fatalf("unexpected messages: %+v", conv.Messages)
+	}
+
+	rec = serve(h, http.MethodPost, "/api/conversations", `{"title": "Disk usage"}`, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &conv); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected create response %d: %s", rec.Code, rec.Body)
+	}
+	if conv.Title != "Disk usage" || conv.ChatModel != "gemini-test" {
+		t.Fatalf("unexpected created conversation: %+v", conv)
+	}
+
+	rec = serve(h, http.MethodPost, "/api/conversations/"+conv.ID+"/messages", `{"content": " "}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an empty message to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(h, http.MethodDelete, "/api/conversations/CONVERSATION-AB12", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected delete response %d: %s", rec.Code, rec.Body)
+	}
+	if _, err := gs.Conversation("CONVERSATION-AB12"); err == nil {
+		t.Fatal("expected the conversation to be deleted")
+	}
+	rec = serve(h, http.MethodGet, "/api/conversations/CONVERSATION-AB12", "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestServerRejectsForeignRequests(t *testing.T) {
+	t.Parallel()
+
+	_, h := newTestServer(t, "secret")
+
+	for name, tc := range map[string]struct {
+		method string
+		header map[string]string
+		body   string
+		want   int
+	}{
+		"missing token": {method: http.MethodGet, want: http.StatusUnauthorized},
+		"wrong token":   {method: http.MethodGet, header: map[string]string{"Authorization": "Bearer nope"}, want: http.StatusUnauthorized},
+		"foreign host":  {method: http.MethodGet, header: map[string]string{"Authorization": "Bearer secret", "Host": "evil.example"}, want: http.StatusForbidden},
+		"foreign origin": {method: http.MethodGet, header: map[string]string{"Authorization": "Bearer secret", "Origin": "https://evil.example"},
+			want: http.StatusForbidden},
+		"form post": {method: http.MethodPost, header: map[string]string{"Authorization": "Bearer secret", "Content-Type": "text/plain"}, body: "{}",
+			want: http.StatusUnsupportedMediaType},
+		"allowed": {method: http.MethodGet, header: map[string]string{"Authorization": "Bearer secret", "Origin": "http://localhost:3000"}, want: http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/api/conversations", strings.NewReader(tc.body))
+		req.Host = "localhost:8765"
+		for key, value := range tc.header {
+			if key == "Host" {
+				req.Host = value
+				continue
+			}
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", name, tc.want, rec.Code, rec.Body)
+		}
+	}
+}
+
+// fakeStream calls onToken the way the service does, with the whole answer
+// so far, and returns the answer.
+func fakeStream(onToken func(string), chunks ...string) string {
+	var answer strings.Builder
+	for _, chunk := range chunks {
+		answer.WriteString(chunk)
+		onToken(answer.String())
+	}
+	return answer.String()
+}
+
+// readEvents parses a server-sent event stream into its events.
+func readEvents(t *testing.T, body string) (names, data []string) {
+	t.Helper()
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	name := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			names = append(names, name)
+			data = append(data, strings.TrimPrefix(line, "data: "))
+			name = ""
+		}
+	}
+	return names, data
+}
+
+func TestSendMessageStreamsNewTextOnly(t *testing.T) {
+	t.Parallel()
+
+	gs, _ := newTestServer(t, "")
+	s := New(gs, "")
+	s.send = func(_ context.Context, _ string, onToken func(string)) (string, error) {
+		return fakeStream(onToken, "Run ", "`nginx -s reload`", "."), nil
+	}
+
+	rec := serve(s.Handler(), http.MethodPost, "/api/conversations/CONVERSATION-AB12/messages", `{"content": "and restart?", "stream": true}`, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+
+	names, data := readEvents(t, rec.Body.String())
+	var joined strings.Builder
+	var final Message
+	for i, name := range names {
+		switch name {
+		case "token":
+			var token struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(data[i]), &token); err != nil {
+				t.Fatalf("invalid token event %q: %v", data[i], err)
+			}
+			joined.WriteString(token.Text)
+		case "done":
+			if err := json.Unmarshal([]byte(data[i]), &final); err != nil {
+				t.Fatalf("invalid done event %q: %v", data[i], err)
+			}
+		default:
+			t.Fatalf("unexpected event %q: %s", name, data[i])
+		}
+	}
+	if len(names) != 4 || final.Text != "Run `nginx -s reload`." || joined.String() != final.Text {
+		t.Fatalf("expected the tokens to add up to the answer, got %q and %+v from %q", joined.String(), final, rec.Body)
+	}
+}
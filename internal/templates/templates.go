@@ -0,0 +1,362 @@
+// Package templates renders the user's prompt templates: Markdown files in
+// the config dir with {{name}} placeholders and front-matter defaults.
+package templates
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vybraan/vyai/internal/agent"
+)
+
+// Command is the chat command that sends a template.
+const Command = "/t"
+
+// InputVar holds the words given without a name, and StdinVar the input
+// piped to vyai ask.
+const (
+	InputVar = "input"
+	StdinVar = "stdin"
+)
+
+// maxFileBytes bounds a workspace file included with {{file:...}}.
+const maxFileBytes = 128 << 10
+
+// ErrNotFound reports a template that does not exist.
+var ErrNotFound = errors.New("template not found")
+
+// Template is a parsed template file. Defaults come from the front matter;
+// its description key describes the template instead.
+type Template struct {
+	Name        string
+	Path        string
+	Description string
+	Defaults    map[string]string
+	Body        string
+}
+
+// Load reads the template called name from dir.
+func Load(dir, name string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	path := filepath.Join(dir, name+".md")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s (looked for %s)", ErrNotFound, name, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(name, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	t.Path = path
+	return t, nil
+}
+
+// List returns the templates in dir sorted by name. A missing dir has
+// none.
+func List(dir string) ([]*Template, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []*Template
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".md")
+		if !ok || entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		t, err := Load(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// Parse splits a template into its front matter and body. The front matter
+// is optional and holds one "key: value" pair per line between --- lines.
+func Parse(name, content string) (*Template, error) {
+	t := &Template{Name: name, Defaults: map[string]string{}, Body: content}
+	rest, ok := strings.CutPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "---\n")
+	if !ok {
+		return t, nil
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		if header, ok = strings.CutSuffix(rest, "\n---"); !ok {
+			return nil, errors.New("front matter is not closed with ---")
+		}
+		body = ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(header))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || !validName(key) {
+			return nil, fmt.Errorf("front matter line %d: expected key: value", n)
+		}
+		value = unquote(strings.TrimSpace(value))
+		if key == "description" {
+			t.Description = value
+			continue
+		}
+		t.Defaults[key] = value
+	}
+	t.Body = strings.TrimLeft(body, "\n")
+	return t, nil
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func validName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// placeholder matches {{name}}, {{stdin}} and {{file:path}}.
+var placeholder = regexp.MustCompile(`\{\{\s*(file:[^}]+?|[A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// Variables lists the names the template refers to, without files.
+func (t *Template) Variables() []string {
+	var names []string
+	for _, match := range placeholder.FindAllStringSubmatch(t.Body, -1) {
+		if name := match[1]; !strings.HasPrefix(name, "file:") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Render fills in the placeholders. vars override the defaults, and
+// {{file:path}} includes a file of workspace; path may also name a
+// variable that holds the path. Values are not expanded again.
+func (t *Template) Render(vars map[string]string, workspace string) (string, error) {
+	value := func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
+		}
+		v, ok := t.Defaults[name]
+		return v, ok
+	}
+
+	var missing []string
+	var renderErr error
+	out := placeholder.ReplaceAllStringFunc(t.Body, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		if path, ok := strings.CutPrefix(name, "file:"); ok {
+			path = strings.TrimSpace(path)
+			if v, ok := value(path); ok {
+				path = v
+			}
+			text, err := includeFile(workspace, path)
+			if err != nil && renderErr == nil {
+				renderErr = err
+			}
+			return text
+		}
+		v, ok := value(name)
+		if !ok && name != StdinVar && name != InputVar {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return match
+		}
+		return v
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %s needs a value for %s (name=value)", t.Name, strings.Join(missing, ", "))
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Prompt loads the template called name from dir and renders it. Input
+// and stdin that the template does not place are added after it.
+func Prompt(dir, name string, vars map[string]string, workspace, stdin string) (string, error) {
+	t, err := Load(dir, name)
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{}
+	for key, value := range vars {
+		values[key] = value
+	}
+	stdin = strings.TrimSpace(stdin)
+	if _, ok := values[StdinVar]; !ok && stdin != "" {
+		values[StdinVar] = stdin
+	}
+
+	prompt, err := t.Render(values, workspace)
+	if err != nil {
+		return "", err
+	}
+	used := t.Variables()
+	if input := strings.TrimSpace(values[InputVar]); input != "" && !slices.Contains(used, InputVar) {
+		prompt += "\n\n" + input
+	}
+	if stdin != "" && !slices.Contains(used, StdinVar) {
+		fence := "```"
+		if strings.Contains(stdin, "```") {
+			fence = "~~~~"
+		}
+		prompt += "\n\n" + fence + "\n" + stdin + "\n" + fence
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return "", fmt.Errorf("template %s is empty", name)
+	}
+	return prompt, nil
+}
+
+// includeFile returns a workspace file as a fenced block headed by its
+// path.
+func includeFile(workspace, path string) (string, error) {
+	if workspace == "" {
+		return "", errors.New("files cannot be included without a workspace")
+	}
+	full, err := agent.ResolveWorkspacePath(workspace, path)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	if info.IsDir() || info.Size() > maxFileBytes {
+		return "", fmt.Errorf("include %s: not a file of at most %d KiB", path, maxFileBytes>>10)
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("include %s: not a text file", path)
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	fence := "```"
+	if strings.Contains(text, "```") {
+		fence = "~~~~"
+	}
+	return fmt.Sprintf("%s\n%s\n%s\n%s", filepath.ToSlash(path), fence, text, fence), nil
+}
+
+// ParseArgs splits the arguments of "/t name key=value ...". Quotes group
+// words; see Vars for the rest.
+func ParseArgs(line string) (name string, vars map[string]string, err error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(words) == 0 {
+		return "", nil, errors.New("usage: " + Command + " <template> [name=value ...] [text]")
+	}
+	return words[0], Vars(words[1:]), nil
+}
+
+// Vars turns name=value words into variables. The other words make up
+// InputVar.
+func Vars(words []string) map[string]string {
+	vars := map[string]string{}
+	var input []string
+	for _, word := range words {
+		if key, value, ok := strings.Cut(word, "="); ok && validName(key) {
+			vars[key] = value
+			continue
+		}
+		input = append(input, word)
+	}
+	if len(input) > 0 {
+		vars[InputVar] = strings.Join(input, " ")
+	}
+	return vars
+}
+
+// splitWords splits on whitespace outside single or double quotes.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Summary lists the templates in dir for the chat.
+func Summary(dir string) (string, error) {
+	list, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return fmt.Sprintf("No templates yet. Add Markdown files to %s.", dir), nil
+	}
+	lines := make([]string, 0, len(list))
+	for _, t := range list {
+		line := t.Name
+		if vars := t.Variables(); len(vars) > 0 {
+			sort.Strings(vars)
+			line += " (" + strings.Join(vars, ", ") + ")"
+		}
+		if t.Description != "" {
+			line += ": " + t.Description
+		}
+		lines = append(lines, line)
+	}
+	return "Templates: " + strings.Join(lines, "; "), nil
+}
@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+}
+
+func TestParseReadsFrontMatter(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := Parse("review", "---\ndescription: Review a file\nfocus: \"error handling\"\n# a comment\n---\n\nReview {{ path }} for {{focus}}.\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if tmpl.Description != "Review a file" || tmpl.Defaults["focus"] != "error handling" || len(tmpl.Defaults) != 1 {
+		t.Fatalf("unexpected front matter: %+v", tmpl)
+	}
+	if tmpl.Body != "Review {{ path }} for {{focus}}.\n" {
+		t.Fatalf("unexpected body: %q", tmpl.Body)
+	}
+	if vars := tmpl.Variables(); len(vars) != 2 || vars[0] != "path" || vars[1] != "focus" {
+		t.Fatalf("unexpected variables: %v", vars)
+	}
+
+	if _, err := Parse("broken", "---\nfocus: x\nReview it.\n"); err == nil {
+		t.Fatal("expected unclosed front matter to be an error")
+	}
+	if tmpl, err := Parse("plain", "Just {{input}}."); err != nil || tmpl.Body != "Just {{input}}." {
+		t.Fatalf("expected a template without front matter, got %+v, %v", tmpl, err)
+	}
+}
+
+func TestPromptFillsVariablesFilesAndStdin(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	workspace := t.TempDir()
+	writeFile(t, filepath.Join(workspace, "nginx", "site.conf"), "server { listen 80; }\n")
+	writeFile(t, filepath.Join(dir, "review.md"), "---\nfocus: security\npath: nginx/site.conf\n---\nReview for {{focus}}:\n\n{{file:path}}\n")
+	writeFile(t, filepath.Join(dir, "logs.md"), "Summarize these {{kind}} logs: {{stdin}}")
+
+	got, err := Prompt(dir, "review", map[string]string{"focus": "performance", InputVar: "be brief"}, workspace, "")
+	if err != nil {
+		t.Fatalf("Prompt returned error: %v", err)
+	}
+	if got != "Review for performance:\n\nnginx/site.conf\n```\nserver { listen 80; }\n```\n\nbe brief" {
+		t.Fatalf("unexpected prompt:\n%s", got)
+	}
+
+	got, err = Prompt(dir, "logs", map[string]string{"kind": "{{stdin}}"}, workspace, "oom killed\n")
+	if err != nil {
+		t.Fatalf("Prompt returned error: %v", err)
+	}
+	if got != "Summarize these {{stdin}} logs: oom killed" {
+		t.Fatalf("unexpected prompt: %q", got)
+	}
+
+	if _, err := Prompt(dir, "logs", nil, workspace, ""); err == nil || !strings.Contains(err.Error(), "needs a value for kind") {
+		t.Fatalf("expected a missing variable error, got %v", err)
+	}
+	if _, err := Prompt(dir, "review", map[string]string{"path": "../secret"}, workspace, ""); err == nil {
+		t.Fatal("expected a path outside the workspace to be refused")
+	}
+	if _, err := Prompt(dir, "nope", nil, workspace, ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := Load(dir, "../review"); err == nil {
+		t.Fatal("expected a name with a slash to be refused")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	t.Parallel()
+
+	name, vars, err := ParseArgs(`review path=nginx/site.conf focus="error handling" and be brief`)
+	if err != nil {
+		t.Fatalf("ParseArgs returned error: %v", err)
+	}
+	if name != "review" || vars["path"] != "nginx/site.conf" || vars["focus"] != "error handling" || vars[InputVar] != "and be brief" {
+		t.Fatalf("unexpected arguments: %s %v", name, vars)
+	}
+	if _, _, err := ParseArgs(`review focus="open`); err == nil {
+		t.Fatal("expected an unterminated quote to be an error")
+	}
+	if _, _, err := ParseArgs("  "); err == nil {
+		t.Fatal("expected a missing name to be an error")
+	}
+}
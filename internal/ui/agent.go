@@ -0,0 +1,320 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+// collapsedFailureLines is how much of a failed tool's output stays visible
+// while tool output is collapsed.
+const collapsedFailureLines = 3
+
+type agentStep struct {
+	tool    string
+	detail  string
+	output  string
+	running bool
+	failed  bool
+}
+
+// agentRun is the live state of the most recent /agent request.
+type agentRun struct {
+	request string
+	program string
+	steps   []agentStep
+	summary string
+	// conversationID is the conversation the run started in, which the
+	// user may have left by the time it finishes.
+	conversationID string
+}
+
+func (r *agentRun) apply(ev agent.Event) {
+	switch ev.Kind {
+	case agent.EventTranslating:
+		r.steps = append(r.steps, agentStep{running: true})
+	case agent.EventProgram:
+		r.finishRunning()
+		r.program = ev.Output
+	case agent.EventToolStarted:
+		r.finishRunning()
+		r.steps = append(r.steps, agentStep{tool: ev.Tool, detail: ev.Detail, running: true})
+	case agent.EventToolFinished:
+		for i := len(r.steps) - 1; i >= 0; i-- {
+			step := &r.steps[i]
+			if step.running && step.tool == ev.Tool {
+				step.running = false
+				step.failed = ev.Failed
+				step.output = ev.Output
+				break
+			}
+		}
+	case agent.EventSummary:
+		r.finishRunning()
+		r.summary = ev.Output
+	}
+}
+
+func (r *agentRun) finishRunning() {
+	for i := range r.steps {
+		r.steps[i].running = false
+	}
+}
+
+// failRunning marks tools that never finished, e.g. when translation failed.
+func (r *agentRun) failRunning() {
+	for i := range r.steps {
+		if r.steps[i].running {
+			r.steps[i].running = false
+			r.steps[i].failed = true
+		}
+	}
+}
+
+func (r *agentRun) hasOutput() bool {
+	for _, step := range r.steps {
+		if step.output != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// record converts a finished run for storage in the conversation.
+func (r agentRun) record(err error) gemini.AgentRun {
+	run := gemini.AgentRun{
+		Request: r.request,
+		Program: r.program,
+		Summary: r.summary,
+	}
+	for _, step := range r.steps {
+		if step.tool == "" {
+			continue
+		}
+		run.Results = append(run.Results, gemini.AgentToolResult{
+			Tool:   step.tool,
+			Detail: step.detail,
+			Output: step.output,
+			Failed: step.failed,
+		})
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	return run
+}
+
+func agentRunFromRecord(run gemini.AgentRun) agentRun {
+	restored := agentRun{
+		request: run.Request,
+		program: run.Program,
+		summary: run.Summary,
+	}
+	for _, result := range run.Results {
+		restored.steps = append(restored.steps, agentStep{
+			tool:   result.Tool,
+			detail: result.Detail,
+			output: result.Output,
+			failed: result.Failed,
+		})
+	}
+	return restored
+}
+
+var (
+	agentToolStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#DFDBDD")).Bold(true)
+	agentMutedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#858392"))
+	agentOKStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#12C78F"))
+	agentFailedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#EB4268"))
+	agentOutputStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#B9B6C0")).
+				PaddingLeft(1).
+				MarginLeft(2).
+				BorderLeft(true).
+				BorderStyle(lipgloss.NormalBorder()).
+				BorderForeground(lipgloss.Color("#3A3943"))
+)
+
+// renderAgentRun draws each tool as a header line with its status and,
+// when expanded, its output. Failures keep their last lines visible even
+// when collapsed so errors are never hidden.
+func renderAgentRun(run agentRun, expanded bool, spin string, width int) string {
+	var blocks []string
+	for _, step := range run.steps {
+		icon := agentOKStyle.Render("✓")
+		switch {
+		case step.running:
+			icon = spin
+		case step.failed:
+			icon = agentFailedStyle.Render("✗")
+		}
+
+		name := step.tool
+		if name == "" {
+			name = "translating request"
+		}
+		header := icon + " " + agentToolStyle.Render(name)
+		if step.detail != "" {
+			header += " " + agentMutedStyle.Render(step.detail)
+		}
+
+		lines := strings.Split(step.output, "\n")
+		if step.output != "" && !step.running {
+			header += agentMutedStyle.Render(fmt.Sprintf(" (%d lines)", len(lines)))
+		}
+		blocks = append(blocks, header)
+
+		switch {
+		case step.output == "":
+		case expanded:
+			blocks = append(blocks, agentOutputStyle.Render(step.output))
+		case step.failed:
+			if len(lines) > collapsedFailureLines {
+				lines = lines[len(lines)-collapsedFailureLines:]
+			}
+			blocks = append(blocks, agentOutputStyle.Render(strings.Join(lines, "\n")))
+		}
+	}
+
+	if run.hasOutput() {
+		hint := "o: show tool output"
+		if expanded {
+			hint = "o: hide tool output"
+		}
+		blocks = append(blocks, agentMutedStyle.Render(hint))
+	}
+
+	if run.summary != "" {
+		blocks = append(blocks, strings.TrimSpace(renderMarkdown(run.summary, width)))
+	}
+
+	return strings.Join(blocks, "\n")
+}
+
+func (m *UIModel) renderAgentRun() string {
+	return renderAssistantMessage(renderAgentRun(m.agentRun, m.agentExpanded, m.spinner.View(), m.width), false)
+}
+
+// agentRunShown reports whether the current run belongs to the
+// conversation on screen.
+func (m *UIModel) agentRunShown() bool {
+	conversation, err := m.gsService.GetActiveConversation()
+	return err == nil && conversation.ID == m.agentRun.conversationID
+}
+
+// refreshAgentView redraws the viewport with the in-progress run below the
+// finished messages.
+func (m *UIModel) refreshAgentView() {
+	if !m.agentRunShown() {
+		return
+	}
+	content := m.renderAgentRun()
+	if len(m.messages) > 0 {
+		content = strings.Join(m.messages, "\n") + "\n" + content
+	}
+	m.renderViewport(content)
+}
+
+// toggleAgentOutput expands or collapses tool output of the current or
+// most recent agent run.
+func (m *UIModel) toggleAgentOutput() {
+	m.agentExpanded = !m.agentExpanded
+	switch {
+	case m.agentRunning:
+		m.refreshAgentView()
+	case m.agentRunIndex >= 0 && m.agentRunIndex < len(m.messages):
+		m.messages[m.agentRunIndex] = m.renderAgentRun()
+		m.renderViewport(strings.Join(m.messages, "\n"))
+	}
+}
+
+// saveAgentRun stores the finished run in the conversation it started in.
+// Runs that never got as far as a program are not worth keeping.
+func (m *UIModel) saveAgentRun(err error) error {
+	if m.agentRun.program == "" {
+		return nil
+	}
+	_, saveErr := m.gsService.RecordAgentRun(context.Background(), m.agentRun.conversationID, m.agentRun.record(err))
+	return saveErr
+}
+
+// clearAgentRun forgets the last run, e.g. when the message list is replaced.
+// A run still in progress is kept so it can be saved when it finishes.
+func (m *UIModel) clearAgentRun() {
+	if !m.agentRunning {
+		m.agentRun = agentRun{}
+	}
+	m.agentRunIndex = -1
+}
+
+// cancelledNotice tells the user what a cancelled run had already done.
+func cancelledNotice(err *agent.CancelledError) string {
+	notice := "Agent run cancelled before any tool completed."
+	if len(err.Executed) > 0 {
+		notice = fmt.Sprintf("Agent run cancelled. Already executed: %s.", strings.Join(err.Executed, "; "))
+	}
+	if err.Interrupted != "" {
+		notice += " Interrupted: " + err.Interrupted + "."
+	}
+	return notice
+}
+
+func sendAgentCmd(ctx context.Context, m UIModel, prompt string) tea.Cmd {
+	return func() tea.Msg {
+		userInput := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prompt), "/agent"))
+		if m.agentRunner == nil {
+			return noticeMsg{text: "Agent runner is not configured.", stopLoading: true}
+		}
+
+		conversation, err := m.gsService.EnsureConversation(ctx)
+		if err != nil {
+			return noticeMsg{text: "Agent request failed: " + summarizeUserError(err), stopLoading: true}
+		}
+
+		req := agent.RunRequest{
+			Input:          userInput,
+			Model:          conversation.ChatModel,
+			ConversationID: conversation.ID,
+			ProjectBrief:   m.gsService.ProjectBrief(),
+		}
+		if req.Model == "" {
+			req.Model = m.gsService.Config().ChatModel
+		}
+		if conversation.Profile != "" {
+			policy, err := agent.PolicyFromConfig(m.gsService.Config().AgentPolicyForProfile(m.workspace, conversation.Profile))
+			if err != nil {
+				return noticeMsg{text: "Agent request failed: profile policy: " + summarizeUserError(err), stopLoading: true}
+			}
+			req.Policy = policy
+		}
+
+		events := make(chan agent.Event, 20)
+		done := make(chan error, 1)
+		req.OnEvent = func(ev agent.Event) {
+			events <- ev
+		}
+
+		go func() {
+			_, err := m.agentRunner.Run(ctx, req)
+			done <- err
+			close(events)
+		}()
+
+		return agentStartMsg{request: userInput, conversationID: conversation.ID, events: events, done: done}
+	}
+}
+
+func pollAgentCmd(m UIModel) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-m.agentEvents
+		if !ok {
+			return agentEndMsg{err: <-m.agentDone}
+		}
+		return agentEventMsg(ev)
+	}
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/appconfig"
+	"github.com/vybraan/vyai/internal/providers/gemini"
+)
+
+func TestAgentRunCollapsesToolOutput(t *testing.T) {
+	t.Parallel()
+
+	var run agentRun
+	for _, ev := range []agent.Event{
+		{Kind: agent.EventTranslating},
+		{Kind: agent.EventToolStarted, Tool: "run-bash", Detail: "go test ./..."},
+		{Kind: agent.EventToolFinished, Tool: "run-bash", Detail: "go test ./...", Output: "ok  \texample/pkg"},
+		{Kind: agent.EventToolStarted, Tool: "read-file", Detail: "missing.go"},
+		{Kind: agent.EventToolFinished, Tool: "read-file", Detail: "missing.go", Output: "File blocked: no such file", Failed: true},
+		{Kind: agent.EventSummary, Output: "done"},
+	} {
+		run.apply(ev)
+	}
+
+	if len(run.steps) != 3 || run.steps[0].running {
+		t.Fatalf("unexpected steps: %+v", run.steps)
+	}
+
+	collapsed := renderAgentRun(run, false, "*", 80)
+	if strings.Contains(collapsed, "example/pkg") {
+		t.Fatalf("expected successful output to be collapsed, got %q", collapsed)
+	}
+	if !strings.Contains(collapsed, "no such file") {
+		t.Fatalf("expected failure output to stay visible, got %q", collapsed)
+	}
+
+	expanded := renderAgentRun(run, true, "*", 80)
+	if !strings.Contains(expanded, "example/pkg") {
+		t.Fatalf("expected expanded output, got %q", expanded)
+	}
+}
+
+func TestAgentRunShowsSpinnerForRunningTool(t *testing.T) {
+	t.Parallel()
+
+	var run agentRun
+	run.apply(agent.Event{Kind: agent.EventToolStarted, Tool: "run-bash", Detail: "go vet ./..."})
+
+	if out := renderAgentRun(run, false, "SPIN", 80); !strings.Contains(out, "SPIN") {
+		t.Fatalf("expected spinner for running tool, got %q", out)
+	}
+
+	run.failRunning()
+	if out := renderAgentRun(run, false, "SPIN", 80); strings.Contains(out, "SPIN") {
+		t.Fatalf("expected no spinner after the run ended, got %q", out)
+	}
+}
+
+func TestAgentRunRecordRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	run := agentRun{request: "list files"}
+	for _, ev := range []agent.Event{
+		{Kind: agent.EventTranslating},
+		{Kind: agent.EventProgram, Output: `<list-dir path="."></list-dir>`},
+		{Kind: agent.EventToolStarted, Tool: "list-dir", Detail: "."},
+		{Kind: agent.EventToolFinished, Tool: "list-dir", Detail: ".", Output: "go.mod"},
+	} {
+		run.apply(ev)
+	}
+
+	record := run.record(errString("agent run cancelled"))
+	if len(record.Results) != 1 || record.Results[0].Tool != "list-dir" {
+		t.Fatalf("expected only tool results to be recorded, got %+v", record.Results)
+	}
+	if record.Request != "list files" || record.Program == "" || record.Error != "agent run cancelled" {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+
+	restored := agentRunFromRecord(record)
+	if len(restored.steps) != 1 || restored.steps[0].output != "go.mod" || restored.steps[0].running {
+		t.Fatalf("unexpected restored run: %+v", restored)
+	}
+}
+
+func TestAgentRunIsSavedWhereItStarted(t *testing.T) {
+	t.Parallel()
+
+	gs := gemini.NewGeminiService(gemini.NewConversationManager(), &appconfig.Config{DataDir: t.TempDir(), ChatModel: "gemini-test"})
+	started, err := gs.NewConversation(context.Background())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	other, err := gs.NewConversation(context.Background())
+	if err != nil {
+		t.Fatalf("NewConversation returned error: %v", err)
+	}
+	if err := gs.SwitchConversation(context.Background(), started.ID); err != nil {
+		t.Fatalf("SwitchConversation returned error: %v", err)
+	}
+
+	var model tea.Model = NewUIModel(gs, t.TempDir(), nil)
+	model, _ = model.Update(agentStartMsg{request: "list files", conversationID: started.ID})
+	model, _ = model.Update(agentEventMsg{Kind: agent.EventProgram, Output: `<list-dir path="."></list-dir>`})
+
+	// The user opens another conversation while the agent is running.
+	m, _ := model.(UIModel).openConversation(other.ID)
+	model, _ = m.Update(agentEventMsg{Kind: agent.EventSummary, Output: "done"})
+	model, _ = model.Update(agentEndMsg{})
+
+	m = model.(UIModel)
+	if strings.Contains(m.notice, "not saved") {
+		t.Fatalf("unexpected notice: %s", m.notice)
+	}
+	if messages, err := started.Repo.GetMessages(); err != nil || len(messages) != 2 || messages[1].Kind != gemini.MessageKindAgent {
+		t.Fatalf("expected the run in the conversation it started in, got %+v, %v", messages, err)
+	}
+	if messages, err := other.Repo.GetMessages(); err == nil && len(messages) > 0 {
+		t.Fatalf("expected the open conversation to be left alone, got %+v", messages)
+	}
+	if len(m.messages) != 0 {
+		t.Fatalf("expected the run not to be shown in the open conversation, got %d messages", len(m.messages))
+	}
+}
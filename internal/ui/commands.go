@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -15,11 +16,32 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss/v2"
-	"github.com/vybraan/vyai/internal/agent"
+	"github.com/vybraan/vyai/internal/appconfig"
 	"github.com/vybraan/vyai/internal/providers/gemini"
+	"github.com/vybraan/vyai/internal/retrieval"
+	"github.com/vybraan/vyai/internal/templates"
 	"github.com/vybraan/vyai/internal/utils"
 )
 
+// isAskCodePrompt reports whether prompt is a /ask-code question.
+func isAskCodePrompt(prompt string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	command, _, _ := strings.Cut(first, " ")
+	return command == retrieval.Command
+}
+
+// storedUserPrompt is what a saved user message looked like when it was
+// typed: /ask-code questions are sent, and were once stored, with their
+// excerpts, which are left out.
+func storedUserPrompt(text string) string {
+	text = strings.TrimSpace(text)
+	if isAskCodePrompt(text) {
+		first, _, _ := strings.Cut(text, "\n")
+		return first
+	}
+	return text
+}
+
 var focusedMessageBorder = lipgloss.Border{
 	Left: "▌",
 }
@@ -65,6 +87,7 @@ func (m *UIModel) NewConversation() (*UIModel, tea.Cmd) {
 	}
 
 	m.messages = []string{}
+	m.clearAgentRun()
 
 	//clear viewport
 	m.renderViewport("New conversation started")
@@ -74,7 +97,7 @@ func (m *UIModel) NewConversation() (*UIModel, tea.Cmd) {
 	if err == nil {
 		listItems := make([]list.Item, 0, len(items))
 		for _, item := range items {
-			listItems = append(listItems, newConversationListItem(item.ID, item.Description, item.ChatModel, item.UpdatedAt))
+			listItems = append(listItems, newConversationListItem(item.ID, item.Description, item.ChatModel, item.Profile, item.UpdatedAt))
 		}
 		m.explore.SetItems(listItems)
 	} else {
@@ -102,7 +125,7 @@ func (m *UIModel) openEditorForTextarea() (*UIModel, tea.Cmd) {
 }
 
 func (m *UIModel) openEditorForPath(path string, reloadConfig bool) (*UIModel, tea.Cmd) {
-	editor, err := findEditor()
+	editor, err := utils.FindEditor()
 	if err != nil {
 		return m, noticeCmd(err.Error(), false)
 	}
@@ -136,7 +159,7 @@ func (m *UIModel) openEditorForConversationTitle(id string, title string) (*UIMo
 		return m, noticeCmd("Conversation title temp file could not be prepared: "+summarizeUserError(err), false)
 	}
 
-	editor, err := findEditor()
+	editor, err := utils.FindEditor()
 	if err != nil {
 		return m, noticeCmd(err.Error(), false)
 	}
@@ -191,6 +214,10 @@ func (m UIModel) handleKeyEnter() (UIModel, tea.Cmd) {
 	case 0:
 		if m.state == Insert {
 			prompt := m.textarea.Value()
+			if isProfilePrompt(prompt) {
+				m.textarea.Reset()
+				return m, m.switchProfile(prompt)
+			}
 
 			message := renderUserMessage(strings.TrimSpace(prompt))
 
@@ -200,10 +227,18 @@ func (m UIModel) handleKeyEnter() (UIModel, tea.Cmd) {
 			m.viewport.GotoBottom()
 
 			m.loading = true
+			ctx, cancel := context.WithCancel(context.Background())
+			m.cancelRequest = cancel
 			if strings.HasPrefix(strings.TrimSpace(prompt), "/agent") {
-				return m, sendAgentCmd(m, prompt)
+				return m, sendAgentCmd(ctx, m, prompt)
+			}
+			if isAskCodePrompt(prompt) {
+				return m, sendAskCodeCmd(ctx, m, prompt)
+			}
+			if isTemplatePrompt(prompt) {
+				return m, sendTemplateCmd(ctx, m, prompt)
 			}
-			return m, sendMessageCmd(m, prompt)
+			return m, sendMessageCmd(ctx, m, prompt)
 		}
 	case 1:
 		i, ok := m.explore.SelectedItem().(conversationListItem)
@@ -212,53 +247,25 @@ func (m UIModel) handleKeyEnter() (UIModel, tea.Cmd) {
 			return m, nil
 		}
 
-		if err := m.gsService.SwitchConversation(context.Background(), i.ID()); err != nil {
-			m.resetState()
-			m.activeTab = 0
-			return m, noticeCmd("Conversation could not be opened: "+summarizeUserError(err), false)
-		}
-		m.messages = []string{}
-
-		conversation, err := m.gsService.GetActiveConversation()
-		if err != nil {
-			m.resetState()
-			m.activeTab = 0
-			return m, noticeCmd("Active conversation could not be loaded: "+summarizeUserError(err), false)
-		}
-
-		messages, err := conversation.Repo.GetMessages()
-
-		if err != nil {
-			// No messages in the conversation
-			m.renderViewport("chat is empty")
-			m.resetState()
-			m.activeTab = 0
-			return m, nil
-		}
-
-		for _, message := range messages {
-			if message.Role == "user" {
-				text := renderUserMessage(strings.TrimSpace(message.Text))
-				m.messages = append(m.messages, text)
-			} else {
-				rendered := renderMarkdown(message.Text, m.width)
-				wrapped := renderAssistantMessage(strings.TrimSpace(rendered), false)
-				m.messages = append(m.messages, wrapped)
-			}
-
-		}
-		m.renderViewport(strings.Join(m.messages, "\n"))
-
-		m.activeTab = 0
-		m.resetState()
-
-		return m, nil
+		return m.openConversation(i.ID())
 	case 2:
 		if m.settingsIndex >= len(m.settingsItems) {
 			return m, nil
 		}
 		item := m.settingsItems[m.settingsIndex]
 		switch item.itemType {
+		case settingTypeProfile:
+			name := nextProfile(m.gsService.ActiveProfile(), m.gsService.Config().ProfileNames())
+			if err := m.gsService.SetDefaultProfile(name); err != nil {
+				return m, noticeCmd("Failed to update profile: "+summarizeUserError(err), false)
+			}
+			if _, err := m.gsService.GetActiveConversation(); err == nil {
+				if err := m.gsService.SetProfile(name); err != nil {
+					return m, noticeCmd("Failed to update profile: "+summarizeUserError(err), false)
+				}
+			}
+			m.refreshSettingsList()
+			return m, noticeCmd("Profile: "+profileLabel(name), false)
 		case settingTypeChatModel:
 			newModel := nextModel(m.gsService.Config().ChatModel)
 			if err := m.gsService.SetChatModel(newModel); err != nil {
@@ -273,6 +280,16 @@ func (m UIModel) handleKeyEnter() (UIModel, tea.Cmd) {
 			}
 			m.refreshSettingsList()
 			return m, noticeCmd("Description model: "+newModel, false)
+		case settingTypeProjectContext:
+			enabled := !m.gsService.Config().ProjectContext.Enabled
+			if err := m.gsService.SetProjectContext(enabled); err != nil {
+				return m, noticeCmd("Failed to update project context: "+summarizeUserError(err), false)
+			}
+			m.refreshSettingsList()
+			if enabled && m.gsService.ProjectBrief() == "" {
+				return m, noticeCmd("Project context: on, but nothing was detected in the workspace", false)
+			}
+			return m, noticeCmd("Project context: "+onOff(enabled), false)
 		default:
 			_, cmd := m.openEditorForPath(item.Path(), true)
 			return m, cmd
@@ -281,6 +298,62 @@ func (m UIModel) handleKeyEnter() (UIModel, tea.Cmd) {
 	return m, nil
 }
 
+// openConversation switches to the conversation with the given ID and
+// shows its messages in the Chat tab.
+func (m UIModel) openConversation(id string) (UIModel, tea.Cmd) {
+	if err := m.gsService.SwitchConversation(context.Background(), id); err != nil {
+		m.resetState()
+		m.activeTab = 0
+		return m, noticeCmd("Conversation could not be opened: "+summarizeUserError(err), false)
+	}
+	m.messages = []string{}
+	m.clearAgentRun()
+
+	conversation, err := m.gsService.GetActiveConversation()
+	if err != nil {
+		m.resetState()
+		m.activeTab = 0
+		return m, noticeCmd("Active conversation could not be loaded: "+summarizeUserError(err), false)
+	}
+
+	messages, err := conversation.Repo.GetMessages()
+
+	if err != nil {
+		// No messages in the conversation
+		m.renderViewport("chat is empty")
+		m.resetState()
+		m.activeTab = 0
+		return m, nil
+	}
+
+	for _, message := range messages {
+		if message.Role == "user" {
+			text := renderUserMessage(storedUserPrompt(message.Text))
+			m.messages = append(m.messages, text)
+		} else if run, ok := conversation.AgentRun(message.AgentRunID); ok && message.Kind == gemini.MessageKindAgent {
+			restored := agentRunFromRecord(run)
+			m.messages = append(m.messages, renderAssistantMessage(renderAgentRun(restored, m.agentExpanded, m.spinner.View(), m.width), false))
+			// Restore the tool blocks so o can expand the latest run again,
+			// unless a run in progress elsewhere still needs its state.
+			if !m.agentRunning {
+				m.agentRun = restored
+				m.agentRunIndex = len(m.messages) - 1
+			}
+		} else {
+			rendered := renderMarkdown(message.Text, m.width)
+			wrapped := renderAssistantMessage(strings.TrimSpace(rendered), false)
+			m.messages = append(m.messages, wrapped)
+		}
+
+	}
+	m.renderViewport(strings.Join(m.messages, "\n"))
+
+	m.activeTab = 0
+	m.resetState()
+
+	return m, nil
+}
+
 func (m UIModel) renameSelectedConversation() (UIModel, tea.Cmd) {
 	item, ok := m.explore.SelectedItem().(conversationListItem)
 	if !ok {
@@ -312,18 +385,151 @@ func (m UIModel) deleteSelectedConversation() (UIModel, tea.Cmd) {
 	return m, noticeCmd("Conversation deleted.", false)
 }
 
-func sendMessageCmd(m UIModel, prompt string) tea.Cmd {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func sendMessageCmd(parent context.Context, m UIModel, prompt string) tea.Cmd {
+	return streamResponseCmd(parent, func(ctx context.Context, onToken func(string)) error {
+		_, err := m.gsService.SendMessageStream(ctx, prompt, onToken)
+		return err
+	})
+}
+
+// sendAskCodeCmd refreshes the workspace index, retrieves the chunks that
+// match the question and sends them along with it. Only the question is
+// kept in the conversation; the cited locations are appended to the
+// streamed answer.
+func sendAskCodeCmd(parent context.Context, m UIModel, prompt string) tea.Cmd {
+	question := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prompt), retrieval.Command))
+	return streamResponseCmd(parent, func(ctx context.Context, onToken func(string)) error {
+		if question == "" {
+			return errors.New("usage: " + retrieval.Command + " <question>")
+		}
+		if m.codeIndex == nil {
+			return errors.New("the workspace index is not available")
+		}
+		if _, err := m.codeIndex.Refresh(ctx); err != nil {
+			return fmt.Errorf("index workspace: %w", err)
+		}
+		hits, err := m.codeIndex.Search(ctx, question, retrieval.DefaultLimit)
+		if err != nil {
+			return fmt.Errorf("search workspace: %w", err)
+		}
+
+		prompt := retrieval.BuildPrompt(question, hits)
+		answer, err := m.gsService.SendMessageStreamAs(ctx, prompt, storedUserPrompt(prompt), onToken)
+		if err != nil {
+			return err
+		}
+		if sources := retrieval.FormatSources(hits); sources != "" {
+			// onToken takes the whole text so far, not a delta.
+			onToken(answer + "\n\n" + sources)
+			return m.gsService.AmendLastAnswer("\n\n" + sources)
+		}
+		return nil
+	})
+}
+
+// profileCommand lists the profiles or switches the active conversation to
+// one of them.
+const profileCommand = "/profile"
+
+// isProfilePrompt reports whether prompt is a /profile command.
+func isProfilePrompt(prompt string) bool {
+	command, _, _ := strings.Cut(strings.TrimSpace(prompt), " ")
+	return command == profileCommand
+}
+
+// switchProfile handles /profile NAME; "none" goes back to the configured
+// settings, and no name lists the profiles. Only the active conversation
+// changes; the Settings tab sets the profile of new ones.
+func (m UIModel) switchProfile(prompt string) tea.Cmd {
+	cfg := m.gsService.Config()
+	name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prompt), profileCommand))
+	if name == "" {
+		return noticeCmd(profileSummary(cfg, m.gsService.ActiveProfile()), false)
+	}
+	if name == "none" {
+		name = ""
+	} else if _, ok := cfg.Profiles[name]; !ok {
+		return noticeCmd(fmt.Sprintf("Profile %q is not defined. %s", name, profileSummary(cfg, m.gsService.ActiveProfile())), false)
+	}
+	if _, err := m.gsService.EnsureConversation(context.Background()); err != nil {
+		return noticeCmd("Profile not switched: "+summarizeUserError(err), false)
+	}
+	if err := m.gsService.SetProfile(name); err != nil {
+		return noticeCmd("Profile not switched: "+summarizeUserError(err), false)
+	}
+	return noticeCmd("Profile: "+profileLabel(name), false)
+}
+
+// profileSummary lists the profiles for the chat, marking the active one.
+func profileSummary(cfg *appconfig.Config, active string) string {
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		return fmt.Sprintf("No profiles yet. Add them under \"profiles\" in %s.", cfg.ConfigFile)
+	}
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		line := name
+		if name == active {
+			line += " (active)"
+		}
+		if desc := cfg.Profiles[name].Description; desc != "" {
+			line += ": " + desc
+		}
+		lines = append(lines, line)
+	}
+	return "Profiles: " + strings.Join(lines, "; ")
+}
+
+// isTemplatePrompt reports whether prompt is a /t template invocation.
+func isTemplatePrompt(prompt string) bool {
+	command, _, _ := strings.Cut(strings.TrimSpace(prompt), " ")
+	return command == templates.Command
+}
 
+// sendTemplateCmd fills in the template named in prompt and sends the
+// result. Without a name it lists the templates instead.
+func sendTemplateCmd(parent context.Context, m UIModel, prompt string) tea.Cmd {
+	args := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prompt), templates.Command))
+	dir := m.gsService.Config().TemplateDir
+	return streamResponseCmd(parent, func(ctx context.Context, onToken func(string)) error {
+		if args == "" {
+			summary, err := templates.Summary(dir)
+			if err != nil {
+				return err
+			}
+			onToken(summary)
+			return nil
+		}
+		name, vars, err := templates.ParseArgs(args)
+		if err != nil {
+			return err
+		}
+		rendered, err := templates.Prompt(dir, name, vars, m.workspace, "")
+		if err != nil {
+			return err
+		}
+		_, err = m.gsService.SendMessageStream(ctx, rendered, onToken)
+		return err
+	})
+}
+
+// streamResponseCmd runs send in the background and streams the tokens it
+// reports. The 60 second response timeout starts when send is called.
+func streamResponseCmd(parent context.Context, send func(ctx context.Context, onToken func(string)) error) tea.Cmd {
 	tokens := make(chan string, 20)
 	errCh := make(chan error, 1)
 
 	go func() {
+		ctx, cancel := context.WithTimeout(parent, 60*time.Second)
 		defer cancel()
-		_, err := m.gsService.SendMessageStream(ctx, prompt, func(token string) {
+		err := send(ctx, func(token string) {
 			tokens <- token
 		})
 		if err != nil {
+			if parent.Err() != nil {
+				// Report a user cancellation rather than the provider's wrapped error.
+				err = parent.Err()
+			}
 			errCh <- err
 		}
 		close(tokens)
@@ -335,18 +541,25 @@ func sendMessageCmd(m UIModel, prompt string) tea.Cmd {
 			if !ok {
 				select {
 				case err := <-errCh:
-					return noticeMsg{text: "Request failed: " + summarizeUserError(err), stopLoading: true}
+					return streamErrMsg(err)
 				default:
 					return streamEndMsg{}
 				}
 			}
 			return streamStartMsg{tokens: tokens, errCh: errCh, firstToken: token}
 		case err := <-errCh:
-			return noticeMsg{text: "Request failed: " + summarizeUserError(err), stopLoading: true}
+			return streamErrMsg(err)
 		}
 	}
 }
 
+func streamErrMsg(err error) tea.Msg {
+	if errors.Is(err, context.Canceled) {
+		return streamEndMsg{cancelled: true}
+	}
+	return noticeMsg{text: "Request failed: " + summarizeUserError(err), stopLoading: true}
+}
+
 func pollStreamCmd(m UIModel) tea.Cmd {
 	return func() tea.Msg {
 		select {
@@ -354,37 +567,18 @@ func pollStreamCmd(m UIModel) tea.Cmd {
 			if !ok {
 				select {
 				case err := <-m.streamErr:
-					return noticeMsg{text: "Request failed: " + summarizeUserError(err), stopLoading: true}
+					return streamErrMsg(err)
 				default:
 					return streamEndMsg{}
 				}
 			}
 			return streamMsg(token)
 		case err := <-m.streamErr:
-			return noticeMsg{text: "Request failed: " + summarizeUserError(err), stopLoading: true}
+			return streamErrMsg(err)
 		}
 	}
 }
 
-func sendAgentCmd(m UIModel, prompt string) tea.Cmd {
-	return func() tea.Msg {
-		userInput := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(prompt), "/agent"))
-		if m.agentRunner == nil {
-			return noticeMsg{text: "Agent runner is not configured.", stopLoading: true}
-		}
-
-		output, err := m.agentRunner.Run(context.Background(), agent.RunRequest{
-			Input: userInput,
-			Model: m.gsService.Config().ChatModel,
-		})
-		if err != nil {
-			return noticeMsg{text: "Agent request failed: " + summarizeUserError(err), stopLoading: true}
-		}
-		rendered := renderMarkdown(output, m.width)
-		return statusMsg(strings.TrimSpace(rendered))
-	}
-}
-
 func (m UIModel) Init() tea.Cmd {
 	return tea.Batch(
 		tea.EnterAltScreen,
@@ -423,6 +617,14 @@ func renderMarkdown(s string, width int) string {
 	return renderedMessage
 }
 
+// finishRequest releases the context of the request that just ended.
+func (m *UIModel) finishRequest() {
+	if m.cancelRequest != nil {
+		m.cancelRequest()
+		m.cancelRequest = nil
+	}
+}
+
 func (m *UIModel) resetState() {
 	m.state = Normal
 	m.textarea.Reset()
@@ -469,21 +671,3 @@ func summarizeUserError(err error) string {
 
 	return strings.TrimSpace(err.Error())
 }
-
-func findEditor() (string, error) {
-	editor := os.Getenv("EDITOR")
-	knownEditors := [...]string{editor, "vim", "vi", "nano", "ed"}
-
-	for _, cmd := range knownEditors {
-		if cmd == "" {
-			continue
-		}
-		pathValue, err := exec.LookPath(cmd)
-		if err != nil {
-			continue
-		}
-		return pathValue, nil
-	}
-
-	return "", fmt.Errorf("EDITOR is not set and no fallback editor was found in PATH: %v", knownEditors[1:])
-}
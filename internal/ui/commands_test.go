@@ -28,3 +28,33 @@ func TestSummarizeUserErrorPreservesWrappedProviderMessage(t *testing.T) {
 type errString string
 
 func (e errString) Error() string { return string(e) }
+
+func TestStoredUserPromptHidesAskCodeExcerpts(t *testing.T) {
+	t.Parallel()
+
+	stored := "/ask-code where is main?\n\nAnswer the question using the excerpts...\n[1] main.go:1-3"
+	if got := storedUserPrompt(stored); got != "/ask-code where is main?" {
+		t.Fatalf("unexpected stored prompt: %q", got)
+	}
+	if got := storedUserPrompt("  /ask-codes are fun\n\nmore "); got != "/ask-codes are fun\n\nmore" {
+		t.Fatalf("expected other prompts to be kept, got %q", got)
+	}
+}
+
+func TestNextProfileCyclesThroughNone(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"reviewer", "writer"}
+	got := []string{}
+	current := ""
+	for range 3 {
+		current = nextProfile(current, names)
+		got = append(got, profileLabel(current))
+	}
+	if strings.Join(got, ",") != "reviewer,writer,none" {
+		t.Fatalf("unexpected profile cycle: %v", got)
+	}
+	if next := nextProfile("", nil); next != "" {
+		t.Fatalf("expected no profile without profiles, got %q", next)
+	}
+}
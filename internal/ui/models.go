@@ -1,12 +1,15 @@
 package ui
 
 import (
+	"context"
+
 	"github.com/charmbracelet/bubbles/v2/list"
 	"github.com/charmbracelet/bubbles/v2/spinner"
 	"github.com/charmbracelet/bubbles/v2/textarea"
 	"github.com/charmbracelet/bubbles/v2/viewport"
 	"github.com/vybraan/vyai/internal/agent"
 	"github.com/vybraan/vyai/internal/providers/gemini"
+	"github.com/vybraan/vyai/internal/retrieval"
 )
 
 type (
@@ -21,7 +24,19 @@ type (
 		firstToken string
 	}
 	streamMsg   string
-	streamEndMsg struct{}
+	streamEndMsg struct {
+		cancelled bool
+	}
+	agentStartMsg struct {
+		request string
+		conversationID string
+		events  chan agent.Event
+		done   chan error
+	}
+	agentEventMsg agent.Event
+	agentEndMsg   struct {
+		err error
+	}
 	editorMsg struct {
 		path                 string
 		reloadConfig         bool
@@ -81,5 +96,14 @@ type UIModel struct {
 	activeTab       int
 	ready           bool
 	agentRunner     agent.Runner
+	agentRunning    bool
+	agentRun        agentRun
+	agentRunIndex   int
+	agentExpanded   bool
+	agentEvents     chan agent.Event
+	agentDone       chan error
+	cancelRequest   context.CancelFunc
 	deleteTarget    string
+	codeIndex       *retrieval.Index
+	resumeID        string
 }
@@ -10,6 +10,8 @@ const (
 	settingTypePath settingsItemType = iota
 	settingTypeChatModel
 	settingTypeDescModel
+	settingTypeProjectContext
+	settingTypeProfile
 )
 
 type settingsItem struct {
@@ -53,10 +55,39 @@ func nextModel(current string) string {
 	return knownModels[0]
 }
 
-func buildSettingsItems(chatModel, descriptionModel, cfgPath, systemPromptPath, descriptionPromptPath string) []settingsItem {
+// nextProfile cycles through no profile and the configured ones.
+func nextProfile(current string, names []string) string {
+	if current == "" && len(names) > 0 {
+		return names[0]
+	}
+	for i, name := range names {
+		if name == current && i+1 < len(names) {
+			return names[i+1]
+		}
+	}
+	return ""
+}
+
+func profileLabel(name string) string {
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+func buildSettingsItems(chatModel, descriptionModel, profile, cfgPath, systemPromptPath, descriptionPromptPath string, projectContext bool) []settingsItem {
 	return []settingsItem{
+		newSettingsItem("Profile", "Assistant profile of this and new conversations (profiles in the config file). Current: "+profileLabel(profile), "", settingTypeProfile, profile),
 		newSettingsItem("Chat Model", "Model used for conversations. Current: "+chatModel, "", settingTypeChatModel, chatModel),
 		newSettingsItem("Description Model", "Model used for conversation titles. Current: "+descriptionModel, "", settingTypeDescModel, descriptionModel),
+		newSettingsItem("Project Context", "Brief chat and agent on the workspace project (go.mod, package.json, Makefile, README, .vyai/context.md). Current: "+onOff(projectContext), "", settingTypeProjectContext, onOff(projectContext)),
 		newSettingsItem("Application Config", "Configure models, prompts, and paths. File: "+cfgPath, cfgPath, settingTypePath, ""),
 		newSettingsItem("System Prompt", "Default assistant behavior and response policy. File: "+systemPromptPath, systemPromptPath, settingTypePath, ""),
 		newSettingsItem("Description Prompt", "Conversation title generation prompt. File: "+descriptionPromptPath, descriptionPromptPath, settingTypePath, ""),
@@ -69,7 +100,10 @@ type conversationListItem struct {
 	desc  string
 }
 
-func newConversationListItem(id, title, model, updatedAt string) conversationListItem {
+func newConversationListItem(id, title, model, profile, updatedAt string) conversationListItem {
+	if profile != "" {
+		model += " (" + profile + ")"
+	}
 	description := fmt.Sprintf("%s  %s", model, updatedAt)
 	return conversationListItem{
 		id:    id,
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"math/rand/v2"
 	"os"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/charmbracelet/lipgloss/v2"
 	"github.com/vybraan/vyai/internal/agent"
 	"github.com/vybraan/vyai/internal/providers/gemini"
+	"github.com/vybraan/vyai/internal/retrieval"
 )
 
 const gap = "\n"
@@ -49,7 +51,7 @@ func NewUIModel(gs *gemini.GeminiService, workspace string, agentRunner agent.Ru
 	explore := list.New([]list.Item{}, newExploreDelegate(), 0, 0)
 
 	tabs := []string{"Chat", "Explore", "Settings"}
-	si := buildSettingsItems(gs.Config().ChatModel, gs.Config().DescriptionModel, gs.Config().ConfigFile, gs.Config().SystemPromptFile, gs.Config().DescriptionPromptFile)
+	si := buildSettingsItems(gs.Config().ChatModel, gs.Config().DescriptionModel, gs.ActiveProfile(), gs.Config().ConfigFile, gs.Config().SystemPromptFile, gs.Config().DescriptionPromptFile, gs.Config().ProjectContext.Enabled)
 	return UIModel{
 		theme:         theme,
 		state:         Normal,
@@ -64,11 +66,20 @@ func NewUIModel(gs *gemini.GeminiService, workspace string, agentRunner agent.Ru
 		loading:       false,
 		workspace:     workspace,
 		agentRunner:   agentRunner,
+		agentRunIndex: -1,
+		codeIndex:     retrieval.NewIndex(retrieval.IndexPath(gs.Config().DataDir, workspace), workspace, nil),
 		Tabs:          tabs,
 		activeTab:     0,
 	}
 }
 
+// WithConversation opens the conversation with the given ID once the
+// interface knows its size.
+func (m UIModel) WithConversation(id string) UIModel {
+	m.resumeID = id
+	return m
+}
+
 func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		textareaCmd tea.Cmd
@@ -141,6 +152,14 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.explore.SetSize(msg.Width-h, msg.Height-v-lipgloss.Height(m.headerView()))
 		m.refreshSettingsList()
 
+		if m.resumeID != "" {
+			id := m.resumeID
+			m.resumeID = ""
+			var openCmd tea.Cmd
+			m, openCmd = m.openConversation(id)
+			cmds = append(cmds, openCmd)
+		}
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
@@ -191,6 +210,10 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, renameCmd)
 				break
 			}
+		case "o":
+			if m.activeTab == 0 && m.state == Normal {
+				m.toggleAgentOutput()
+			}
 		case "x":
 			if m.activeTab == 1 {
 				var deleteCmd tea.Cmd
@@ -216,6 +239,11 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				switch m.activeTab {
 				case 0:
+					if m.loading && m.cancelRequest != nil {
+						m.cancelRequest()
+						m.notice = "Cancelling..."
+						m.resizeViewport()
+					}
 					m.viewport.MouseWheelEnabled = false
 					m.state = Normal
 					m.textarea.Blur()
@@ -271,12 +299,63 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.streamTokens = nil
 		m.streamErr = nil
 		m.notice = ""
+		if msg.cancelled {
+			m.notice = "Response cancelled."
+		}
+		m.finishRequest()
+		m.resizeViewport()
+		m.spinnerIndex = rand.IntN(len(spinners) - 1)
+		m.resetSpinner()
+	case agentStartMsg:
+		m.agentRunning = true
+		m.agentRun = agentRun{request: msg.request, conversationID: msg.conversationID}
+		m.agentEvents = msg.events
+		m.agentDone = msg.done
+		m.refreshAgentView()
+		m.viewport.GotoBottom()
+		return m, pollAgentCmd(m)
+	case agentEventMsg:
+		m.agentRun.apply(agent.Event(msg))
+		m.refreshAgentView()
+		m.viewport.GotoBottom()
+		return m, pollAgentCmd(m)
+	case agentEndMsg:
+		m.agentRunning = false
+		m.agentEvents = nil
+		m.agentDone = nil
+		if msg.err != nil {
+			m.agentRun.failRunning()
+		}
+		m.agentRun.finishRunning()
+		if (len(m.agentRun.steps) > 0 || m.agentRun.summary != "") && m.agentRunShown() {
+			m.messages = append(m.messages, m.renderAgentRun())
+			m.agentRunIndex = len(m.messages) - 1
+		}
+		m.loading = false
+		m.notice = ""
+		var cancelled *agent.CancelledError
+		switch {
+		case errors.As(msg.err, &cancelled):
+			m.notice = cancelledNotice(cancelled)
+		case msg.err != nil:
+			m.notice = "Agent request failed: " + summarizeUserError(msg.err)
+		}
+		if err := m.saveAgentRun(msg.err); err != nil {
+			m.notice = "Agent run was not saved: " + summarizeUserError(err)
+		}
+		m.finishRequest()
 		m.resizeViewport()
 		m.spinnerIndex = rand.IntN(len(spinners) - 1)
 		m.resetSpinner()
+		m.renderViewport(strings.Join(m.messages, "\n"))
+		m.viewport.GotoBottom()
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
+		if m.agentRunning {
+			// Keep the per-tool spinners moving.
+			m.refreshAgentView()
+		}
 
 		cmds = append(cmds, cmd)
 
@@ -284,6 +363,7 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.notice = msg.text
 		m.resizeViewport()
 		if msg.stopLoading {
+			m.finishRequest()
 			m.loading = false
 			m.streaming = false
 			m.partialResponse = ""
@@ -365,7 +445,7 @@ func (m *UIModel) refreshExploreList() {
 	if err == nil {
 		listItems := make([]list.Item, 0, len(items))
 		for _, item := range items {
-			listItems = append(listItems, newConversationListItem(item.ID, item.Description, item.ChatModel, item.UpdatedAt))
+			listItems = append(listItems, newConversationListItem(item.ID, item.Description, item.ChatModel, item.Profile, item.UpdatedAt))
 		}
 		m.explore.SetItems(listItems)
 		m.explore.SetShowTitle(true)
@@ -378,7 +458,7 @@ func (m *UIModel) refreshExploreList() {
 
 func (m *UIModel) refreshSettingsList() {
 	cfg := m.gsService.Config()
-	m.settingsItems = buildSettingsItems(cfg.ChatModel, cfg.DescriptionModel, cfg.ConfigFile, cfg.SystemPromptFile, cfg.DescriptionPromptFile)
+	m.settingsItems = buildSettingsItems(cfg.ChatModel, cfg.DescriptionModel, m.gsService.ActiveProfile(), cfg.ConfigFile, cfg.SystemPromptFile, cfg.DescriptionPromptFile, cfg.ProjectContext.Enabled)
 	if m.settingsIndex >= len(m.settingsItems) {
 		m.settingsIndex = 0
 	}
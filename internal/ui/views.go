@@ -73,7 +73,10 @@ func (m UIModel) inputView() string {
 	if m.streaming && m.partialResponse != "" {
 		return "\n\n" // 2 blank lines + gap's \n = 3 lines total, matches textarea
 	}
-	return m.spinner.View() + " Thinking...\n\n" // 3 lines total (matches textarea height)
+	if m.agentRunning {
+		return m.spinner.View() + " Agent running... (esc to cancel)\n\n"
+	}
+	return m.spinner.View() + " Thinking... (esc to cancel)\n\n" // 3 lines total (matches textarea height)
 }
 
 func (m UIModel) headerView() string {
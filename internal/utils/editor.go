@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// FindEditor returns $EDITOR, or the first common editor found in PATH.
+func FindEditor() (string, error) {
+	editor := os.Getenv("EDITOR")
+	knownEditors := [...]string{editor, "vim", "vi", "nano", "ed"}
+
+	for _, cmd := range knownEditors {
+		if cmd == "" {
+			continue
+		}
+		pathValue, err := exec.LookPath(cmd)
+		if err != nil {
+			continue
+		}
+		return pathValue, nil
+	}
+
+	return "", fmt.Errorf("EDITOR is not set and no fallback editor was found in PATH: %v", knownEditors[1:])
+}
@@ -52,6 +52,23 @@ func FormatSettings(cfg *appconfig.Config, apiKeySet bool) string {
 		apiKeyStatus = "set"
 	}
 
+	projectContext := "off"
+	if cfg.ProjectContext.Enabled {
+		projectContext = "on"
+	}
+	resumeLast := "off"
+	if cfg.ResumeLast {
+		resumeLast = "on"
+	}
+	defaultProfile := "none"
+	if cfg.DefaultProfile != "" {
+		defaultProfile = cfg.DefaultProfile
+	}
+	profiles := "none"
+	if names := cfg.ProfileNames(); len(names) > 0 {
+		profiles = strings.Join(names, ", ")
+	}
+
 	return strings.TrimSpace(fmt.Sprintf(`
 # Settings
 
@@ -63,8 +80,15 @@ func FormatSettings(cfg *appconfig.Config, apiKeySet bool) string {
 - Description model: %s
 - System prompt source: %s
 - Description prompt source: %s
+- Commit prompt source: %s
+- Review prompt source: %s
+- Pull request prompt source: %s
+- Profiles: %s
+- Default profile: %s
+- Project context: %s
+- Resume last conversation: %s
 - GOOGLE_API_KEY: %s
-`, cfg.AppName, cfg.ConfigDir, cfg.ConfigFile, cfg.DataDir, cfg.ChatModel, cfg.DescriptionModel, cfg.SystemPromptSource, cfg.DescriptionSource, apiKeyStatus))
+`, cfg.AppName, cfg.ConfigDir, cfg.ConfigFile, cfg.DataDir, cfg.ChatModel, cfg.DescriptionModel, cfg.SystemPromptSource, cfg.DescriptionSource, cfg.CommitPromptSource, cfg.ReviewPromptSource, cfg.PRPromptSource, profiles, defaultProfile, projectContext, resumeLast, apiKeyStatus))
 }
 
 func responseText(resp *genai.GenerateContentResponse) (string, error) {